@@ -0,0 +1,106 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// AggregateConfig represents the configuration for an Aggregate unit
+type AggregateConfig struct {
+	UnitConfig `yaml:",inline"`
+	Units      []string `yaml:"units"`
+}
+
+// AggregateUnit combines the results of a set of units that ran earlier in the
+// cycle, such as builds fanned out to several machines, into one summary. The
+// summary is its output, so a notification unit it triggers sends a single
+// report.
+type AggregateUnit struct {
+	name      string
+	units     []string
+	result    func(name string) (*UnitResult, bool)
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// NewAggregateUnit creates a new Aggregate unit summarizing the named units
+func NewAggregateUnit(name string, units []string, onSuccess, onFailure, always []string) *AggregateUnit {
+	return &AggregateUnit{
+		name:      name,
+		units:     units,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}
+}
+
+// SetResultLookup sets the function used to look up the result of a unit in
+// the current cycle. The orchestrator sets it.
+func (a *AggregateUnit) SetResultLookup(result func(name string) (*UnitResult, bool)) {
+	a.result = result
+}
+
+// Name returns the unit name
+func (a *AggregateUnit) Name() string {
+	return a.name
+}
+
+// Type returns the unit type
+func (a *AggregateUnit) Type() string {
+	return "aggregate"
+}
+
+// Run prints a summary of the units' results, e.g.
+// "2/3 passed: imx8 ✓, rpi4 ✓, agx ✗", followed by the error of each unit
+// that failed. It fails if any unit failed or hasn't run in this cycle.
+func (a *AggregateUnit) Run(ctx context.Context) error {
+	log.Printf("Running aggregate unit '%s'", a.name)
+
+	if a.result == nil {
+		return fmt.Errorf("no result lookup set")
+	}
+
+	passed := 0
+	var statuses, details []string
+	for _, name := range a.units {
+		result, ok := a.result(name)
+		switch {
+		case !ok:
+			statuses = append(statuses, name+" (not run)")
+		case result.Error != nil:
+			statuses = append(statuses, name+" ✗")
+			details = append(details, fmt.Sprintf("%s: %v", name, result.Error))
+		default:
+			passed++
+			statuses = append(statuses, name+" ✓")
+		}
+	}
+
+	fmt.Printf("%d/%d passed: %s\n", passed, len(a.units), strings.Join(statuses, ", "))
+	for _, detail := range details {
+		fmt.Println(detail)
+	}
+
+	if passed < len(a.units) {
+		return fmt.Errorf("%d of %d units didn't pass", len(a.units)-passed, len(a.units))
+	}
+	return nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (a *AggregateUnit) OnSuccess() []string {
+	return a.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (a *AggregateUnit) OnFailure() []string {
+	return a.onFailure
+}
+
+// Always returns the list of units to always trigger
+func (a *AggregateUnit) Always() []string {
+	return a.always
+}
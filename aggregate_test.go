@@ -0,0 +1,62 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAggregateUnit_Run(t *testing.T) {
+	units := []Unit{
+		NewStartTrigger("start", []string{"imx8", "rpi4", "agx", "summary"}, nil, nil),
+		NewRunUnit("imx8", "true", "", 0, "", false, nil, nil, nil),
+		NewRunUnit("rpi4", "true", "", 0, "", false, nil, nil, nil),
+		NewRunUnit("agx", "exit 3", "", 0, "", false, nil, nil, nil),
+		NewAggregateUnit("summary", []string{"imx8", "rpi4", "agx", "never"}, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	result, ok := orchestrator.GetResults()["summary"]
+	if !ok {
+		t.Fatal("Expected aggregate unit to run")
+	}
+	if result.Error == nil {
+		t.Error("Expected aggregate unit to fail when a unit failed")
+	}
+	for _, want := range []string{
+		"2/4 passed: imx8 ✓, rpi4 ✓, agx ✗, never (not run)",
+		"agx: script exited with code 3",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("Output missing %q:\n%s", want, result.Output)
+		}
+	}
+}
+
+func TestLoadConfig_AggregateRequiresUnits(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configData := `config:
+  state_location: state.yaml
+units:
+  - aggregate:
+      name: summary
+`
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if _, err := config.CreateUnits(); err == nil || !strings.Contains(err.Error(), "units is required") {
+		t.Errorf("Expected units required error, got %v", err)
+	}
+}
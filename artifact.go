@@ -0,0 +1,35 @@
+package brun
+
+import (
+	"regexp"
+	"strings"
+)
+
+// artifactRefRegex matches artifact references like ${artifact.image_path}
+var artifactRefRegex = regexp.MustCompile(`\$\{artifact\.([A-Za-z0-9_]+)\}`)
+
+// expandArtifacts replaces ${artifact.<name>} references in s with artifact
+// values. References to unknown artifacts are left unchanged.
+func expandArtifacts(s string, artifacts map[string]string) string {
+	if len(artifacts) == 0 {
+		return s
+	}
+
+	return artifactRefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := artifactRefRegex.FindStringSubmatch(ref)[1]
+		if value, ok := artifacts[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// artifactEnv returns artifacts as BRUN_ARTIFACT_<NAME>=value environment
+// variables
+func artifactEnv(artifacts map[string]string) []string {
+	var env []string
+	for name, value := range artifacts {
+		env = append(env, "BRUN_ARTIFACT_"+strings.ToUpper(name)+"="+value)
+	}
+	return env
+}
@@ -0,0 +1,134 @@
+package brun
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// defaultMaxFailureBackoff is the longest a failing unit waits between runs
+// when failure_backoff_max isn't set
+const defaultMaxFailureBackoff = time.Hour
+
+// FailureBackoff delays running a unit again after it fails. The delay
+// starts at Initial and doubles with each consecutive failure up to Max.
+type FailureBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// delay returns how long to wait after the given number of consecutive
+// failures
+func (b FailureBackoff) delay(failures int) time.Duration {
+	limit := b.Max
+	if limit <= 0 {
+		limit = max(defaultMaxFailureBackoff, b.Initial)
+	}
+
+	delay := b.Initial
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// SetFailureBackoffs configures, keyed by unit name, how long units wait
+// before running again after they fail. Consecutive failures and the time
+// of the next retry are stored in state so the backoff survives restarts.
+func (o *Orchestrator) SetFailureBackoffs(backoffs map[string]FailureBackoff, state *State) {
+	o.backoffs = backoffs
+	o.backoffState = state
+}
+
+// backoffFailures returns the number of consecutive failures of the named
+// unit and when it may run again, or 0 if it isn't backing off
+func (o *Orchestrator) backoffFailures(name string) (int, time.Time) {
+	if _, ok := o.backoffs[name]; !ok || o.backoffState == nil {
+		return 0, time.Time{}
+	}
+
+	val, ok := o.backoffState.Get(name, "backoff_failures")
+	if !ok {
+		return 0, time.Time{}
+	}
+	failures, _ := val.(int)
+
+	retryStr, _ := o.backoffState.GetString(name, "backoff_retry_at")
+	retryAt, err := time.Parse(time.RFC3339, retryStr)
+	if err != nil {
+		return failures, time.Time{}
+	}
+	return failures, retryAt
+}
+
+// backingOff returns true if the named unit failed and must not run again
+// yet. Units run with -unit or -trigger don't wait.
+func (o *Orchestrator) backingOff(name string) bool {
+	if o.singleRun {
+		return false
+	}
+	failures, retryAt := o.backoffFailures(name)
+	if failures == 0 || !time.Now().Before(retryAt) {
+		return false
+	}
+	log.Printf("Unit '%s' skipped, failed %d times in a row, backing off until %s",
+		name, failures, retryAt.Format(time.RFC3339))
+	return true
+}
+
+// recordBackoff updates the failure backoff of the named unit after it ran
+func (o *Orchestrator) recordBackoff(name string, err error) {
+	backoff, ok := o.backoffs[name]
+	if !ok || o.backoffState == nil {
+		return
+	}
+
+	failures, _ := o.backoffFailures(name)
+	if err == nil {
+		if failures == 0 {
+			return
+		}
+		log.Printf("Unit '%s' succeeded after %d failures, backoff reset", name, failures)
+		if err := o.backoffState.DeleteKey(name, "backoff_failures"); err != nil {
+			log.Printf("Error resetting backoff of unit '%s': %v", name, err)
+		}
+		if err := o.backoffState.DeleteKey(name, "backoff_retry_at"); err != nil {
+			log.Printf("Error resetting backoff of unit '%s': %v", name, err)
+		}
+		return
+	}
+
+	failures++
+	retryAt := time.Now().Add(backoff.delay(failures))
+	log.Printf("Unit '%s' failed %d times in a row, not running it again before %s",
+		name, failures, retryAt.Format(time.RFC3339))
+	if err := o.backoffState.Set(name, "backoff_failures", failures); err != nil {
+		log.Printf("Error saving backoff of unit '%s': %v", name, err)
+	}
+	if err := o.backoffState.SetString(name, "backoff_retry_at", retryAt.Format(time.RFC3339)); err != nil {
+		log.Printf("Error saving backoff of unit '%s': %v", name, err)
+	}
+}
+
+// retryFailedUnits runs the units backing off after a failure whose retry
+// time has passed, so a failed unit is retried even if nothing triggers it
+// again. The retry runs the unit on its own, without the artifacts and
+// variables of the chain that first triggered it.
+func (o *Orchestrator) retryFailedUnits(ctx context.Context) {
+	for _, unit := range o.units {
+		failures, retryAt := o.backoffFailures(unit.Name())
+		if failures == 0 || time.Now().Before(retryAt) {
+			continue
+		}
+		// Already ran in this cycle
+		if _, ok := o.result(unit.Name()); ok {
+			continue
+		}
+
+		log.Printf("Retrying unit %s after %d failures", o.describe(unit.Name()), failures)
+		o.resetActivation()
+		if err := o.executeUnit(ctx, unit, []string{unit.Name()}); err != nil {
+			log.Printf("Retry of unit %s failed: %v", o.describe(unit.Name()), err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailureBackoff_Delay(t *testing.T) {
+	tests := []struct {
+		backoff  FailureBackoff
+		failures int
+		want     time.Duration
+	}{
+		{FailureBackoff{Initial: time.Minute}, 1, time.Minute},
+		{FailureBackoff{Initial: time.Minute}, 3, 4 * time.Minute},
+		{FailureBackoff{Initial: time.Minute}, 20, time.Hour},
+		{FailureBackoff{Initial: time.Minute, Max: 5 * time.Minute}, 4, 5 * time.Minute},
+		{FailureBackoff{Initial: 2 * time.Hour}, 2, 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := tt.backoff.delay(tt.failures); got != tt.want {
+			t.Errorf("%+v.delay(%d) = %s, want %s", tt.backoff, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestOrchestrator_FailureBackoff(t *testing.T) {
+	tmpDir := t.TempDir()
+	runsFile := filepath.Join(tmpDir, "runs.txt")
+	okFile := filepath.Join(tmpDir, "ok")
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	build := NewRunUnit("build", "echo run >> "+runsFile+"; test -f "+okFile, "", 0, "", false, nil, nil, nil)
+	units := []Unit{
+		NewStartTrigger("start", []string{"build"}, nil, nil),
+		build,
+	}
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetFailureBackoffs(map[string]FailureBackoff{"build": {Initial: time.Hour}}, state)
+	ctx := context.Background()
+
+	runs := func() int {
+		data, _ := os.ReadFile(runsFile)
+		return strings.Count(string(data), "run")
+	}
+
+	if err := orchestrator.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if failures, _ := orchestrator.backoffFailures("build"); failures != 1 {
+		t.Fatalf("Expected 1 failure, got %d", failures)
+	}
+
+	// Triggering the unit again while backing off doesn't run it, and it
+	// isn't retried before its retry time
+	if err := orchestrator.executeUnit(ctx, build, []string{"start", "build"}); err != nil {
+		t.Errorf("Expected a skipped unit not to fail, got %v", err)
+	}
+	orchestrator.runPollCycle(ctx)
+	if got := runs(); got != 1 {
+		t.Errorf("Expected build to run once while backing off, ran %d times", got)
+	}
+
+	// Once the retry time has passed, a poll cycle retries it
+	if err := state.SetString("build", "backoff_retry_at", time.Now().Add(-time.Second).Format(time.RFC3339)); err != nil {
+		t.Fatalf("Failed to set retry time: %v", err)
+	}
+	if err := os.WriteFile(okFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	orchestrator.runPollCycle(ctx)
+	if got := runs(); got != 2 {
+		t.Errorf("Expected build to be retried, ran %d times", got)
+	}
+
+	// Success resets the backoff
+	if _, ok := state.Get("build", "backoff_failures"); ok {
+		t.Error("Expected backoff to be reset after success")
+	}
+}
@@ -3,6 +3,7 @@ package brun
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -62,29 +63,23 @@ func (s *BootTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
 		if err := s.state.Set(s.name, "boot_count", 1); err != nil {
 			return false, fmt.Errorf("failed to save boot count: %w", err)
 		}
-		return true, nil
-	}
-
-	// Parse last boot time
-	lastBootTime, err := parseBootTime(lastBootTimeStr)
-	if err != nil {
-		// Invalid boot time in state, treat as first run
-		if err := s.state.SetString(s.name, "last_boot_time", currentBootTime.Format("2006-01-02T15:04:05Z07:00")); err != nil {
-			return false, fmt.Errorf("failed to save boot time: %w", err)
-		}
-		if err := s.state.Set(s.name, "boot_count", 1); err != nil {
-			return false, fmt.Errorf("failed to save boot count: %w", err)
+		if err := s.state.Set(s.name, "first_install", true); err != nil {
+			return false, fmt.Errorf("failed to save first install flag: %w", err)
 		}
 		return true, nil
 	}
 
-	// Check if boot time has changed (with 10 second tolerance)
-	diff := currentBootTime.Sub(lastBootTime)
-	if diff < 0 {
-		diff = -diff
+	// An invalid boot time in state is treated as a reboot. The state was
+	// written by an earlier run, so it isn't a first install.
+	isFirstRun := true
+	if lastBootTime, err := parseBootTime(lastBootTimeStr); err == nil {
+		// Check if boot time has changed (with 10 second tolerance)
+		diff := currentBootTime.Sub(lastBootTime)
+		if diff < 0 {
+			diff = -diff
+		}
+		isFirstRun = diff > 10*time.Second
 	}
-
-	isFirstRun := diff > 10*time.Second
 	if isFirstRun {
 		// Get current boot count
 		bootCount := 1
@@ -101,11 +96,32 @@ func (s *BootTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
 		if err := s.state.Set(s.name, "boot_count", bootCount); err != nil {
 			return false, fmt.Errorf("failed to save boot count: %w", err)
 		}
+		// Boot time changed since a recorded run, so this is a genuine reboot
+		if err := s.state.Set(s.name, "first_install", false); err != nil {
+			return false, fmt.Errorf("failed to save first install flag: %w", err)
+		}
 	}
 
 	return isFirstRun, nil
 }
 
+// IsFirstInstall returns true if the most recent firing was the first run ever
+// (no prior boot time in state) rather than a run after a reboot
+func (s *BootTrigger) IsFirstInstall() bool {
+	if val, ok := s.state.Get(s.name, "first_install"); ok {
+		if firstInstall, ok := val.(bool); ok {
+			return firstInstall
+		}
+	}
+	return false
+}
+
+// Env returns BRUN_BOOT_FIRST_INSTALL, "true" if the most recent firing was
+// the first run ever and "false" after a reboot, for the units it triggers
+func (s *BootTrigger) Env() map[string]string {
+	return map[string]string{"BRUN_BOOT_FIRST_INSTALL": strconv.FormatBool(s.IsFirstInstall())}
+}
+
 // OnSuccess returns the list of units to trigger on success
 func (s *BootTrigger) OnSuccess() []string {
 	return s.onSuccess
@@ -132,6 +148,11 @@ func (s *BootTrigger) Run(ctx context.Context) error {
 		}
 	}
 
+	if s.IsFirstInstall() {
+		fmt.Printf("Boot trigger '%s' activated (first install, boot count: %d)\n", s.name, bootCount)
+		return nil
+	}
+
 	fmt.Printf("Boot trigger '%s' activated (boot count: %d)\n", s.name, bootCount)
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -116,6 +117,90 @@ func TestBootTrigger_Check(t *testing.T) {
 	}
 }
 
+func TestBootTrigger_IsFirstInstall(t *testing.T) {
+	tempDir := t.TempDir()
+	stateFile := filepath.Join(tempDir, "state.yaml")
+
+	state := NewState(stateFile)
+	trigger := NewBootTrigger("test-boot", state, nil, nil, nil)
+
+	ctx := context.Background()
+
+	// No prior state, first check is a first install
+	triggered, err := trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !triggered {
+		t.Error("Expected trigger to activate on first check")
+	}
+	if !trigger.IsFirstInstall() {
+		t.Error("Expected first install on first check")
+	}
+
+	// Simulate a reboot by moving the recorded boot time into the past
+	pastBoot := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+	if err := state.SetString("test-boot", "last_boot_time", pastBoot); err != nil {
+		t.Fatalf("Failed to set boot time: %v", err)
+	}
+
+	triggered, err = trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed after reboot: %v", err)
+	}
+	if !triggered {
+		t.Error("Expected trigger to activate after reboot")
+	}
+	if trigger.IsFirstInstall() {
+		t.Error("Expected reboot to not be a first install")
+	}
+
+	if count, _ := state.Get("test-boot", "boot_count"); count != 2 {
+		t.Errorf("Expected boot count 2, got %v", count)
+	}
+	if env := trigger.Env(); env["BRUN_BOOT_FIRST_INSTALL"] != "false" {
+		t.Errorf("Expected BRUN_BOOT_FIRST_INSTALL=false, got %v", env)
+	}
+
+	// A corrupted boot time is a reboot, not a first install
+	if err := state.SetString("test-boot", "last_boot_time", "garbage"); err != nil {
+		t.Fatalf("Failed to set boot time: %v", err)
+	}
+	triggered, err = trigger.Check(ctx, CheckModePolling)
+	if err != nil || !triggered {
+		t.Fatalf("Expected trigger after invalid boot time, got %v, %v", triggered, err)
+	}
+	if trigger.IsFirstInstall() {
+		t.Error("Expected invalid boot time to not be a first install")
+	}
+	if count, _ := state.Get("test-boot", "boot_count"); count != 3 {
+		t.Errorf("Expected boot count 3, got %v", count)
+	}
+}
+
+// TestBootTrigger_EnvInChain verifies that units triggered by a boot trigger
+// see whether it was a first install
+func TestBootTrigger_EnvInChain(t *testing.T) {
+	tempDir := t.TempDir()
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	outFile := filepath.Join(tempDir, "out.txt")
+
+	boot := NewBootTrigger("boot", state, []string{"alert"}, nil, nil)
+	alert := NewRunUnit("alert", `echo "$BRUN_BOOT_FIRST_INSTALL" > `+outFile, tempDir, 0, "", false, nil, nil, nil)
+	orchestrator := NewOrchestrator([]Unit{boot, alert})
+
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+	out, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Expected alert to run: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "true" {
+		t.Errorf("Expected BRUN_BOOT_FIRST_INSTALL=true, got %q", out)
+	}
+}
+
 func TestBootTrigger_Run(t *testing.T) {
 	tempDir := t.TempDir()
 	stateFile := filepath.Join(tempDir, "state.yaml")
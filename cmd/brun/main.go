@@ -4,16 +4,19 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
+	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/cbrake/brun"
 	"github.com/oklog/run"
 )
 
-var version = "dev"
-
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
@@ -24,10 +27,20 @@ func main() {
 	args := os.Args[2:]
 
 	switch command {
+	case "doctor":
+		cmdDoctor(args)
 	case "install":
 		cmdInstall(args)
+	case "logs":
+		cmdLogs(args)
+	case "next":
+		cmdNext(args)
 	case "run":
 		cmdRun(args)
+	case "state":
+		cmdState(args)
+	case "status":
+		cmdStatus(args)
 	case "update":
 		cmdUpdate(args)
 	case "version":
@@ -42,8 +55,15 @@ func main() {
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [OPTIONS]\n\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "Commands:\n")
-	fmt.Fprintf(os.Stderr, "  run <config-file>       Run brun with the given config file\n")
+	fmt.Fprintf(os.Stderr, "  run <config-file>       Run brun with the given config file or directory\n")
+	fmt.Fprintf(os.Stderr, "  state <config-file>     Show the persisted state of all units\n")
+	fmt.Fprintf(os.Stderr, "  state reset <config-file> <unit>\n")
+	fmt.Fprintf(os.Stderr, "                          Clear the persisted state of a unit\n")
+	fmt.Fprintf(os.Stderr, "  status [config-file]    Show whether the service is running and a summary of its state\n")
+	fmt.Fprintf(os.Stderr, "  next <config-file>      Show when each trigger fires next, without changing state\n")
+	fmt.Fprintf(os.Stderr, "  doctor <config-file>    Check the environment for the configured units\n")
 	fmt.Fprintf(os.Stderr, "  install                 Install brun as a systemd service\n")
+	fmt.Fprintf(os.Stderr, "  logs [-f]               Show the logs of the installed service\n")
 	fmt.Fprintf(os.Stderr, "  update                  Updates BRun to the latest version\n")
 	fmt.Fprintf(os.Stderr, "  version                 Display version information\n")
 	fmt.Fprintf(os.Stderr, "\n")
@@ -51,37 +71,78 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "  -daemon                 Run in daemon mode (continuous monitoring)\n")
 	fmt.Fprintf(os.Stderr, "  -unit <name>            Run a single unit (triggers disabled, useful for debugging)\n")
 	fmt.Fprintf(os.Stderr, "  -trigger <name>         Trigger a unit and execute its on_success triggers\n")
+	fmt.Fprintf(os.Stderr, "  -reset-state <name>     Clear a unit's state before running so it re-baselines\n")
+	fmt.Fprintf(os.Stderr, "  -state <path>           Use this state file instead of config.state_location\n")
+	fmt.Fprintf(os.Stderr, "  -overlay <file>         Merge a config onto the config file by unit name (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  -skip-trigger <name>    Don't check a trigger, e.g. when a systemd timer runs it (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  -no-check               With -trigger, run the trigger without checking its condition\n")
+	fmt.Fprintf(os.Stderr, "  -timer <name>           Run a trigger and its units from a systemd timer, without checking its condition\n")
+	fmt.Fprintf(os.Stderr, "  -allow-destructive      Run reboot and destructive units with -unit and -trigger\n")
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "State Options:\n")
+	fmt.Fprintf(os.Stderr, "  -json                   Show state as JSON instead of YAML\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Install Options:\n")
 	fmt.Fprintf(os.Stderr, "  -daemon                 Install service in daemon mode (continuous monitoring)\n")
+	fmt.Fprintf(os.Stderr, "  -force                  Overwrite the service file even if the service is active\n")
+	fmt.Fprintf(os.Stderr, "  -use-systemd-timers     Run cron triggers from systemd timers instead of the service\n")
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "Logs Options:\n")
+	fmt.Fprintf(os.Stderr, "  -f                      Follow new log entries\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Examples:\n")
 	fmt.Fprintf(os.Stderr, "  %s run config.yaml\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s run config.yaml -daemon\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s run config.yaml -unit my-build\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s run config.yaml -reset-state my-git-trigger\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s run config.yaml -state /tmp/test-state.yaml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s run base.yaml -overlay prod.yaml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s run /etc/brun/conf.d/\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s state config.yaml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s state reset config.yaml my-git-trigger\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s status\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s next config.yaml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s doctor config.yaml\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s install\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s install -daemon\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s install -daemon -use-systemd-timers\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s logs -f\n", os.Args[0])
 }
 
 func cmdInstall(args []string) {
 	fs := flag.NewFlagSet("install", flag.ExitOnError)
 	daemonMode := fs.Bool("daemon", false, "Install service in daemon mode (continuous monitoring)")
+	force := fs.Bool("force", false, "Overwrite the service file even if the service is active")
+	systemdTimers := fs.Bool("use-systemd-timers", false, "Run cron triggers from systemd timers instead of the service")
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
 	}
 
-	if err := brun.Install(*daemonMode); err != nil {
+	if err := brun.Install(*daemonMode, *force, *systemdTimers); err != nil {
 		fmt.Fprintf(os.Stderr, "Installation failed: %v\n", err)
 		os.Exit(1)
 	}
 	fmt.Println("Installation completed successfully")
 }
 
+func cmdLogs(args []string) {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := fs.Bool("f", false, "Follow new log entries")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if err := brun.Logs(*follow); err != nil {
+		fmt.Fprintf(os.Stderr, "Error showing logs: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func cmdRun(args []string) {
-	log.Printf("BRun version %s\n", version)
+	log.Printf("BRun version %s\n", brun.Version())
 
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s run <config-file> [-daemon] [-unit <unit name>] [-trigger <unit name>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s run <config-file> [-daemon] [-unit <unit name>] [-trigger <unit name>] [-reset-state <unit name>] [-state <path>] [-overlay <file>] [-skip-trigger <unit name>] [-no-check] [-timer <unit name>] [-allow-destructive]\n", os.Args[0])
 		os.Exit(1)
 	}
 
@@ -91,6 +152,15 @@ func cmdRun(args []string) {
 	daemonMode := fs.Bool("daemon", false, "Run in daemon mode (continuous monitoring)")
 	singleUnit := fs.String("unit", "", "Run a single unit (triggers disabled, useful for debugging)")
 	triggerUnit := fs.String("trigger", "", "Trigger a unit and execute its on_success triggers")
+	resetState := fs.String("reset-state", "", "Clear a unit's state before running so it re-baselines")
+	stateFile := fs.String("state", "", "Use this state file instead of config.state_location")
+	allowDestructive := fs.Bool("allow-destructive", false, "Run reboot and destructive units with -unit and -trigger")
+	var overlays stringList
+	fs.Var(&overlays, "overlay", "Merge this config onto the config file by unit name (repeatable)")
+	noCheck := fs.Bool("no-check", false, "With -trigger, run the trigger without checking its condition")
+	timerTrigger := fs.String("timer", "", "Run a trigger and its units from a systemd timer, without checking its condition")
+	var skipTriggers stringList
+	fs.Var(&skipTriggers, "skip-trigger", "Don't check this trigger, e.g. when a systemd timer runs it (repeatable)")
 	if err := fs.Parse(args[1:]); err != nil {
 		os.Exit(1)
 	}
@@ -100,14 +170,31 @@ func cmdRun(args []string) {
 		fmt.Fprintf(os.Stderr, "Error: -unit and -trigger cannot be used together\n")
 		os.Exit(1)
 	}
-
-	// Load configuration
-	config, err := brun.LoadConfig(configFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+	if *timerTrigger != "" && (*singleUnit != "" || *triggerUnit != "") {
+		fmt.Fprintf(os.Stderr, "Error: -timer cannot be used with -unit or -trigger\n")
 		os.Exit(1)
 	}
 
+	config := loadConfig(configFile, overlays...)
+
+	// Override the state file, e.g. to test a config against throwaway state
+	if *stateFile != "" {
+		config.ConfigBlock.StateLocation = *stateFile
+	}
+
+	// Clear state before units load it
+	if *resetState != "" {
+		if !slices.Contains(config.UnitNames(), *resetState) {
+			fmt.Fprintf(os.Stderr, "Error: unit '%s' not found in config\n", *resetState)
+			os.Exit(1)
+		}
+		if err := loadState(config).Delete(*resetState); err != nil {
+			fmt.Fprintf(os.Stderr, "Error resetting state: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("State for unit '%s' reset\n", *resetState)
+	}
+
 	// Create units from configuration
 	units, err := config.CreateUnits()
 	if err != nil {
@@ -117,8 +204,68 @@ func cmdRun(args []string) {
 
 	fmt.Printf("Loaded %d unit(s)\n", len(units))
 
+	cycleTimeout, err := config.GetCycleTimeout()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	pollInterval, err := config.GetPollInterval()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	maxRuntime, err := config.GetMaxRuntime()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	cooldowns, err := config.UnitCooldowns()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	pollIntervals, err := config.UnitPollIntervals()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	backoffs, err := config.UnitFailureBackoffs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	forcedTriggers, err := config.UnitForcedTriggers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create orchestrator
 	orchestrator := brun.NewOrchestrator(units)
+	orchestrator.SetCooldowns(cooldowns, config.State())
+	orchestrator.SetFailureBackoffs(backoffs, config.State())
+	orchestrator.SetEdgeTriggers(config.EdgeTriggerUnits(), config.State())
+	orchestrator.SetErrorTriggers(config.UnitErrorTriggers())
+	orchestrator.SetPropagateFailure(config.PropagateFailureUnits())
+	orchestrator.SetForcedTriggers(forcedTriggers)
+	orchestrator.SetCycleTimeout(cycleTimeout)
+	orchestrator.SetPollInterval(pollInterval)
+	orchestrator.SetPollIntervals(pollIntervals)
+	orchestrator.SetSkipTriggers(skipTriggers)
+	orchestrator.SetMaxRuntime(maxRuntime)
+	orchestrator.SetUnitArtifacts(config.UnitArtifacts())
+	orchestrator.SetUnitPriorities(config.UnitPriorities())
+	orchestrator.SetUnitDescriptions(config.UnitDescriptions())
+	orchestrator.SetDestructiveUnits(config.DestructiveUnits())
+	orchestrator.SetAllowDestructive(*allowDestructive)
+	orchestrator.SetSkipSingleCheck(*noCheck)
+	orchestrator.SetGlobalTriggers(config.ConfigBlock.OnAnySuccess, config.ConfigBlock.OnAnyFailure)
 
 	// Handle single unit execution (no triggers)
 	if *singleUnit != "" {
@@ -144,8 +291,18 @@ func cmdRun(args []string) {
 		return
 	}
 
+	// Handle a trigger started by its systemd timer
+	if *timerTrigger != "" {
+		if err := orchestrator.RunTimerTrigger(context.Background(), *timerTrigger); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running trigger '%s': %v\n", *timerTrigger, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Configure daemon mode
 	orchestrator.SetDaemonMode(*daemonMode)
+	orchestrator.SetLifecycleHooks(config.ConfigBlock.OnStart, config.ConfigBlock.OnShutdown)
 	if *daemonMode {
 		fmt.Println("Running in daemon mode (press Ctrl+C to stop)...")
 	}
@@ -168,14 +325,201 @@ func cmdRun(args []string) {
 	}
 }
 
+func cmdState(args []string) {
+	if len(args) > 0 && args[0] == "reset" {
+		cmdStateReset(args[1:])
+		return
+	}
+
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s state <config-file> [-json]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("state", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Show state as JSON instead of YAML")
+	if err := fs.Parse(args[1:]); err != nil {
+		os.Exit(1)
+	}
+
+	state := loadState(loadConfig(args[0]))
+
+	data, err := state.Dump(*jsonOutput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+}
+
+func cmdStateReset(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s state reset <config-file> <unit>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	state := loadState(loadConfig(args[0]))
+	unitName := args[1]
+
+	if !slices.Contains(state.Units(), unitName) {
+		fmt.Printf("No state stored for unit '%s'\n", unitName)
+		return
+	}
+
+	if err := state.Delete(unitName); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resetting state: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("State for unit '%s' reset\n", unitName)
+}
+
+func cmdStatus(args []string) {
+	var configFile string
+	if len(args) > 0 {
+		configFile = args[0]
+	} else {
+		var err error
+		configFile, err = brun.DefaultConfigPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("Service: %s\n", brun.ServiceStatus())
+	fmt.Printf("Version: %s\n", brun.Version())
+	fmt.Printf("Config:  %s\n", configFile)
+
+	config := loadConfig(configFile)
+	fmt.Printf("State:   %s\n", config.ConfigBlock.StateLocation)
+
+	summary := loadState(config).Summary()
+	if len(summary) == 0 {
+		fmt.Println("\nNo state stored yet")
+		return
+	}
+	fmt.Println()
+	for _, line := range summary {
+		fmt.Println(line)
+	}
+}
+
+func cmdNext(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s next <config-file>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	config := loadConfig(args[0])
+
+	// Checks log as they would in a run; only the report is of interest
+	log.SetOutput(io.Discard)
+	forecasts, err := config.Next(context.Background(), time.Now())
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(forecasts) == 0 {
+		fmt.Println("No triggers configured")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range forecasts {
+		var status string
+		switch {
+		case f.Err != nil:
+			status = fmt.Sprintf("check failed: %v", f.Err)
+		case !f.Next.IsZero():
+			status = "next fires at " + f.Next.Format("2006-01-02 15:04:05")
+			if !f.Last.IsZero() {
+				status += " (last scheduled run " + f.Last.Format("2006-01-02 15:04:05") + ")"
+			}
+		case f.Note != "":
+			status = f.Note
+		case f.Fire:
+			status = "would fire now"
+		default:
+			status = "would not fire now"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Unit, f.Type, status)
+	}
+	w.Flush()
+}
+
+// loadConfig loads the config file, exiting on error
+func loadConfig(configFile string, overlays ...string) *brun.Config {
+	config, err := brun.LoadConfig(configFile, overlays...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return config
+}
+
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// loadState loads the state file referenced by the config, exiting on error
+func loadState(config *brun.Config) *brun.State {
+	if config.ConfigBlock.StateLocation == "" {
+		fmt.Fprintf(os.Stderr, "Error: config.state_location is required in config file\n")
+		os.Exit(1)
+	}
+
+	state := brun.NewState(config.ConfigBlock.StateLocation)
+	if err := state.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		os.Exit(1)
+	}
+
+	return state
+}
+
 func cmdUpdate(args []string) {
-	if err := brun.Update(version); err != nil {
+	if err := brun.Update(brun.Version()); err != nil {
 		fmt.Fprintf(os.Stderr, "Update failed: %v\n", err)
 		os.Exit(1)
 	}
 	fmt.Println("Update completed successfully")
 }
 
+func cmdDoctor(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s doctor <config-file>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	config := loadConfig(args[0])
+
+	failed := 0
+	for _, check := range config.Doctor(context.Background()) {
+		if check.Err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", check.Name, check.Err)
+		} else {
+			fmt.Printf("PASS  %s\n", check.Name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\nAll checks passed")
+}
+
 func cmdVersion() {
-	fmt.Printf("%s\n", version)
+	fmt.Printf("%s\n", brun.Version())
 }
@@ -0,0 +1,102 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// ComposeConfig represents the configuration for a Compose unit
+type ComposeConfig struct {
+	UnitConfig `yaml:",inline"`
+	Directory  string   `yaml:"directory"`
+	Action     string   `yaml:"action,omitempty"`
+	Services   []string `yaml:"services,omitempty"`
+}
+
+// ComposeUnit runs docker compose up, down, or restart for a project
+type ComposeUnit struct {
+	name      string
+	directory string
+	action    string
+	services  []string
+	command   string // docker binary, overridable for tests
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// NewComposeUnit creates a new Compose unit. directory is the compose project
+// directory and action is "up" (the default), "down", or "restart". If
+// services is empty, the action applies to all services in the project.
+func NewComposeUnit(name, directory, action string, services []string, onSuccess, onFailure, always []string) *ComposeUnit {
+	if action == "" {
+		action = "up"
+	}
+	return &ComposeUnit{
+		name:      name,
+		directory: directory,
+		action:    action,
+		services:  services,
+		command:   "docker",
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}
+}
+
+// Name returns the unit name
+func (c *ComposeUnit) Name() string {
+	return c.name
+}
+
+// Type returns the unit type
+func (c *ComposeUnit) Type() string {
+	return "compose"
+}
+
+// args returns the docker arguments for the configured action
+func (c *ComposeUnit) args() []string {
+	args := []string{"compose", "--project-directory", c.directory, c.action}
+	if c.action == "up" {
+		args = append(args, "-d")
+	}
+	return append(args, c.services...)
+}
+
+// Run executes docker compose with the configured action
+func (c *ComposeUnit) Run(ctx context.Context) error {
+	log.Printf("Running compose unit '%s' (%s in %s)", c.name, c.action, c.directory)
+
+	cmd := exec.CommandContext(ctx, c.command, c.args()...)
+	cmd.Dir = c.directory
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("docker compose %s exited with code %d", c.action, exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run docker compose: %w", err)
+	}
+
+	log.Printf("Compose unit '%s' completed successfully", c.name)
+	return nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (c *ComposeUnit) OnSuccess() []string {
+	return c.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (c *ComposeUnit) OnFailure() []string {
+	return c.onFailure
+}
+
+// Always returns the list of units to always trigger
+func (c *ComposeUnit) Always() []string {
+	return c.always
+}
@@ -0,0 +1,115 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDocker writes a docker stand-in that records its arguments and exits
+// with the given code
+func fakeDocker(t *testing.T, exitCode string) (command, argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	command = filepath.Join(dir, "docker")
+	argsFile = filepath.Join(dir, "args")
+
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\necho pulling\nexit " + exitCode + "\n"
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake docker: %v", err)
+	}
+	return command, argsFile
+}
+
+func TestComposeUnit_Run(t *testing.T) {
+	projectDir := t.TempDir()
+
+	tests := []struct {
+		action   string
+		services []string
+		expected string
+	}{
+		{"", nil, "compose --project-directory " + projectDir + " up -d"},
+		{"up", []string{"web", "db"}, "compose --project-directory " + projectDir + " up -d web db"},
+		{"down", nil, "compose --project-directory " + projectDir + " down"},
+		{"restart", []string{"web"}, "compose --project-directory " + projectDir + " restart web"},
+	}
+
+	for _, tt := range tests {
+		command, argsFile := fakeDocker(t, "0")
+		unit := NewComposeUnit("deploy", projectDir, tt.action, tt.services, nil, nil, nil)
+		unit.command = command
+
+		if err := unit.Run(context.Background()); err != nil {
+			t.Fatalf("Run(%q) failed: %v", tt.action, err)
+		}
+
+		args, err := os.ReadFile(argsFile)
+		if err != nil {
+			t.Fatalf("Failed to read args: %v", err)
+		}
+		if strings.TrimSpace(string(args)) != tt.expected {
+			t.Errorf("Expected args %q, got %q", tt.expected, strings.TrimSpace(string(args)))
+		}
+	}
+}
+
+func TestComposeUnit_RunFailure(t *testing.T) {
+	command, _ := fakeDocker(t, "3")
+	unit := NewComposeUnit("deploy", t.TempDir(), "up", nil, nil, nil, nil)
+	unit.command = command
+
+	err := unit.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exited with code 3") {
+		t.Errorf("Expected exit code error, got %v", err)
+	}
+}
+
+func TestCreateUnits_Compose(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - compose:
+      name: deploy
+      directory: /srv/app
+      services:
+        - web
+      on_failure:
+        - teardown
+  - compose:
+      name: teardown
+      directory: /srv/app
+      action: down
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	deploy, ok := units[0].(*ComposeUnit)
+	if !ok {
+		t.Fatalf("Expected ComposeUnit, got %T", units[0])
+	}
+	if deploy.action != "up" || len(deploy.services) != 1 || deploy.OnFailure()[0] != "teardown" {
+		t.Errorf("Unexpected compose unit: %+v", deploy)
+	}
+
+	config.Units[1].Compose.Action = "pull"
+	if _, err := config.CreateUnits(); err == nil || !strings.Contains(err.Error(), "invalid action") {
+		t.Errorf("Expected invalid action error, got %v", err)
+	}
+}
@@ -2,64 +2,683 @@ package brun
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/getsops/sops/v3/decrypt"
+	"github.com/go-git/go-git/v5"
 	"gopkg.in/yaml.v3"
 )
 
 // ConfigBlock represents the config section of the configuration file
 type ConfigBlock struct {
-	StateLocation string `yaml:"state_location"`
+	StateLocation string   `yaml:"state_location"`
+	OnStart       []string `yaml:"on_start,omitempty"`
+	OnShutdown    []string `yaml:"on_shutdown,omitempty"`
+	OnAnySuccess  []string `yaml:"on_any_success,omitempty"`
+	OnAnyFailure  []string `yaml:"on_any_failure,omitempty"`
+	Jitter        string   `yaml:"jitter,omitempty"`
+	CycleTimeout  string   `yaml:"cycle_timeout,omitempty"`
+	PollInterval  string   `yaml:"poll_interval,omitempty"`
+	MaxRuntime    string   `yaml:"max_daemon_runtime,omitempty"`
+	PruneState    bool     `yaml:"prune_state,omitempty"`
+	// Env sets environment variables for every run unit's scripts
+	Env map[string]string `yaml:"env,omitempty"`
+	// QuietHours holds back non-critical notifications during a daily window
+	QuietHours *QuietHoursConfig `yaml:"quiet_hours,omitempty"`
 }
 
 // Config represents the SimplCI configuration file
 type Config struct {
-	ConfigBlock ConfigBlock         `yaml:"config"`
-	Units       []UnitConfigWrapper `yaml:"units"`
+	ConfigBlock ConfigBlock `yaml:"config"`
+	Units       UnitList    `yaml:"units"`
+
+	// state is the state shared by the units, set by CreateUnits
+	state *State
 }
 
 // UnitConfigWrapper wraps different unit configuration types
 type UnitConfigWrapper struct {
-	Boot   *BootConfig   `yaml:"boot,omitempty"`
-	Count  *CountConfig  `yaml:"count,omitempty"`
-	Cron   *CronConfig   `yaml:"cron,omitempty"`
-	Email  *EmailConfig  `yaml:"email,omitempty"`
-	File   *FileConfig   `yaml:"file,omitempty"`
-	Git    *GitConfig    `yaml:"git,omitempty"`
-	Log    *LogConfig    `yaml:"log,omitempty"`
-	Ntfy   *NtfyConfig   `yaml:"ntfy,omitempty"`
-	Reboot *RebootConfig `yaml:"reboot,omitempty"`
-	Run    *RunConfig    `yaml:"run,omitempty"`
-	Start  *StartConfig  `yaml:"start,omitempty"`
+	Aggregate   *AggregateConfig   `yaml:"aggregate,omitempty"`
+	Boot        *BootConfig        `yaml:"boot,omitempty"`
+	Compose     *ComposeConfig     `yaml:"compose,omitempty"`
+	Content     *ContentConfig     `yaml:"content,omitempty"`
+	Copy        *CopyConfig        `yaml:"copy,omitempty"`
+	Count       *CountConfig       `yaml:"count,omitempty"`
+	Cron        *CronConfig        `yaml:"cron,omitempty"`
+	DaemonStart *DaemonStartConfig `yaml:"daemon_start,omitempty"`
+	Digest      *DigestConfig      `yaml:"digest,omitempty"`
+	Disk        *DiskConfig        `yaml:"disk,omitempty"`
+	Email       *EmailConfig       `yaml:"email,omitempty"`
+	Escalation  *EscalationConfig  `yaml:"escalation,omitempty"`
+	File        *FileConfig        `yaml:"file,omitempty"`
+	Git         *GitConfig         `yaml:"git,omitempty"`
+	Journal     *JournalConfig     `yaml:"journal,omitempty"`
+	Log         *LogConfig         `yaml:"log,omitempty"`
+	Ntfy        *NtfyConfig        `yaml:"ntfy,omitempty"`
+	Process     *ProcessConfig     `yaml:"process,omitempty"`
+	Reboot      *RebootConfig      `yaml:"reboot,omitempty"`
+	Run         *RunConfig         `yaml:"run,omitempty"`
+	Start       *StartConfig       `yaml:"start,omitempty"`
+	State       *StateConfig       `yaml:"state,omitempty"`
+}
+
+// unitConfig returns the common configuration of the wrapped unit, or nil if
+// the wrapper is empty
+func (w UnitConfigWrapper) unitConfig() *UnitConfig {
+	switch {
+	case w.Aggregate != nil:
+		return &w.Aggregate.UnitConfig
+	case w.Boot != nil:
+		return &w.Boot.UnitConfig
+	case w.Compose != nil:
+		return &w.Compose.UnitConfig
+	case w.Content != nil:
+		return &w.Content.UnitConfig
+	case w.Copy != nil:
+		return &w.Copy.UnitConfig
+	case w.Count != nil:
+		return &w.Count.UnitConfig
+	case w.Cron != nil:
+		return &w.Cron.UnitConfig
+	case w.DaemonStart != nil:
+		return &w.DaemonStart.UnitConfig
+	case w.Digest != nil:
+		return &w.Digest.UnitConfig
+	case w.Disk != nil:
+		return &w.Disk.UnitConfig
+	case w.Email != nil:
+		return &w.Email.UnitConfig
+	case w.Escalation != nil:
+		return &w.Escalation.UnitConfig
+	case w.File != nil:
+		return &w.File.UnitConfig
+	case w.Git != nil:
+		return &w.Git.UnitConfig
+	case w.Journal != nil:
+		return &w.Journal.UnitConfig
+	case w.Log != nil:
+		return &w.Log.UnitConfig
+	case w.Ntfy != nil:
+		return &w.Ntfy.UnitConfig
+	case w.Process != nil:
+		return &w.Process.UnitConfig
+	case w.Reboot != nil:
+		return &w.Reboot.UnitConfig
+	case w.Run != nil:
+		return &w.Run.UnitConfig
+	case w.Start != nil:
+		return &w.Start.UnitConfig
+	case w.State != nil:
+		return &w.State.UnitConfig
+	}
+	return nil
+}
+
+// typeName returns the unit type, the key of the unit's block in the list
+// form of the units section
+func (w UnitConfigWrapper) typeName() string {
+	switch {
+	case w.Aggregate != nil:
+		return "aggregate"
+	case w.Boot != nil:
+		return "boot"
+	case w.Compose != nil:
+		return "compose"
+	case w.Content != nil:
+		return "content"
+	case w.Copy != nil:
+		return "copy"
+	case w.Count != nil:
+		return "count"
+	case w.Cron != nil:
+		return "cron"
+	case w.DaemonStart != nil:
+		return "daemon_start"
+	case w.Digest != nil:
+		return "digest"
+	case w.Disk != nil:
+		return "disk"
+	case w.Email != nil:
+		return "email"
+	case w.Escalation != nil:
+		return "escalation"
+	case w.File != nil:
+		return "file"
+	case w.Git != nil:
+		return "git"
+	case w.Journal != nil:
+		return "journal"
+	case w.Log != nil:
+		return "log"
+	case w.Ntfy != nil:
+		return "ntfy"
+	case w.Process != nil:
+		return "process"
+	case w.Reboot != nil:
+		return "reboot"
+	case w.Run != nil:
+		return "run"
+	case w.Start != nil:
+		return "start"
+	case w.State != nil:
+		return "state"
+	}
+	return ""
+}
+
+// UnitArtifacts returns the set_artifact declarations of all units keyed by
+// unit name
+func (c *Config) UnitArtifacts() map[string]map[string]string {
+	artifacts := make(map[string]map[string]string)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && len(cfg.SetArtifact) > 0 {
+			artifacts[cfg.Name] = cfg.SetArtifact
+		}
+	}
+	return artifacts
+}
+
+// UnitPriorities returns the non-zero trigger priorities of all units keyed by unit
+// name
+func (c *Config) UnitPriorities() map[string]int {
+	priorities := make(map[string]int)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && cfg.TriggerPriority != 0 {
+			priorities[cfg.Name] = cfg.TriggerPriority
+		}
+	}
+	return priorities
+}
+
+// UnitDescriptions returns the descriptions of all units that set one, keyed
+// by unit name
+func (c *Config) UnitDescriptions() map[string]string {
+	descriptions := make(map[string]string)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && cfg.Description != "" {
+			descriptions[cfg.Name] = cfg.Description
+		}
+	}
+	return descriptions
+}
+
+// DestructiveUnits returns the names of units marked destructive
+func (c *Config) DestructiveUnits() map[string]bool {
+	destructive := make(map[string]bool)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && cfg.Destructive {
+			destructive[cfg.Name] = true
+		}
+	}
+	return destructive
 }
 
+// EdgeTriggerUnits returns the names of units with edge_trigger set
+func (c *Config) EdgeTriggerUnits() map[string]bool {
+	edge := make(map[string]bool)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && cfg.EdgeTrigger {
+			edge[cfg.Name] = true
+		}
+	}
+	return edge
+}
+
+// PropagateFailureUnits returns the names of units with propagate_failure set
+func (c *Config) PropagateFailureUnits() map[string]bool {
+	propagate := make(map[string]bool)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && cfg.PropagateFailure {
+			propagate[cfg.Name] = true
+		}
+	}
+	return propagate
+}
+
+// UnitErrorTriggers returns the on_error lists of all units that set one,
+// keyed by unit name
+func (c *Config) UnitErrorTriggers() map[string][]string {
+	errorTriggers := make(map[string][]string)
+	for _, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg != nil && len(cfg.OnError) > 0 {
+			errorTriggers[cfg.Name] = cfg.OnError
+		}
+	}
+	return errorTriggers
+}
+
+// UnitForcedTriggers returns the force lists of all units that set one, keyed
+// by unit name. Each forced unit must be one the unit triggers.
+func (c *Config) UnitForcedTriggers() (map[string][]string, error) {
+	forced := make(map[string][]string)
+	for i, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg == nil || len(cfg.Force) == 0 {
+			continue
+		}
+		for _, name := range cfg.Force {
+			if !slices.Contains(cfg.OnSuccess, name) && !slices.Contains(cfg.OnFailure, name) && !slices.Contains(cfg.Always, name) {
+				return nil, fmt.Errorf("unit %d (%s): force '%s' isn't in on_success, on_failure, or always", i, cfg.Name, name)
+			}
+		}
+		forced[cfg.Name] = cfg.Force
+	}
+	return forced, nil
+}
+
+// UnitNames returns the names of all configured units
+func (c *Config) UnitNames() []string {
+	var names []string
+	for _, wrapper := range c.Units {
+		if cfg := wrapper.unitConfig(); cfg != nil {
+			names = append(names, cfg.Name)
+		}
+	}
+	return names
+}
+
+// PollingTriggers returns the names of trigger units that fire on conditions
+// found by polling, such as cron schedules or file changes, as opposed to boot
+// and start triggers, which fire once per run. Passive git triggers (without
+// poll) are only checked when triggered, so they aren't included.
+func (c *Config) PollingTriggers() []string {
+	var names []string
+	for _, wrapper := range c.Units {
+		switch {
+		case wrapper.Git != nil && wrapper.Git.Poll == "":
+		case wrapper.Content != nil, wrapper.Cron != nil, wrapper.Disk != nil,
+			wrapper.File != nil, wrapper.Git != nil, wrapper.Journal != nil,
+			wrapper.Process != nil, wrapper.State != nil:
+			names = append(names, wrapper.unitConfig().Name)
+		}
+	}
+	return names
+}
+
+// GetMaxRuntime returns the parsed config.max_daemon_runtime, or 0 if not set
+func (c *Config) GetMaxRuntime() (time.Duration, error) {
+	if c.ConfigBlock.MaxRuntime == "" {
+		return 0, nil
+	}
+
+	maxRuntime, err := time.ParseDuration(c.ConfigBlock.MaxRuntime)
+	if err != nil {
+		return 0, fmt.Errorf("config.max_daemon_runtime: invalid format '%s': %w", c.ConfigBlock.MaxRuntime, err)
+	}
+	if maxRuntime <= 0 {
+		return 0, fmt.Errorf("config.max_daemon_runtime: must be positive, got '%s'", c.ConfigBlock.MaxRuntime)
+	}
+	return maxRuntime, nil
+}
+
+// UnitCooldowns returns the parsed cooldowns of all units that set one, keyed
+// by unit name
+func (c *Config) UnitCooldowns() (map[string]time.Duration, error) {
+	cooldowns := make(map[string]time.Duration)
+	for i, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg == nil || cfg.Cooldown == "" {
+			continue
+		}
+		cooldown, err := time.ParseDuration(cfg.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("unit %d (%s): invalid cooldown format '%s': %w", i, cfg.Name, cfg.Cooldown, err)
+		}
+		if cooldown <= 0 {
+			return nil, fmt.Errorf("unit %d (%s): cooldown must be positive, got '%s'", i, cfg.Name, cfg.Cooldown)
+		}
+		cooldowns[cfg.Name] = cooldown
+	}
+	return cooldowns, nil
+}
+
+// UnitFailureBackoffs returns the parsed failure backoffs of all units that
+// set one, keyed by unit name
+func (c *Config) UnitFailureBackoffs() (map[string]FailureBackoff, error) {
+	backoffs := make(map[string]FailureBackoff)
+	for i, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg == nil {
+			continue
+		}
+		if cfg.FailureBackoff == "" {
+			if cfg.FailureBackoffMax != "" {
+				return nil, fmt.Errorf("unit %d (%s): failure_backoff_max requires failure_backoff", i, cfg.Name)
+			}
+			continue
+		}
+
+		initial, err := time.ParseDuration(cfg.FailureBackoff)
+		if err != nil || initial <= 0 {
+			return nil, fmt.Errorf("unit %d (%s): invalid failure_backoff '%s'", i, cfg.Name, cfg.FailureBackoff)
+		}
+		backoff := FailureBackoff{Initial: initial}
+		if cfg.FailureBackoffMax != "" {
+			backoff.Max, err = time.ParseDuration(cfg.FailureBackoffMax)
+			if err != nil || backoff.Max < initial {
+				return nil, fmt.Errorf("unit %d (%s): invalid failure_backoff_max '%s' (must be at least failure_backoff)", i, cfg.Name, cfg.FailureBackoffMax)
+			}
+		}
+		backoffs[cfg.Name] = backoff
+	}
+	return backoffs, nil
+}
+
+// UnitPollIntervals returns the parsed poll intervals of all units that set
+// one, keyed by unit name. Git triggers are left out since they track their
+// own poll interval.
+func (c *Config) UnitPollIntervals() (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration)
+	for i, wrapper := range c.Units {
+		cfg := wrapper.unitConfig()
+		if cfg == nil || cfg.Poll == "" || wrapper.Git != nil {
+			continue
+		}
+		interval, err := time.ParseDuration(cfg.Poll)
+		if err != nil {
+			return nil, fmt.Errorf("unit %d (%s): invalid poll interval format '%s': %w", i, cfg.Name, cfg.Poll, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("unit %d (%s): poll must be positive, got '%s'", i, cfg.Name, cfg.Poll)
+		}
+		intervals[cfg.Name] = interval
+	}
+	return intervals, nil
+}
+
+// State returns the state shared by the units, or nil if CreateUnits hasn't
+// been called
+func (c *Config) State() *State {
+	return c.state
+}
+
+// GetCycleTimeout returns the parsed config.cycle_timeout, or 0 if not set
+func (c *Config) GetCycleTimeout() (time.Duration, error) {
+	if c.ConfigBlock.CycleTimeout == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(c.ConfigBlock.CycleTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("config.cycle_timeout: invalid format '%s': %w", c.ConfigBlock.CycleTimeout, err)
+	}
+	return timeout, nil
+}
+
+// GetPollInterval returns the parsed config.poll_interval, or 0 if not set
+func (c *Config) GetPollInterval() (time.Duration, error) {
+	if c.ConfigBlock.PollInterval == "" {
+		return 0, nil
+	}
+
+	interval, err := time.ParseDuration(c.ConfigBlock.PollInterval)
+	if err != nil {
+		return 0, fmt.Errorf("config.poll_interval: invalid format '%s': %w", c.ConfigBlock.PollInterval, err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("config.poll_interval: must be positive, got '%s'", c.ConfigBlock.PollInterval)
+	}
+	return interval, nil
+}
+
+// configStdin is read when the config path is "-", overridable for tests
+var configStdin io.Reader = os.Stdin
+
 // LoadConfig loads a configuration file from the given path.
 // If the file is encrypted with SOPS, it will be automatically decrypted.
-func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// If path is a directory, the *.yaml files in it are merged into one config;
+// see readConfigDir.
+// A path of "-" reads the config from stdin; its relative paths are resolved
+// against the working directory, and state_location is required.
+// Overlays, e.g. per-environment overrides, are merged onto the config in
+// order before it's parsed; see applyOverlay. Relative paths in an overlay
+// are resolved against the base config's directory.
+func LoadConfig(path string, overlays ...string) (*Config, error) {
+	// Relative paths are resolved against the config file's directory, or
+	// the config directory itself
+	base := filepath.Dir(path)
+
+	var data []byte
+	var err error
+	if info, statErr := os.Stat(path); path != "-" && statErr == nil && info.IsDir() {
+		base = path
+		data, err = readConfigDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory: %w", err)
+		}
+	} else {
+		data, err = readConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	for _, overlay := range overlays {
+		overlayData, err := readConfigFile(overlay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay %s: %w", overlay, err)
+		}
+		data, err = applyOverlay(data, overlayData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply overlay %s: %w", overlay, err)
+		}
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// A config from stdin has no directory to resolve relative paths against
+	if path == "-" {
+		if config.ConfigBlock.StateLocation == "" {
+			return nil, fmt.Errorf("config.state_location is required when reading config from stdin")
+		}
+		return &config, nil
+	}
+
+	config.resolvePaths(base)
+
+	return &config, nil
+}
+
+// readConfigFile reads a config file, or stdin for "-", decrypting it if it's
+// encrypted with SOPS
+func readConfigFile(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(configStdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
 	// Check if file is SOPS-encrypted by looking for sops metadata
 	if bytes.Contains(data, []byte("sops:")) || bytes.Contains(data, []byte("\"sops\":")) {
 		// Decrypt with SOPS
-		cleartext, err := decrypt.File(path, "yaml")
+		cleartext, err := decrypt.Data(data, "yaml")
 		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt config file: %w", err)
+			return nil, fmt.Errorf("failed to decrypt: %w", err)
 		}
 		data = cleartext
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	return data, nil
+}
+
+// resolvePaths makes relative file system paths in the config relative to
+// base (the config file's directory) instead of the working directory
+func (c *Config) resolvePaths(base string) {
+	c.ConfigBlock.StateLocation = resolvePath(base, c.ConfigBlock.StateLocation)
+	for _, w := range c.Units {
+		switch {
+		case w.Compose != nil:
+			w.Compose.Directory = resolvePath(base, w.Compose.Directory)
+		case w.Content != nil:
+			w.Content.File = resolvePath(base, w.Content.File)
+		case w.Copy != nil:
+			w.Copy.Source = resolveLocalPath(base, w.Copy.Source)
+			w.Copy.Dest = resolveLocalPath(base, w.Copy.Dest)
+		case w.Disk != nil:
+			w.Disk.Path = resolvePath(base, w.Disk.Path)
+		case w.Email != nil:
+			w.Email.OutputDir = resolvePath(base, w.Email.OutputDir)
+		case w.File != nil:
+			for i, pattern := range w.File.Pattern {
+				w.File.Pattern[i] = resolvePath(base, pattern)
+			}
+		case w.Git != nil:
+			w.Git.Repository = resolvePath(base, w.Git.Repository)
+		case w.Log != nil:
+			w.Log.File = resolvePath(base, w.Log.File)
+		case w.Ntfy != nil:
+			w.Ntfy.OutputDir = resolvePath(base, w.Ntfy.OutputDir)
+		case w.Run != nil:
+			// A directory starting with a reference, like ${HOME}/builds,
+			// is usually absolute once expanded
+			if !strings.HasPrefix(w.Run.Directory, "${") {
+				w.Run.Directory = resolvePath(base, w.Run.Directory)
+			}
+			w.Run.EnvFile = resolvePath(base, w.Run.EnvFile)
+		}
 	}
+}
 
-	return &config, nil
+// resolvePath returns path joined to base if it is relative. Empty and
+// absolute paths are returned unchanged, and a trailing separator is kept.
+func resolvePath(base, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	resolved := filepath.Join(base, path)
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		resolved += string(filepath.Separator)
+	}
+	return resolved
+}
+
+// resolveLocalPath is like resolvePath but leaves remote rsync paths
+// (host:path) unchanged
+func resolveLocalPath(base, path string) string {
+	if i := strings.Index(path, ":"); i > 0 && !strings.ContainsAny(path[:i], `/\`) && filepath.VolumeName(path) == "" {
+		return path
+	}
+	return resolvePath(base, path)
+}
+
+// parseUnitTimeout parses the timeout of unit i, returning 0 if not set
+func parseUnitTimeout(i int, name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("unit %d (%s): invalid timeout format '%s': %w", i, name, value, err)
+	}
+	return timeout, nil
+}
+
+// StringList is a list of strings that can also be written in YAML as a
+// single string
+type StringList []string
+
+// UnmarshalYAML accepts a scalar or a sequence of strings
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// UnitList is the list of configured units. In YAML it is either a list of
+// single-key maps naming the unit type (- run: {name: build, ...}), or a map
+// keyed by unit name whose values set the type (build: {type: run, ...}).
+type UnitList []UnitConfigWrapper
+
+// UnmarshalYAML accepts the list and map forms of the units section
+func (l *UnitList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		var list []UnitConfigWrapper
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*l = list
+		return nil
+	}
+
+	var units UnitList
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		name, body := value.Content[i].Value, value.Content[i+1]
+
+		var typed struct {
+			Type string `yaml:"type"`
+		}
+		if err := body.Decode(&typed); err != nil {
+			return fmt.Errorf("unit '%s': %w", name, err)
+		}
+		if typed.Type == "" {
+			return fmt.Errorf("unit '%s': type is required", name)
+		}
+
+		// Decode as the list form, {<type>: <body>}
+		node := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: typed.Type},
+			body,
+		}}
+		var wrapper UnitConfigWrapper
+		if err := node.Decode(&wrapper); err != nil {
+			return fmt.Errorf("unit '%s': %w", name, err)
+		}
+
+		cfg := wrapper.unitConfig()
+		if cfg == nil {
+			return fmt.Errorf("unit '%s': unknown type '%s'", name, typed.Type)
+		}
+		if cfg.Name == "" {
+			cfg.Name = name
+		} else if cfg.Name != name {
+			return fmt.Errorf("unit '%s': name '%s' doesn't match its key", name, cfg.Name)
+		}
+		units = append(units, wrapper)
+	}
+	*l = units
+	return nil
+}
+
+// parseFileMode parses an octal permission value such as "0640"
+func parseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("'%s' is not an octal mode between 0000 and 0777", value)
+	}
+	return os.FileMode(mode), nil
 }
 
 // CreateUnits creates unit instances from the configuration
@@ -71,15 +690,51 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 
 	// Create shared state manager
 	state := NewState(c.ConfigBlock.StateLocation)
+	c.state = state
 
 	// Load state once at startup - units should not call Load() individually
 	if err := state.Load(); err != nil {
 		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
 
+	// Drop state left behind by units removed from the config
+	if c.ConfigBlock.PruneState {
+		pruned, err := state.Prune(c.UnitNames())
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune state: %w", err)
+		}
+		for _, name := range pruned {
+			log.Printf("Pruned state for unit '%s' (no longer in config)", name)
+		}
+	}
+
+	// Parse jitter if specified
+	var jitter time.Duration
+	if c.ConfigBlock.Jitter != "" {
+		var err error
+		jitter, err = time.ParseDuration(c.ConfigBlock.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf("config.jitter: invalid format '%s': %w", c.ConfigBlock.Jitter, err)
+		}
+	}
+
+	var quietHours *QuietHours
+	if q := c.ConfigBlock.QuietHours; q != nil {
+		var err error
+		quietHours, err = NewQuietHours(q.Start, q.End, q.Timezone, q.Queue)
+		if err != nil {
+			return nil, fmt.Errorf("config.quiet_hours: %w", err)
+		}
+	}
+
 	var units []Unit
 
 	for i, wrapper := range c.Units {
+		// type is optional in the list form, but must match the block key
+		if cfg := wrapper.unitConfig(); cfg != nil && cfg.Type != "" && cfg.Type != wrapper.typeName() {
+			return nil, fmt.Errorf("unit %d (%s): type '%s' doesn't match its '%s' block", i, cfg.Name, cfg.Type, wrapper.typeName())
+		}
+
 		if wrapper.Start != nil {
 			cfg := wrapper.Start
 			if cfg.Name == "" {
@@ -95,6 +750,21 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 			units = append(units, unit)
 		}
 
+		if wrapper.DaemonStart != nil {
+			cfg := wrapper.DaemonStart
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+
+			unit := NewDaemonStartTrigger(
+				cfg.Name,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			units = append(units, unit)
+		}
+
 		if wrapper.Boot != nil {
 			cfg := wrapper.Boot
 			if cfg.Name == "" {
@@ -120,10 +790,18 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 			unit := NewRebootUnit(
 				cfg.Name,
 				cfg.Delay,
+				state,
 				cfg.OnSuccess,
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			if cfg.PreReboot != "" {
+				timeout, err := parseUnitTimeout(i, cfg.Name, cfg.PreRebootTimeout)
+				if err != nil {
+					return nil, err
+				}
+				unit.SetPreReboot(cfg.PreReboot, timeout, cfg.AbortOnPreFailure)
+			}
 			units = append(units, unit)
 		}
 
@@ -136,14 +814,17 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				return nil, fmt.Errorf("unit %d: script is required", i)
 			}
 
-			// Parse timeout if specified
-			var timeout time.Duration
-			if cfg.Timeout != "" {
-				var err error
-				timeout, err = time.ParseDuration(cfg.Timeout)
-				if err != nil {
-					return nil, fmt.Errorf("unit %d (%s): invalid timeout format '%s': %w", i, cfg.Name, cfg.Timeout, err)
-				}
+			timeout, err := parseUnitTimeout(i, cfg.Name, cfg.Timeout)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg.FailOnStderr && cfg.UsePTY {
+				return nil, fmt.Errorf("unit %d (%s): fail_on_stderr can't be used with use_pty", i, cfg.Name)
+			}
+
+			if cfg.PTYRows < 0 || cfg.PTYRows > 65535 || cfg.PTYCols < 0 || cfg.PTYCols > 65535 {
+				return nil, fmt.Errorf("unit %d (%s): pty_rows and pty_cols must be between 0 and 65535", i, cfg.Name)
 			}
 
 			unit := NewRunUnit(
@@ -157,6 +838,86 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			unit.SetPrePost(cfg.Pre, cfg.Post)
+			unit.SetPTYSize(cfg.PTYRows, cfg.PTYCols)
+			unit.SetFailOnStderr(cfg.FailOnStderr)
+			// The unit's env overrides config.env
+			variables := maps.Clone(c.ConfigBlock.Env)
+			if variables == nil {
+				variables = make(map[string]string)
+			}
+			maps.Copy(variables, cfg.Env)
+			unit.SetVariables(variables)
+			unit.SetEnvFile(cfg.EnvFile)
+			unit.SetState(state)
+			if err := unit.SetRunAs(cfg.RunAsUser, cfg.RunAsGroup); err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
+			if cfg.Umask != "" {
+				umask, err := parseFileMode(cfg.Umask)
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): invalid umask: %w", i, cfg.Name, err)
+				}
+				if !isPOSIXShell(unit.shell) {
+					return nil, fmt.Errorf("unit %d (%s): umask requires a POSIX shell, not %s", i, cfg.Name, unit.shell)
+				}
+				unit.SetUmask(umask)
+			}
+			units = append(units, unit)
+		}
+
+		if wrapper.Compose != nil {
+			cfg := wrapper.Compose
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if cfg.Directory == "" {
+				return nil, fmt.Errorf("unit %d: directory is required", i)
+			}
+			switch cfg.Action {
+			case "", "up", "down", "restart":
+			default:
+				return nil, fmt.Errorf("unit %d (%s): invalid action '%s' (expected up, down, or restart)", i, cfg.Name, cfg.Action)
+			}
+
+			unit := NewComposeUnit(
+				cfg.Name,
+				cfg.Directory,
+				cfg.Action,
+				cfg.Services,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			units = append(units, unit)
+		}
+
+		if wrapper.Copy != nil {
+			cfg := wrapper.Copy
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if cfg.Source == "" {
+				return nil, fmt.Errorf("unit %d: source is required", i)
+			}
+			if cfg.Dest == "" {
+				return nil, fmt.Errorf("unit %d: dest is required", i)
+			}
+			if cfg.Delete && !cfg.Recursive {
+				return nil, fmt.Errorf("unit %d (%s): delete requires recursive", i, cfg.Name)
+			}
+
+			unit := NewCopyUnit(
+				cfg.Name,
+				cfg.Source,
+				cfg.Dest,
+				cfg.Recursive,
+				cfg.Preserve,
+				cfg.Delete,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
 			units = append(units, unit)
 		}
 
@@ -176,6 +937,13 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			if cfg.Mode != "" {
+				mode, err := parseFileMode(cfg.Mode)
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): invalid mode: %w", i, cfg.Name, err)
+				}
+				unit.SetMode(mode)
+			}
 			units = append(units, unit)
 		}
 
@@ -187,6 +955,25 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 			if cfg.Topic == "" {
 				return nil, fmt.Errorf("unit %d: topic is required", i)
 			}
+			if cfg.OutputURL != "" && cfg.OutputDir == "" {
+				return nil, fmt.Errorf("unit %d: output_dir is required with output_url_template", i)
+			}
+			timeout, err := parseUnitTimeout(i, cfg.Name, cfg.Timeout)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(cfg.Actions) > maxNtfyActions {
+				return nil, fmt.Errorf("unit %d (%s): at most %d actions are allowed", i, cfg.Name, maxNtfyActions)
+			}
+			for _, action := range cfg.Actions {
+				if err := action.validate(); err != nil {
+					return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+				}
+			}
+			if err := validateNotificationTemplate(cfg.TitlePrefix); err != nil {
+				return nil, fmt.Errorf("unit %d (%s): invalid title_prefix: %w", i, cfg.Name, err)
+			}
 
 			// Set defaults
 			server := cfg.Server
@@ -212,6 +999,29 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			unit.SetOutputLink(cfg.OutputDir, cfg.OutputURL)
+			unit.SetTimeout(timeout)
+			if cfg.HTTPTimeout != "" {
+				httpTimeout, err := time.ParseDuration(cfg.HTTPTimeout)
+				if err != nil || httpTimeout <= 0 {
+					return nil, fmt.Errorf("unit %d (%s): invalid http_timeout '%s'", i, cfg.Name, cfg.HTTPTimeout)
+				}
+				unit.SetHTTPTimeout(httpTimeout)
+			}
+			unit.SetMarkdown(cfg.Markdown)
+			unit.SetCollapseOutput(cfg.CollapseOutput)
+			unit.SetDelay(cfg.Delay)
+			unit.SetActions(cfg.Actions)
+			unit.SetStderrOnFailure(cfg.StderrOnFailure)
+			unit.SetQuietHours(quietHours, state)
+			unit.SetCritical(cfg.Critical)
+			if cfg.RetryTTL != "" {
+				retryTTL, err := time.ParseDuration(cfg.RetryTTL)
+				if err != nil || retryTTL <= 0 {
+					return nil, fmt.Errorf("unit %d (%s): invalid retry_ttl '%s'", i, cfg.Name, cfg.RetryTTL)
+				}
+				unit.SetOutbox(NewOutbox(retryTTL, state))
+			}
 			units = append(units, unit)
 		}
 
@@ -228,6 +1038,95 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			if cfg.Window != "" {
+				window, err := time.ParseDuration(cfg.Window)
+				if err != nil || window <= 0 {
+					return nil, fmt.Errorf("unit %d (%s): invalid window '%s'", i, cfg.Name, cfg.Window)
+				}
+				unit.SetWindow(window)
+			}
+			units = append(units, unit)
+		}
+
+		if wrapper.Aggregate != nil {
+			cfg := wrapper.Aggregate
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if len(cfg.Units) == 0 {
+				return nil, fmt.Errorf("unit %d (%s): units is required", i, cfg.Name)
+			}
+			if slices.Contains(cfg.Units, cfg.Name) {
+				return nil, fmt.Errorf("unit %d (%s): units can't include the aggregate unit itself", i, cfg.Name)
+			}
+
+			unit := NewAggregateUnit(
+				cfg.Name,
+				cfg.Units,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			units = append(units, unit)
+		}
+
+		if wrapper.Digest != nil {
+			cfg := wrapper.Digest
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if len(cfg.FlushOn) == 0 {
+				return nil, fmt.Errorf("unit %d (%s): flush_on is required", i, cfg.Name)
+			}
+
+			unit := NewDigestUnit(
+				cfg.Name,
+				cfg.FlushOn,
+				state,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			units = append(units, unit)
+		}
+
+		if wrapper.Escalation != nil {
+			cfg := wrapper.Escalation
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if cfg.Unit == "" {
+				return nil, fmt.Errorf("unit %d (%s): unit is required", i, cfg.Name)
+			}
+			if cfg.Unit == cfg.Name {
+				return nil, fmt.Errorf("unit %d (%s): unit can't be the escalation unit itself", i, cfg.Name)
+			}
+			if cfg.Attempts < 0 {
+				return nil, fmt.Errorf("unit %d (%s): attempts must not be negative, got %d", i, cfg.Name, cfg.Attempts)
+			}
+			if cfg.Backoff < 0 {
+				return nil, fmt.Errorf("unit %d (%s): backoff must not be negative, got %g", i, cfg.Name, cfg.Backoff)
+			}
+
+			var delay time.Duration
+			if cfg.Delay != "" {
+				var err error
+				delay, err = time.ParseDuration(cfg.Delay)
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): invalid delay format '%s': %w", i, cfg.Name, cfg.Delay, err)
+				}
+			}
+
+			unit := NewEscalationUnit(
+				cfg.Name,
+				cfg.Unit,
+				cfg.Attempts,
+				delay,
+				cfg.Backoff,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
 			units = append(units, unit)
 		}
 
@@ -240,14 +1139,19 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				return nil, fmt.Errorf("unit %d: schedule is required", i)
 			}
 
-			unit := NewCronTrigger(
+			unit, err := NewCronTrigger(
 				cfg.Name,
 				cfg.Schedule,
 				state,
+				jitter,
 				cfg.OnSuccess,
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			if err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
+			unit.SetSkipIfRunning(cfg.SkipIfRunning)
 			units = append(units, unit)
 		}
 
@@ -265,6 +1169,30 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 			if cfg.SMTPHost == "" {
 				return nil, fmt.Errorf("unit %d: smtp_host is required", i)
 			}
+			if cfg.OutputURL != "" && cfg.OutputDir == "" {
+				return nil, fmt.Errorf("unit %d: output_dir is required with output_url_template", i)
+			}
+			timeout, err := parseUnitTimeout(i, cfg.Name, cfg.Timeout)
+			if err != nil {
+				return nil, err
+			}
+
+			if strings.ContainsAny(cfg.ReplyTo, "\r\n") {
+				return nil, fmt.Errorf("unit %d (%s): reply_to must not contain line breaks", i, cfg.Name)
+			}
+			if err := validateEmailHeaders(cfg.Headers); err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
+
+			if err := validateNotificationTemplate(cfg.SubjectPrefix); err != nil {
+				return nil, fmt.Errorf("unit %d (%s): invalid subject_prefix: %w", i, cfg.Name, err)
+			}
+
+			switch cfg.SMTPAuth {
+			case "", "plain", "login", "cram-md5":
+			default:
+				return nil, fmt.Errorf("unit %d (%s): invalid smtp_auth '%s' (expected plain, login, or cram-md5)", i, cfg.Name, cfg.SMTPAuth)
+			}
 
 			// Set defaults
 			smtpPort := cfg.SMTPPort
@@ -298,6 +1226,22 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			unit.SetOutputLink(cfg.OutputDir, cfg.OutputURL)
+			unit.SetTimeout(timeout)
+			unit.SetAuthMechanism(cfg.SMTPAuth)
+			unit.SetHeaders(cfg.ReplyTo, cfg.Headers)
+			unit.SetHTML(cfg.HTML)
+			unit.SetCollapseOutput(cfg.CollapseOutput)
+			unit.SetStderrOnFailure(cfg.StderrOnFailure)
+			unit.SetQuietHours(quietHours, state)
+			unit.SetCritical(cfg.Critical)
+			if cfg.RetryTTL != "" {
+				retryTTL, err := time.ParseDuration(cfg.RetryTTL)
+				if err != nil || retryTTL <= 0 {
+					return nil, fmt.Errorf("unit %d (%s): invalid retry_ttl '%s'", i, cfg.Name, cfg.RetryTTL)
+				}
+				unit.SetOutbox(NewOutbox(retryTTL, state))
+			}
 			units = append(units, unit)
 		}
 
@@ -306,18 +1250,171 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 			if cfg.Name == "" {
 				return nil, fmt.Errorf("unit %d: name is required", i)
 			}
-			if cfg.Pattern == "" {
+			if len(cfg.Pattern) == 0 || slices.Contains(cfg.Pattern, "") {
 				return nil, fmt.Errorf("unit %d: pattern is required", i)
 			}
 
 			unit := NewFileTrigger(
 				cfg.Name,
+				cfg.Pattern[0],
+				state,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			unit.SetPatterns(cfg.Pattern)
+			if cfg.InitialTrigger != nil {
+				unit.SetInitialTrigger(*cfg.InitialTrigger)
+			}
+			unit.SetFollowSymlinks(cfg.FollowSymlinks)
+			units = append(units, unit)
+		}
+
+		if wrapper.Content != nil {
+			cfg := wrapper.Content
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if cfg.File == "" {
+				return nil, fmt.Errorf("unit %d: file is required", i)
+			}
+			if cfg.Pattern == "" {
+				return nil, fmt.Errorf("unit %d: pattern is required", i)
+			}
+
+			unit, err := NewContentTrigger(
+				cfg.Name,
+				cfg.File,
+				cfg.Pattern,
+				state,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
+			units = append(units, unit)
+		}
+
+		if wrapper.Process != nil {
+			cfg := wrapper.Process
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+
+			targets := 0
+			for _, set := range []bool{cfg.PIDFile != "", cfg.ProcessName != "", cfg.TCPPort != 0} {
+				if set {
+					targets++
+				}
+			}
+			if targets != 1 {
+				return nil, fmt.Errorf("unit %d (%s): exactly one of pid_file, process_name, or tcp_port is required", i, cfg.Name)
+			}
+			if cfg.TCPPort < 0 || cfg.TCPPort > 65535 {
+				return nil, fmt.Errorf("unit %d (%s): invalid tcp_port %d", i, cfg.Name, cfg.TCPPort)
+			}
+
+			unit := NewProcessTrigger(
+				cfg.Name,
+				cfg.PIDFile,
+				cfg.ProcessName,
+				cfg.TCPPort,
+				state,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			units = append(units, unit)
+		}
+
+		if wrapper.State != nil {
+			cfg := wrapper.State
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if cfg.Unit == "" || cfg.Key == "" {
+				return nil, fmt.Errorf("unit %d (%s): unit and key are required", i, cfg.Name)
+			}
+			if cfg.Value == "" {
+				return nil, fmt.Errorf("unit %d (%s): value is required", i, cfg.Name)
+			}
+
+			unit, err := NewStateTrigger(
+				cfg.Name,
+				cfg.Unit,
+				cfg.Key,
+				cfg.Compare,
+				cfg.Value,
+				state,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
+			units = append(units, unit)
+		}
+
+		if wrapper.Disk != nil {
+			cfg := wrapper.Disk
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			if cfg.Path == "" {
+				return nil, fmt.Errorf("unit %d: path is required", i)
+			}
+			if cfg.Threshold == "" {
+				return nil, fmt.Errorf("unit %d: threshold is required", i)
+			}
+
+			var cooldown time.Duration
+			if cfg.Cooldown != "" {
+				var err error
+				cooldown, err = time.ParseDuration(cfg.Cooldown)
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): invalid cooldown format '%s': %w", i, cfg.Name, cfg.Cooldown, err)
+				}
+			}
+
+			unit, err := NewDiskTrigger(
+				cfg.Name,
+				cfg.Path,
+				cfg.Threshold,
+				cooldown,
+				state,
+				cfg.OnSuccess,
+				cfg.OnFailure,
+				cfg.Always,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
+			units = append(units, unit)
+		}
+
+		if wrapper.Journal != nil {
+			cfg := wrapper.Journal
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+
+			unit, err := NewJournalTrigger(
+				cfg.Name,
+				cfg.SystemdUnit,
+				cfg.Priority,
+				cfg.Matches,
 				cfg.Pattern,
 				state,
 				cfg.OnSuccess,
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			if err != nil {
+				return nil, fmt.Errorf("unit %d (%s): %w", i, cfg.Name, err)
+			}
 			units = append(units, unit)
 		}
 
@@ -329,20 +1426,54 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 			if cfg.Repository == "" {
 				return nil, fmt.Errorf("unit %d: repository is required", i)
 			}
-			if cfg.Branch == "" {
-				return nil, fmt.Errorf("unit %d: branch is required", i)
+			// Without a branch, the default branch is detected from the
+			// workspace's origin, so the repository must be a local workspace
+			if cfg.Branch == "" || cfg.Branch == "HEAD" {
+				repo, err := git.PlainOpen(cfg.Repository)
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): branch is required unless repository is a local git workspace: %w", i, cfg.Name, err)
+				}
+				// Without origin, the checked out branch is used. Detecting
+				// origin's HEAD branch needs the network, so it's left to the
+				// first check.
+				_, err = repo.Remote("origin")
+				if err == git.ErrRemoteNotFound {
+					_, err = defaultBranch(context.Background(), repo)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): can't detect the default branch: %w", i, cfg.Name, err)
+				}
 			}
 
-			// Parse poll interval if specified
+			for _, pattern := range cfg.Paths {
+				if !doublestar.ValidatePattern(pattern) {
+					return nil, fmt.Errorf("unit %d (%s): invalid paths pattern '%s'", i, cfg.Name, pattern)
+				}
+			}
+
+			if cfg.FetchDepth < 0 {
+				return nil, fmt.Errorf("unit %d (%s): fetch_depth must not be negative", i, cfg.Name)
+			}
+
+			// Parse poll interval if specified. A state or env reference
+			// must resolve at startup and is read again at each check.
 			var pollInterval time.Duration
 			if cfg.Poll != "" {
-				var err error
-				pollInterval, err = time.ParseDuration(cfg.Poll)
+				poll, err := resolveDynamic(cfg.Poll, state)
+				if err != nil {
+					return nil, fmt.Errorf("unit %d (%s): poll: %w", i, cfg.Name, err)
+				}
+				pollInterval, err = time.ParseDuration(poll)
 				if err != nil {
-					return nil, fmt.Errorf("unit %d (%s): invalid poll interval format '%s': %w", i, cfg.Name, cfg.Poll, err)
+					return nil, fmt.Errorf("unit %d (%s): invalid poll interval format '%s': %w", i, cfg.Name, poll, err)
 				}
 			}
 
+			timeout, err := parseUnitTimeout(i, cfg.Name, cfg.Timeout)
+			if err != nil {
+				return nil, err
+			}
+
 			unit := NewGitTrigger(
 				cfg.Name,
 				cfg.Repository,
@@ -355,6 +1486,21 @@ func (c *Config) CreateUnits() ([]Unit, error) {
 				cfg.OnFailure,
 				cfg.Always,
 			)
+			if isDynamicRef(cfg.Poll) {
+				unit.SetPollRef(cfg.Poll)
+			}
+			unit.SetFetchOptions(cfg.FetchDepth, cfg.FetchRefspec)
+			unit.SetPaths(cfg.Paths)
+			if cfg.InitialTrigger != nil {
+				unit.SetInitialTrigger(*cfg.InitialTrigger)
+			}
+			if cfg.UseNativeGit != nil {
+				unit.SetNativeGit(*cfg.UseNativeGit)
+			}
+			if cfg.Submodules != nil {
+				unit.SetSubmodules(*cfg.Submodules)
+			}
+			unit.SetTimeout(timeout)
 			units = append(units, unit)
 		}
 		// Add other unit types here as they are implemented
@@ -2,9 +2,13 @@ package brun
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -66,6 +70,21 @@ units:
 	}
 }
 
+func TestConfig_UnitNames(t *testing.T) {
+	config := &Config{
+		Units: []UnitConfigWrapper{
+			{Boot: &BootConfig{UnitConfig: UnitConfig{Name: "boot"}}},
+			{Run: &RunConfig{UnitConfig: UnitConfig{Name: "build"}, Script: "make"}},
+			{Git: &GitConfig{UnitConfig: UnitConfig{Name: "repo"}}},
+		},
+	}
+
+	names := config.UnitNames()
+	if !slices.Equal(names, []string{"boot", "build", "repo"}) {
+		t.Errorf("UnitNames() = %v, want [boot build repo]", names)
+	}
+}
+
 func TestCreateUnits(t *testing.T) {
 	tempDir := t.TempDir()
 	stateFile := filepath.Join(tempDir, "state.yaml")
@@ -186,3 +205,522 @@ func TestCreateUnits_MissingStateLocation(t *testing.T) {
 		t.Error("Expected error for missing state_location")
 	}
 }
+
+func TestCreateUnits_PruneState(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+	stateFile := filepath.Join(tempDir, "state.yaml")
+
+	if err := os.WriteFile(stateFile, []byte("boot-trigger:\n  boot_count: 2\nold-unit:\n  count: 5\n"), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	configContent := fmt.Sprintf(`config:
+  state_location: %s
+  prune_state: true
+
+units:
+  - boot:
+      name: boot-trigger
+`, stateFile)
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if _, err := config.CreateUnits(); err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	state := NewState(stateFile)
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if units := state.Units(); !slices.Equal(units, []string{"boot-trigger"}) {
+		t.Errorf("State units = %v, want [boot-trigger]", units)
+	}
+}
+
+func TestCreateUnits_InvalidCronSchedule(t *testing.T) {
+	config := &Config{
+		ConfigBlock: ConfigBlock{
+			StateLocation: filepath.Join(t.TempDir(), "state.yaml"),
+		},
+		Units: []UnitConfigWrapper{
+			{Cron: &CronConfig{UnitConfig: UnitConfig{Name: "nightly"}, Schedule: "0 2 * *"}},
+		},
+	}
+
+	_, err := config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "unit 0 (nightly): failed to parse cron schedule") {
+		t.Errorf("Expected invalid schedule error, got %v", err)
+	}
+}
+
+func TestConfig_GetPollInterval(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"1m", time.Minute, false},
+		{"0s", 0, true},
+		{"often", 0, true},
+	}
+
+	for _, tt := range tests {
+		config := &Config{ConfigBlock: ConfigBlock{PollInterval: tt.value}}
+		interval, err := config.GetPollInterval()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetPollInterval(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if interval != tt.expected {
+			t.Errorf("GetPollInterval(%q) = %v, want %v", tt.value, interval, tt.expected)
+		}
+	}
+}
+
+func TestLoadConfig_RelativePaths(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: state/brun.yaml
+
+units:
+  - log:
+      name: log
+      file: logs/brun.log
+  - run:
+      name: build
+      script: make
+      directory: /srv/app
+  - copy:
+      name: deploy
+      source: dist/
+      dest: host:/srv/www
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if want := filepath.Join(tempDir, "state/brun.yaml"); config.ConfigBlock.StateLocation != want {
+		t.Errorf("Expected state_location %s, got %s", want, config.ConfigBlock.StateLocation)
+	}
+	if want := filepath.Join(tempDir, "logs/brun.log"); config.Units[0].Log.File != want {
+		t.Errorf("Expected log file %s, got %s", want, config.Units[0].Log.File)
+	}
+	if config.Units[1].Run.Directory != "/srv/app" {
+		t.Errorf("Expected absolute directory unchanged, got %s", config.Units[1].Run.Directory)
+	}
+	if want := filepath.Join(tempDir, "dist") + "/"; config.Units[2].Copy.Source != want {
+		t.Errorf("Expected copy source %s, got %s", want, config.Units[2].Copy.Source)
+	}
+	if config.Units[2].Copy.Dest != "host:/srv/www" {
+		t.Errorf("Expected remote dest unchanged, got %s", config.Units[2].Copy.Dest)
+	}
+}
+
+func TestLoadConfig_MergeKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: state.yaml
+
+# Shared fragments, ignored by brun
+x-smtp: &smtp
+  smtp_host: smtp.example.com
+  smtp_port: 2525
+  from: ci@example.com
+x-alerts: &alerts
+  on_failure: [alert]
+x-team: &team
+  - dev@example.com
+  - ops@example.com
+
+units:
+  - email:
+      <<: [*smtp, *alerts]
+      name: mail1
+      to: *team
+  - email:
+      <<: *smtp
+      name: mail2
+      smtp_port: 25
+      to: [b@example.com]
+  - run:
+      <<: *alerts
+      name: alert
+      script: echo alert
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(config.Units) != 3 {
+		t.Fatalf("Expected 3 units, got %d", len(config.Units))
+	}
+
+	mail1 := config.Units[0].Email
+	if mail1.SMTPHost != "smtp.example.com" || mail1.SMTPPort != 2525 || mail1.From != "ci@example.com" {
+		t.Errorf("Expected SMTP settings from the merged fragment, got %+v", mail1)
+	}
+	if len(mail1.OnFailure) != 1 || mail1.OnFailure[0] != "alert" {
+		t.Errorf("Expected on_failure from the second merged fragment, got %v", mail1.OnFailure)
+	}
+	if len(mail1.To) != 2 || mail1.To[1] != "ops@example.com" {
+		t.Errorf("Expected to from the aliased list, got %v", mail1.To)
+	}
+
+	// Keys set in the unit override the merged fragment
+	mail2 := config.Units[1].Email
+	if mail2.SMTPPort != 25 || mail2.SMTPHost != "smtp.example.com" {
+		t.Errorf("Expected smtp_port override with merged smtp_host, got %+v", mail2)
+	}
+
+	// Common unit fields merge into any unit type
+	if run := config.Units[2].Run; len(run.OnFailure) != 1 || run.OnFailure[0] != "alert" {
+		t.Errorf("Expected on_failure merged into run unit, got %v", run.OnFailure)
+	}
+
+	if _, err := config.CreateUnits(); err != nil {
+		t.Errorf("CreateUnits failed: %v", err)
+	}
+}
+
+func TestLoadConfig_Overlay(t *testing.T) {
+	tempDir := t.TempDir()
+	baseFile := filepath.Join(tempDir, "base.yaml")
+	overlayFile := filepath.Join(tempDir, "prod.yaml")
+
+	baseContent := `config:
+  state_location: state.yaml
+  env:
+    REGION: eu
+units:
+  - run:
+      name: deploy
+      script: ./deploy.sh
+      env:
+        TARGET: staging
+        LOG_LEVEL: debug
+      on_success: [notify, log]
+  - ntfy:
+      name: notify
+      topic: deploys-staging
+`
+	overlayContent := `config:
+  env:
+    STAGE: prod
+units:
+  deploy:
+    env:
+      TARGET: production
+    on_success: [notify]
+  notify:
+    type: ntfy
+    topic: deploys-prod
+  log:
+    type: log
+    file: deploy.log
+`
+	if err := os.WriteFile(baseFile, []byte(baseContent), 0644); err != nil {
+		t.Fatalf("Failed to write base config: %v", err)
+	}
+	if err := os.WriteFile(overlayFile, []byte(overlayContent), 0644); err != nil {
+		t.Fatalf("Failed to write overlay: %v", err)
+	}
+
+	config, err := LoadConfig(baseFile, overlayFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if config.ConfigBlock.StateLocation != filepath.Join(tempDir, "state.yaml") {
+		t.Errorf("Expected state_location from base, got %s", config.ConfigBlock.StateLocation)
+	}
+	if env := config.ConfigBlock.Env; env["REGION"] != "eu" || env["STAGE"] != "prod" {
+		t.Errorf("Expected config.env merged, got %v", env)
+	}
+	if len(config.Units) != 3 {
+		t.Fatalf("Expected 3 units, got %d", len(config.Units))
+	}
+
+	deploy := config.Units[0].Run
+	if deploy == nil || deploy.Script != "./deploy.sh" {
+		t.Fatalf("Expected deploy run unit from base, got %+v", config.Units[0])
+	}
+	if deploy.Env["TARGET"] != "production" || deploy.Env["LOG_LEVEL"] != "debug" {
+		t.Errorf("Expected env merged key by key, got %v", deploy.Env)
+	}
+	if !slices.Equal(deploy.OnSuccess, []string{"notify"}) {
+		t.Errorf("Expected on_success replaced by overlay, got %v", deploy.OnSuccess)
+	}
+	if notify := config.Units[1].Ntfy; notify == nil || notify.Topic != "deploys-prod" {
+		t.Errorf("Expected topic from overlay, got %+v", config.Units[1])
+	}
+	if log := config.Units[2].Log; log == nil || log.Name != "log" {
+		t.Errorf("Expected log unit added by overlay, got %+v", config.Units[2])
+	}
+
+	// A unit's type can't be changed by an overlay
+	if err := os.WriteFile(overlayFile, []byte("units:\n  - log:\n      name: notify\n"), 0644); err != nil {
+		t.Fatalf("Failed to write overlay: %v", err)
+	}
+	if _, err := LoadConfig(baseFile, overlayFile); err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Errorf("Expected type mismatch error, got %v", err)
+	}
+}
+
+func TestLoadConfig_UnitMap(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: state.yaml
+
+units:
+  start:
+    type: start
+    on_success: [build]
+  build:
+    type: run
+    script: make
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(config.Units) != 2 {
+		t.Fatalf("Expected 2 units, got %d", len(config.Units))
+	}
+	if start := config.Units[0].Start; start == nil || start.Name != "start" || len(start.OnSuccess) != 1 {
+		t.Errorf("Expected start unit named by its key, got %+v", config.Units[0])
+	}
+	if run := config.Units[1].Run; run == nil || run.Name != "build" || run.Script != "make" {
+		t.Errorf("Expected run unit named by its key, got %+v", config.Units[1])
+	}
+
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+	if len(units) != 2 || units[1].Name() != "build" {
+		t.Errorf("Unexpected units: %v", units)
+	}
+
+	invalid := map[string]string{
+		"missing type":  "units:\n  build:\n    script: make\n",
+		"unknown type":  "units:\n  build:\n    type: make\n",
+		"name mismatch": "units:\n  build:\n    type: run\n    name: test\n",
+	}
+	for name, content := range invalid {
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		if _, err := LoadConfig(configFile); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCreateUnits_TypeMismatch(t *testing.T) {
+	config := Config{
+		ConfigBlock: ConfigBlock{StateLocation: filepath.Join(t.TempDir(), "state.yaml")},
+		Units: []UnitConfigWrapper{
+			{Run: &RunConfig{UnitConfig: UnitConfig{Name: "build", Type: "run"}, Script: "make"}},
+		},
+	}
+	if _, err := config.CreateUnits(); err != nil {
+		t.Fatalf("CreateUnits failed with matching type: %v", err)
+	}
+
+	config.Units[0].Run.Type = "email"
+	_, err := config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "type 'email' doesn't match its 'run' block") {
+		t.Errorf("Expected type mismatch error, got %v", err)
+	}
+}
+
+func TestConfig_UnitCooldowns(t *testing.T) {
+	config := Config{Units: []UnitConfigWrapper{
+		{File: &FileConfig{UnitConfig: UnitConfig{Name: "watch", Cooldown: "10m"}}},
+		{Run: &RunConfig{UnitConfig: UnitConfig{Name: "build"}}},
+	}}
+	cooldowns, err := config.UnitCooldowns()
+	if err != nil {
+		t.Fatalf("UnitCooldowns failed: %v", err)
+	}
+	if len(cooldowns) != 1 || cooldowns["watch"] != 10*time.Minute {
+		t.Errorf("Unexpected cooldowns: %v", cooldowns)
+	}
+
+	config.Units[0].File.Cooldown = "soon"
+	if _, err := config.UnitCooldowns(); err == nil {
+		t.Error("Expected error for invalid cooldown")
+	}
+}
+
+func TestConfig_UnitPollIntervals(t *testing.T) {
+	config := Config{Units: []UnitConfigWrapper{
+		{Disk: &DiskConfig{UnitConfig: UnitConfig{Name: "disk", Poll: "5m"}}},
+		{Git: &GitConfig{UnitConfig: UnitConfig{Name: "repo", Poll: "1m"}}},
+		{File: &FileConfig{UnitConfig: UnitConfig{Name: "watch"}}},
+	}}
+	intervals, err := config.UnitPollIntervals()
+	if err != nil {
+		t.Fatalf("UnitPollIntervals failed: %v", err)
+	}
+	if len(intervals) != 1 || intervals["disk"] != 5*time.Minute {
+		t.Errorf("Unexpected poll intervals: %v", intervals)
+	}
+
+	for _, poll := range []string{"often", "0s"} {
+		config.Units[0].Disk.Poll = poll
+		if _, err := config.UnitPollIntervals(); err == nil {
+			t.Errorf("Expected error for poll '%s'", poll)
+		}
+	}
+}
+
+func TestLoadConfig_Stdin(t *testing.T) {
+	defer func(r io.Reader) { configStdin = r }(configStdin)
+
+	configStdin = strings.NewReader(`config:
+  state_location: state.yaml
+units:
+  - start:
+      name: start
+`)
+	config, err := LoadConfig("-")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.ConfigBlock.StateLocation != "state.yaml" {
+		t.Errorf("Expected relative state_location to be unchanged, got %s", config.ConfigBlock.StateLocation)
+	}
+	if len(config.Units) != 1 || config.Units[0].Start == nil {
+		t.Errorf("Expected 1 start unit, got %+v", config.Units)
+	}
+
+	configStdin = strings.NewReader("units: []\n")
+	if _, err := LoadConfig("-"); err == nil {
+		t.Error("Expected error for missing state_location")
+	}
+}
+
+func TestLoadConfig_Directory(t *testing.T) {
+	confDir := filepath.Join(t.TempDir(), "conf.d")
+	if err := os.Mkdir(confDir, 0755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(confDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	write("00-base.yaml", `config:
+  state_location: state.yaml
+  env:
+    REGION: eu
+units:
+  - start:
+      name: start
+      on_success: [build]
+`)
+	write("10-build.yaml", `config:
+  env:
+    STAGE: prod
+units:
+  build:
+    type: run
+    script: make
+    directory: src
+`)
+	write("20-empty.yaml", "")
+	write("README", "not a config")
+
+	config, err := LoadConfig(confDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if config.ConfigBlock.StateLocation != filepath.Join(confDir, "state.yaml") {
+		t.Errorf("Expected state_location relative to the directory, got %s", config.ConfigBlock.StateLocation)
+	}
+	if env := config.ConfigBlock.Env; env["REGION"] != "eu" || env["STAGE"] != "prod" {
+		t.Errorf("Expected config.env merged, got %v", env)
+	}
+	if len(config.Units) != 2 {
+		t.Fatalf("Expected 2 units, got %d", len(config.Units))
+	}
+	if config.Units[0].Start == nil {
+		t.Errorf("Expected start unit first, got %+v", config.Units[0])
+	}
+	build := config.Units[1].Run
+	if build == nil || build.Name != "build" || build.Directory != filepath.Join(confDir, "src") {
+		t.Errorf("Expected build run unit with resolved directory, got %+v", config.Units[1])
+	}
+
+	// A unit name can only be defined once across the files
+	write("30-dup.yaml", "units:\n  - log:\n      name: build\n")
+	if _, err := LoadConfig(confDir); err == nil || !strings.Contains(err.Error(), "already defined in 10-build.yaml") {
+		t.Errorf("Expected duplicate unit error, got %v", err)
+	}
+
+	if _, err := LoadConfig(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no *.yaml files") {
+		t.Errorf("Expected error for empty directory, got %v", err)
+	}
+}
+
+func TestConfig_UnitForcedTriggers(t *testing.T) {
+	config := &Config{Units: UnitList{
+		{Cron: &CronConfig{UnitConfig: UnitConfig{Name: "weekly", OnSuccess: []string{"git-watch"}, Force: []string{"git-watch"}}}},
+	}}
+	forced, err := config.UnitForcedTriggers()
+	if err != nil || !slices.Equal(forced["weekly"], []string{"git-watch"}) {
+		t.Errorf("Expected weekly to force git-watch, got %v, %v", forced, err)
+	}
+
+	// A forced unit the unit doesn't trigger is likely a typo
+	config.Units[0].Cron.Force = []string{"git-wach"}
+	if _, err := config.UnitForcedTriggers(); err == nil || !strings.Contains(err.Error(), "git-wach") {
+		t.Errorf("Expected error for unknown forced unit, got %v", err)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	if mode, err := parseFileMode("0640"); err != nil || mode != 0o640 {
+		t.Errorf("parseFileMode(0640) = %04o, %v", mode, err)
+	}
+	if mode, err := parseFileMode("22"); err != nil || mode != 0o022 {
+		t.Errorf("parseFileMode(22) = %04o, %v", mode, err)
+	}
+	for _, value := range []string{"0999", "1777", "rw-r--r--"} {
+		if _, err := parseFileMode(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
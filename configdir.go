@@ -0,0 +1,80 @@
+package brun
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readConfigDir reads the *.yaml files in dir in lexical order and merges
+// them into one config document, so units can be dropped into a directory
+// like /etc/brun/conf.d/. The config blocks are merged key by key, later
+// files winning, and the units of all files are combined. A unit name may
+// only be defined once across the files.
+func readConfigDir(dir string) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.yaml files in %s", dir)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	units := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	defined := make(map[string]string)
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		data, err := readConfigFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		fileRoot := doc.Content[0]
+		if fileRoot.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s: config must be a map", name)
+		}
+
+		for i := 0; i+1 < len(fileRoot.Content); i += 2 {
+			key, value := fileRoot.Content[i].Value, fileRoot.Content[i+1]
+			if key != "units" {
+				if baseValue := mappingValue(root, key); baseValue != nil {
+					setMappingValue(root, key, mergeNodes(baseValue, value))
+				} else {
+					root.Content = append(root.Content, fileRoot.Content[i], value)
+				}
+				continue
+			}
+
+			// An empty units section
+			if value.Tag == "!!null" {
+				continue
+			}
+			fileUnits, err := overlayUnits(value, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			for _, u := range fileUnits {
+				if previous, ok := defined[u.name]; ok {
+					return nil, fmt.Errorf("%s: unit '%s' is already defined in %s", name, u.name, previous)
+				}
+				defined[u.name] = name
+				units.Content = append(units.Content, u.listItem())
+			}
+		}
+	}
+
+	if len(units.Content) > 0 {
+		setMappingValue(root, "units", units)
+	}
+	return yaml.Marshal(root)
+}
@@ -0,0 +1,141 @@
+package brun
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+)
+
+// ContentTrigger is a trigger unit that fires when a line matching a pattern
+// is appended to a file
+type ContentTrigger struct {
+	name      string
+	file      string
+	pattern   *regexp.Regexp
+	state     *State
+	lastMatch string
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// ContentConfig represents the configuration for a content trigger
+type ContentConfig struct {
+	UnitConfig `yaml:",inline"`
+	File       string `yaml:"file"`
+	Pattern    string `yaml:"pattern"`
+}
+
+// NewContentTrigger creates a new content trigger unit. pattern is a regular
+// expression matched against each line of the file.
+func NewContentTrigger(name, file, pattern string, state *State, onSuccess, onFailure, always []string) (*ContentTrigger, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+
+	return &ContentTrigger{
+		name:      name,
+		file:      file,
+		pattern:   re,
+		state:     state,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}, nil
+}
+
+// Name returns the name of the unit
+func (c *ContentTrigger) Name() string {
+	return c.name
+}
+
+// Type returns the unit type
+func (c *ContentTrigger) Type() string {
+	return "trigger.content"
+}
+
+// Check returns true if a line matching the pattern was added to the file
+// since the last check. Only complete lines are considered, so a line that
+// is still being written is matched on a later check.
+func (c *ContentTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	file, err := os.Open(c.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The file may not have been created yet
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Resume from the last offset (state is already loaded at startup)
+	var offset int64
+	if val, ok := c.state.Get(c.name, "offset"); ok {
+		if intVal, ok := val.(int); ok {
+			offset = int64(intVal)
+		}
+	}
+
+	// Start over if the file was truncated or replaced (e.g., log rotation)
+	if offset > info.Size() {
+		offset = 0
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return false, fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	matched := false
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// Leave a partial last line for the next check
+			break
+		}
+		offset += int64(len(line))
+
+		if !matched && c.pattern.MatchString(line[:len(line)-1]) {
+			matched = true
+			c.lastMatch = line[:len(line)-1]
+		}
+	}
+
+	if err := c.state.Set(c.name, "offset", int(offset)); err != nil {
+		return false, fmt.Errorf("failed to save offset: %w", err)
+	}
+
+	return matched, nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (c *ContentTrigger) OnSuccess() []string {
+	return c.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (c *ContentTrigger) OnFailure() []string {
+	return c.onFailure
+}
+
+// Always returns the list of units to trigger regardless of success/failure
+func (c *ContentTrigger) Always() []string {
+	return c.always
+}
+
+// Run executes the trigger unit
+// Note: Check() has already been called by the orchestrator before Run() is invoked
+func (c *ContentTrigger) Run(ctx context.Context) error {
+	log.Printf("Content trigger '%s' activated (%s: %s)", c.name, c.file, c.lastMatch)
+	return nil
+}
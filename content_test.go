@@ -0,0 +1,120 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+func TestContentTrigger_Check(t *testing.T) {
+	tempDir := t.TempDir()
+	logFile := filepath.Join(tempDir, "build.log")
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+
+	trigger, err := NewContentTrigger("build-done", logFile, `^BUILD (COMPLETE|DONE)$`, state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewContentTrigger failed: %v", err)
+	}
+
+	ctx := context.Background()
+	check := func(want bool, msg string) {
+		t.Helper()
+		got, err := trigger.Check(ctx, CheckModePolling)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: Check() = %v, want %v", msg, got, want)
+		}
+	}
+
+	check(false, "missing file")
+
+	appendToFile(t, logFile, "compiling\nlinking\n")
+	check(false, "no marker")
+
+	appendToFile(t, logFile, "BUILD COMPLETE\n")
+	check(true, "marker appended")
+	check(false, "marker already seen")
+
+	// A partial line is not matched until it is complete
+	appendToFile(t, logFile, "BUILD COMP")
+	check(false, "partial line")
+	appendToFile(t, logFile, "LETE\n")
+	check(true, "line completed")
+
+	// Offset survives a restart
+	reloaded := NewState(filepath.Join(tempDir, "state.yaml"))
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	trigger, _ = NewContentTrigger("build-done", logFile, `^BUILD (COMPLETE|DONE)$`, reloaded, nil, nil, nil)
+	check(false, "after restart")
+
+	// A truncated file is read from the start
+	if err := os.WriteFile(logFile, []byte("BUILD DONE\n"), 0644); err != nil {
+		t.Fatalf("Failed to truncate file: %v", err)
+	}
+	check(true, "after truncation")
+}
+
+func TestContentTrigger_InvalidPattern(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	if _, err := NewContentTrigger("bad", "build.log", "([", state, nil, nil, nil); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestLoadConfig_WithContentUnit(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tempDir, "state.yaml") + `
+
+units:
+  - content:
+      name: build-done
+      file: /var/log/build.log
+      pattern: "BUILD COMPLETE"
+      on_success:
+        - deploy
+  - content:
+      name: bad-pattern
+      file: /var/log/build.log
+      pattern: "(["
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	cfg := config.Units[0].Content
+	if cfg == nil || cfg.File != "/var/log/build.log" || cfg.Pattern != "BUILD COMPLETE" {
+		t.Fatalf("Unexpected content config: %+v", cfg)
+	}
+
+	_, err = config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("Expected invalid pattern error, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package brun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CopyConfig represents the configuration for a Copy unit
+type CopyConfig struct {
+	UnitConfig `yaml:",inline"`
+	Source     string `yaml:"source"`
+	Dest       string `yaml:"dest"`
+	Recursive  bool   `yaml:"recursive,omitempty"`
+	Preserve   bool   `yaml:"preserve,omitempty"`
+	Delete     bool   `yaml:"delete,omitempty"`
+}
+
+// rsyncTransferredRegex matches the transferred size in rsync --stats output
+var rsyncTransferredRegex = regexp.MustCompile(`Total transferred file size: ([\d,.]+) bytes`)
+
+// CopyUnit copies files to a local or remote (host:path over SSH) destination
+// using rsync
+type CopyUnit struct {
+	name      string
+	source    string
+	dest      string
+	recursive bool
+	preserve  bool
+	delete    bool
+	command   string // rsync binary, overridable for tests
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// NewCopyUnit creates a new Copy unit. recursive copies directories,
+// preserve keeps permissions, times, symlinks, and ownership, and delete
+// removes files from dest that aren't in source (mirror).
+func NewCopyUnit(name, source, dest string, recursive, preserve, delete bool, onSuccess, onFailure, always []string) *CopyUnit {
+	return &CopyUnit{
+		name:      name,
+		source:    source,
+		dest:      dest,
+		recursive: recursive,
+		preserve:  preserve,
+		delete:    delete,
+		command:   "rsync",
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}
+}
+
+// Name returns the unit name
+func (c *CopyUnit) Name() string {
+	return c.name
+}
+
+// Type returns the unit type
+func (c *CopyUnit) Type() string {
+	return "copy"
+}
+
+// args returns the rsync arguments for the configured options
+func (c *CopyUnit) args() []string {
+	args := []string{"--stats"}
+	if c.recursive {
+		args = append(args, "--recursive")
+	}
+	if c.preserve {
+		args = append(args, "--links", "--perms", "--times", "--group", "--owner")
+	}
+	if c.delete {
+		args = append(args, "--delete")
+	}
+	return append(args, c.source, c.dest)
+}
+
+// Run copies source to dest
+func (c *CopyUnit) Run(ctx context.Context) error {
+	log.Printf("Running copy unit '%s' (%s -> %s)", c.name, c.source, c.dest)
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command, c.args()...)
+	cmd.Stdout = io.MultiWriter(os.Stdout, &stdout)
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("rsync exited with code %d", exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run rsync: %w", err)
+	}
+
+	if bytesCopied, ok := parseRsyncTransferred(stdout.String()); ok {
+		log.Printf("Copy unit '%s' completed, copied %d bytes", c.name, bytesCopied)
+	} else {
+		log.Printf("Copy unit '%s' completed", c.name)
+	}
+	return nil
+}
+
+// parseRsyncTransferred returns the number of bytes transferred from rsync
+// --stats output. Newer rsync versions add thousands separators.
+func parseRsyncTransferred(output string) (int64, bool) {
+	match := rsyncTransferredRegex.FindStringSubmatch(output)
+	if match == nil {
+		return 0, false
+	}
+	digits := strings.NewReplacer(",", "", ".", "").Replace(match[1])
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (c *CopyUnit) OnSuccess() []string {
+	return c.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (c *CopyUnit) OnFailure() []string {
+	return c.onFailure
+}
+
+// Always returns the list of units to always trigger
+func (c *CopyUnit) Always() []string {
+	return c.always
+}
@@ -0,0 +1,88 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRsync writes an rsync stand-in that records its arguments, prints
+// --stats style output, and exits with the given code
+func fakeRsync(t *testing.T, exitCode string) (command, argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	command = filepath.Join(dir, "rsync")
+	argsFile = filepath.Join(dir, "args")
+
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile +
+		"\necho 'Total transferred file size: 1,234,567 bytes'\nexit " + exitCode + "\n"
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake rsync: %v", err)
+	}
+	return command, argsFile
+}
+
+func TestCopyUnit_Run(t *testing.T) {
+	command, argsFile := fakeRsync(t, "0")
+	unit := NewCopyUnit("publish", "build/site/", "web:/var/www/html", true, true, true, nil, nil, nil)
+	unit.command = command
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read args: %v", err)
+	}
+	expected := "--stats --recursive --links --perms --times --group --owner --delete build/site/ web:/var/www/html"
+	if strings.TrimSpace(string(args)) != expected {
+		t.Errorf("Expected args %q, got %q", expected, strings.TrimSpace(string(args)))
+	}
+}
+
+func TestCopyUnit_RunFailure(t *testing.T) {
+	command, _ := fakeRsync(t, "23")
+	unit := NewCopyUnit("publish", "a", "b", false, false, false, nil, nil, nil)
+	unit.command = command
+
+	err := unit.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exited with code 23") {
+		t.Errorf("Expected exit code error, got %v", err)
+	}
+}
+
+func TestParseRsyncTransferred(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected int64
+		ok       bool
+	}{
+		{"Total transferred file size: 1,234,567 bytes\n", 1234567, true},
+		{"Number of files: 3\nTotal transferred file size: 42 bytes\n", 42, true},
+		{"sending incremental file list\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		n, ok := parseRsyncTransferred(tt.output)
+		if n != tt.expected || ok != tt.ok {
+			t.Errorf("parseRsyncTransferred(%q) = %d, %v; expected %d, %v", tt.output, n, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestCreateUnits_CopyDeleteRequiresRecursive(t *testing.T) {
+	config := &Config{
+		ConfigBlock: ConfigBlock{StateLocation: filepath.Join(t.TempDir(), "state.yaml")},
+		Units: []UnitConfigWrapper{
+			{Copy: &CopyConfig{UnitConfig: UnitConfig{Name: "publish"}, Source: "a", Dest: "b", Delete: true}},
+		},
+	}
+
+	_, err := config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "delete requires recursive") {
+		t.Errorf("Expected delete requires recursive error, got %v", err)
+	}
+}
@@ -4,18 +4,24 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 )
 
 // CountConfig represents the configuration for a Count unit
 type CountConfig struct {
 	UnitConfig `yaml:",inline"`
+	// Window only counts triggers within this duration, e.g. "1h", instead
+	// of all-time totals
+	Window string `yaml:"window,omitempty"`
 }
 
 // CountUnit tracks how many times it has been triggered by each unit
 type CountUnit struct {
 	name           string
 	state          *State
-	triggeringUnit string // Name of the unit that triggered this count
+	triggeringUnit string        // Name of the unit that triggered this count
+	count          int           // Count after the last run
+	window         time.Duration // 0 counts all triggers
 	onSuccess      []string
 	onFailure      []string
 	always         []string
@@ -42,6 +48,12 @@ func (c *CountUnit) Type() string {
 	return "count"
 }
 
+// SetWindow makes the unit only count triggers within the last window. The
+// time of each trigger is kept in state.
+func (c *CountUnit) SetWindow(window time.Duration) {
+	c.window = window
+}
+
 // SetTriggeringUnit sets the name of the unit that triggered this count
 func (c *CountUnit) SetTriggeringUnit(unitName string) {
 	c.triggeringUnit = unitName
@@ -57,26 +69,66 @@ func (c *CountUnit) Run(ctx context.Context) error {
 		unitName = "unknown"
 	}
 
-	// Get current count for this triggering unit
-	currentCount := 0
-	if val, ok := c.state.Get(c.name, unitName); ok {
-		if intVal, ok := val.(int); ok {
-			currentCount = intVal
+	var newCount int
+	if c.window > 0 {
+		var err error
+		newCount, err = c.countInWindow(unitName)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Get current count for this triggering unit
+		currentCount := 0
+		if val, ok := c.state.Get(c.name, unitName); ok {
+			if intVal, ok := val.(int); ok {
+				currentCount = intVal
+			}
 		}
-	}
 
-	// Increment count
-	newCount := currentCount + 1
+		// Increment count
+		newCount = currentCount + 1
+	}
 
 	// Save to state
 	if err := c.state.Set(c.name, unitName, newCount); err != nil {
 		return fmt.Errorf("failed to save count: %w", err)
 	}
 
+	c.count = newCount
+
 	log.Printf("Count unit '%s': unit '%s' has triggered %d time(s)", c.name, unitName, newCount)
 	return nil
 }
 
+// countInWindow records a trigger from unitName and returns how many of its
+// triggers are within the window. Older trigger times are dropped from state.
+func (c *CountUnit) countInWindow(unitName string) (int, error) {
+	now := time.Now()
+	cutoff := now.Add(-c.window)
+
+	var times []any
+	if val, ok := c.state.Get(c.name, unitName+"_times"); ok {
+		list, _ := val.([]any)
+		for _, item := range list {
+			str, _ := item.(string)
+			if t, err := time.Parse(time.RFC3339Nano, str); err == nil && t.After(cutoff) {
+				times = append(times, str)
+			}
+		}
+	}
+	times = append(times, now.Format(time.RFC3339Nano))
+
+	if err := c.state.Set(c.name, unitName+"_times", times); err != nil {
+		return 0, fmt.Errorf("failed to save trigger times: %w", err)
+	}
+	return len(times), nil
+}
+
+// Count returns the count for the triggering unit after the last run
+func (c *CountUnit) Count() int {
+	return c.count
+}
+
 // OnSuccess returns the list of units to trigger on success
 func (c *CountUnit) OnSuccess() []string {
 	return c.onSuccess
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -159,6 +160,35 @@ func TestCountUnit_StateFormat(t *testing.T) {
 	}
 }
 
+func TestCountUnit_Window(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	unit := NewCountUnit("failures", state, nil, nil, nil)
+	unit.SetWindow(time.Hour)
+	unit.SetTriggeringUnit("build")
+
+	// One trigger from yesterday and one from a few minutes ago
+	times := []any{
+		time.Now().Add(-24 * time.Hour).Format(time.RFC3339Nano),
+		time.Now().Add(-5 * time.Minute).Format(time.RFC3339Nano),
+	}
+	if err := state.Set("failures", "build_times", times); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if unit.Count() != 2 {
+		t.Errorf("Expected count 2 within the window, got %d", unit.Count())
+	}
+	if val, _ := state.Get("failures", "build"); val != 2 {
+		t.Errorf("Expected stored count 2, got %v", val)
+	}
+	if val, _ := state.Get("failures", "build_times"); len(val.([]any)) != 2 {
+		t.Errorf("Expected expired trigger time to be dropped, got %v", val)
+	}
+}
+
 func TestLoadConfig_WithCountUnit(t *testing.T) {
 	tempDir := t.TempDir()
 	configFile := filepath.Join(tempDir, "config.yaml")
@@ -3,7 +3,10 @@ package brun
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -11,32 +14,134 @@ import (
 
 // CronTrigger is a trigger unit that fires based on a cron schedule
 type CronTrigger struct {
-	name      string
-	schedule  string
-	state     *State
-	parser    cron.Parser
-	onSuccess []string
-	onFailure []string
-	always    []string
+	name          string
+	schedule      string
+	scheduleRef   string // state or env reference the schedule is read from
+	mu            sync.Mutex
+	state         *State
+	offset        time.Duration // per-instance jitter offset applied to the schedule
+	skipIfRunning bool
+	// chainFinished is when the chain started by the last firing completed
+	chainFinished time.Time
+	sched         cron.Schedule // parsed schedule
+	now           func() time.Time
+	onSuccess     []string
+	onFailure     []string
+	always        []string
 }
 
 // CronConfig represents the configuration for a cron trigger
 type CronConfig struct {
-	UnitConfig `yaml:",inline"`
-	Schedule   string `yaml:"schedule"`
+	UnitConfig    `yaml:",inline"`
+	Schedule      string `yaml:"schedule"`
+	SkipIfRunning bool   `yaml:"skip_if_running,omitempty"`
+}
+
+// cronParser parses standard 5-field cron schedules and descriptors like @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseCronSchedule parses a cron schedule
+func parseCronSchedule(schedule string) (cron.Schedule, error) {
+	sched, err := cronParser.Parse(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cron schedule '%s': %w", schedule, err)
+	}
+	return sched, nil
 }
 
 // NewCronTrigger creates a new cron trigger unit
-func NewCronTrigger(name, schedule string, state *State, onSuccess, onFailure, always []string) *CronTrigger {
+// jitter is the maximum random delay applied to each scheduled time, 0 disables it
+// schedule may be a ${state:<unit>.<key>} or ${env:<NAME>} reference, which
+// is read again before each check and scheduling
+// An error is returned if the schedule can't be resolved or parsed
+func NewCronTrigger(name, schedule string, state *State, jitter time.Duration, onSuccess, onFailure, always []string) (*CronTrigger, error) {
+	var scheduleRef string
+	if isDynamicRef(schedule) {
+		scheduleRef = schedule
+		var err error
+		schedule, err = resolveDynamic(scheduleRef, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sched, err := parseCronSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+
 	return &CronTrigger{
-		name:      name,
-		schedule:  schedule,
-		state:     state,
-		parser:    cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor),
-		onSuccess: onSuccess,
-		onFailure: onFailure,
-		always:    always,
+		name:        name,
+		schedule:    schedule,
+		scheduleRef: scheduleRef,
+		sched:       sched,
+		now:         time.Now,
+		state:       state,
+		offset:      jitterOffset(name, jitter),
+		onSuccess:   onSuccess,
+		onFailure:   onFailure,
+		always:      always,
+	}, nil
+}
+
+// SetSkipIfRunning configures whether the trigger skips scheduled times that
+// came due while the chain started by its previous firing was still running
+func (c *CronTrigger) SetSkipIfRunning(skip bool) {
+	c.skipIfRunning = skip
+}
+
+// SkipIfRunning returns true if the trigger should not fire while its previous
+// chain is still running
+func (c *CronTrigger) SkipIfRunning() bool {
+	return c.skipIfRunning
+}
+
+// ChainFinished records that the chain started by the trigger's last firing
+// has completed
+func (c *CronTrigger) ChainFinished() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chainFinished = c.now()
+}
+
+// ranDuringChain returns true if a scheduled time, in the jitter shifted
+// frame, came due while the chain of the previous firing was still running
+func (c *CronTrigger) ranDuringChain(scheduled time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return scheduled.Add(c.offset).Before(c.chainFinished)
+}
+
+// DynamicSchedule returns true if the schedule is read from state or the
+// environment and can change at runtime
+func (c *CronTrigger) DynamicSchedule() bool {
+	return c.scheduleRef != ""
+}
+
+// currentSchedule returns the parsed schedule, first re-reading it from the
+// schedule reference if set. If the reference can't be resolved or parsed,
+// the previous schedule is kept.
+func (c *CronTrigger) currentSchedule() cron.Schedule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.scheduleRef == "" {
+		return c.sched
+	}
+
+	schedule, err := resolveDynamic(c.scheduleRef, c.state)
+	if err == nil && schedule != c.schedule {
+		var sched cron.Schedule
+		sched, err = parseCronSchedule(schedule)
+		if err == nil {
+			log.Printf("Cron trigger '%s' schedule changed from '%s' to '%s'", c.name, c.schedule, schedule)
+			c.schedule, c.sched = schedule, sched
+		}
+	}
+	if err != nil {
+		log.Printf("Cron trigger '%s' keeping schedule '%s', failed to read %s: %v", c.name, c.schedule, c.scheduleRef, err)
 	}
+	return c.sched
 }
 
 // Name returns the name of the unit
@@ -49,95 +154,106 @@ func (c *CronTrigger) Type() string {
 	return "trigger.cron"
 }
 
+// cronToleranceWindow is how late a scheduled run may be detected and still
+// fire. The orchestrator checks every 10 seconds, but we need buffer for
+// processing delays, system load, and time for unit execution. Older runs
+// (e.g., missed during downtime) are skipped to avoid catch-up behavior.
+const cronToleranceWindow = 60 * time.Second
+
 // Check returns true if the cron schedule has triggered since the last execution
+// last_execution in state is always set to a scheduled time (not the time of
+// the check), so each scheduled time fires at most once no matter how the
+// checks line up with it.
 func (c *CronTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
 	// Cron triggers work the same way regardless of mode
 	// The schedule determines when they fire
-	// Parse the schedule
-	sched, err := c.parser.Parse(c.schedule)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse cron schedule '%s': %w", c.schedule, err)
-	}
 
-	now := time.Now()
+	// Shift the clock back by the jitter offset so the trigger fires at
+	// scheduled time + offset. All state values stay in this shifted frame.
+	now := c.now().Add(-c.offset)
 
 	// Get last execution time from state (state is already loaded at startup)
+	var lastExec time.Time
 	lastExecStr, ok := c.state.GetString(c.name, "last_execution")
-	if !ok {
-		// No previous execution, check if we should trigger now
-		nextRun := sched.Next(now.Add(-1 * time.Minute))
-		if nextRun.Before(now) || nextRun.Equal(now) {
-			// Schedule says we should have run, so trigger
-			// Save the scheduled time (nextRun) rather than current time (now)
-			// This ensures subsequent checks correctly identify we've handled this scheduled run
-			if err := c.state.SetString(c.name, "last_execution", nextRun.Format(time.RFC3339)); err != nil {
-				return false, fmt.Errorf("failed to save execution time: %w", err)
-			}
-			return true, nil
+	if ok {
+		var err error
+		lastExec, err = time.Parse(time.RFC3339, lastExecStr)
+		if err != nil {
+			log.Printf("Cron trigger '%s' ignoring invalid last_execution '%s'", c.name, lastExecStr)
+			ok = false
 		}
+	}
+	if !ok {
+		// No previous execution, only consider a scheduled time in the last minute
+		lastExec = now.Add(-1 * time.Minute)
+	}
+
+	// Find the most recent scheduled time since the last execution
+	scheduled := latestScheduled(c.currentSchedule(), lastExec, now)
+	if scheduled.IsZero() {
+		// Next scheduled time is in the future - don't fire yet
 		return false, nil
 	}
 
-	// Parse last execution time
-	lastExec, err := time.Parse(time.RFC3339, lastExecStr)
-	if err != nil {
-		// Invalid execution time in state, treat as first run
-		if err := c.state.SetString(c.name, "last_execution", now.Format(time.RFC3339)); err != nil {
-			return false, fmt.Errorf("failed to save execution time: %w", err)
-		}
-		return true, nil
-	}
-
-	// Check if the schedule indicates we should run
-	// Get the next scheduled time after the last execution
-	nextRun := sched.Next(lastExec)
-
-	// Calculate how long ago the scheduled time was
-	timeSinceScheduled := now.Sub(nextRun)
-
-	// Define tolerance window (60 seconds to account for check interval + processing delays)
-	// The orchestrator checks every 10 seconds, but we need buffer for:
-	// - Processing delays
-	// - System load
-	// - Time for unit execution
-	// This ensures we catch scheduled runs within a reasonable window while
-	// avoiding catch-up behavior for truly missed runs (hours/days old)
-	const toleranceWindow = 60 * time.Second
-
-	// If the scheduled time is in the past
-	if nextRun.Before(now) {
-		if timeSinceScheduled > toleranceWindow {
-			// We missed the scheduled time - skip this run to avoid catch-up behavior
-			// Update last_execution to now so we can check for future runs
-			if err := c.state.SetString(c.name, "last_execution", now.Format(time.RFC3339)); err != nil {
-				return false, fmt.Errorf("failed to save execution time: %w", err)
-			}
-			log.Printf("Cron trigger '%s' skipped missed run (was scheduled for %v, now is %v)",
-				c.name, nextRun.Format(time.RFC3339), now.Format(time.RFC3339))
-			return false, nil
-		}
+	// Save the scheduled time rather than the current time so subsequent
+	// checks correctly identify we've handled this scheduled run
+	if err := c.state.SetString(c.name, "last_execution", scheduled.Format(time.RFC3339)); err != nil {
+		return false, fmt.Errorf("failed to save execution time: %w", err)
+	}
 
-		// We're within the tolerance window - fire
-		// Save the scheduled time (nextRun) rather than current time (now)
-		// This ensures subsequent checks correctly identify we've handled this scheduled run
-		if err := c.state.SetString(c.name, "last_execution", nextRun.Format(time.RFC3339)); err != nil {
-			return false, fmt.Errorf("failed to save execution time: %w", err)
-		}
-		return true, nil
+	if now.Sub(scheduled) > cronToleranceWindow {
+		// We missed the scheduled time - skip this run to avoid catch-up behavior
+		log.Printf("Cron trigger '%s' skipped missed run (was scheduled for %v, now is %v)",
+			c.name, scheduled.Format(time.RFC3339), now.Format(time.RFC3339))
+		return false, nil
 	}
 
-	// If the scheduled time is exactly now, fire
-	if nextRun.Equal(now) {
-		// Save the scheduled time (nextRun) rather than current time (now)
-		// This ensures subsequent checks correctly identify we've handled this scheduled run
-		if err := c.state.SetString(c.name, "last_execution", nextRun.Format(time.RFC3339)); err != nil {
-			return false, fmt.Errorf("failed to save execution time: %w", err)
-		}
-		return true, nil
+	// Checks wait for the running cycle, so a time that came due during a
+	// slow chain is only seen once the chain is done
+	if c.skipIfRunning && c.ranDuringChain(scheduled) {
+		log.Printf("Trigger '%s' skipped, previous run still active (was scheduled for %v)",
+			c.name, scheduled.Add(c.offset).Format(time.RFC3339))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// NextRun returns the first scheduled time after after, including the jitter
+// offset, or the zero time if the schedule never matches
+func (c *CronTrigger) NextRun(after time.Time) time.Time {
+	next := c.currentSchedule().Next(after.Add(-c.offset))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(c.offset)
+}
+
+// latestScheduled returns the latest time in the schedule after after and at
+// or before now, or the zero time if there is none
+func latestScheduled(sched cron.Schedule, after, now time.Time) time.Time {
+	var latest time.Time
+	for next := sched.Next(after); !next.IsZero() && !next.After(now); next = sched.Next(next) {
+		latest = next
 	}
+	return latest
+}
 
-	// Future scheduled time - don't fire yet
-	return false, nil
+// jitterOffset returns a delay in [0, jitter) that is stable for a given host
+// and unit name, so restarts don't move the schedule but a fleet of devices
+// sharing a config spreads out
+func jitterOffset(name string, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+
+	hostname, _ := os.Hostname()
+	h := fnv.New64a()
+	h.Write([]byte(hostname + "/" + name))
+
+	// Round down to whole seconds to keep state timestamps readable
+	offset := time.Duration(h.Sum64() % uint64(jitter))
+	return offset.Truncate(time.Second)
 }
 
 // OnSuccess returns the list of units to trigger on success
@@ -158,6 +274,10 @@ func (c *CronTrigger) Always() []string {
 // Run executes the trigger unit
 // Note: Check() has already been called by the orchestrator before Run() is invoked
 func (c *CronTrigger) Run(ctx context.Context) error {
+	if c.offset > 0 {
+		log.Printf("Cron trigger '%s' activated (schedule: %s, jitter offset: %s)", c.name, c.schedule, c.offset)
+		return nil
+	}
 	log.Printf("Cron trigger '%s' activated (schedule: %s)", c.name, c.schedule)
 	return nil
 }
@@ -2,6 +2,7 @@ package brun
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -15,14 +16,18 @@ func TestCronTrigger_Check(t *testing.T) {
 	state := NewState(stateFile)
 
 	// Create a cron trigger that runs every minute
-	trigger := NewCronTrigger(
+	trigger, err := NewCronTrigger(
 		"test-cron",
 		"* * * * *",
 		state,
+		0,
 		[]string{"next-unit"},
 		nil,
 		nil,
 	)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
 
 	ctx := context.Background()
 
@@ -79,20 +84,16 @@ func TestCronTrigger_InvalidSchedule(t *testing.T) {
 
 	state := NewState(stateFile)
 
-	// Create a cron trigger with invalid schedule
-	trigger := NewCronTrigger(
+	// Creating a cron trigger with an invalid schedule should fail
+	_, err := NewCronTrigger(
 		"test-cron-invalid",
 		"invalid schedule",
 		state,
+		0,
 		nil,
 		nil,
 		nil,
 	)
-
-	ctx := context.Background()
-
-	// Check should fail with invalid schedule
-	_, err := trigger.Check(ctx, CheckModePolling)
 	if err == nil {
 		t.Error("Expected error for invalid schedule")
 	}
@@ -104,14 +105,18 @@ func TestCronTrigger_Run(t *testing.T) {
 
 	state := NewState(stateFile)
 
-	trigger := NewCronTrigger(
+	trigger, err := NewCronTrigger(
 		"test-cron-run",
 		"* * * * *",
 		state,
+		0,
 		[]string{"next-unit"},
 		nil,
 		nil,
 	)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
 
 	if trigger.Name() != "test-cron-run" {
 		t.Errorf("Expected name 'test-cron-run', got '%s'", trigger.Name())
@@ -222,14 +227,18 @@ func TestCronTrigger_SkipMissedRun(t *testing.T) {
 	state := NewState(stateFile)
 
 	// Create a cron trigger that runs daily at midnight
-	trigger := NewCronTrigger(
+	trigger, err := NewCronTrigger(
 		"test-cron-skip",
 		"0 0 * * *",
 		state,
+		0,
 		[]string{"next-unit"},
 		nil,
 		nil,
 	)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
 
 	ctx := context.Background()
 
@@ -250,7 +259,7 @@ func TestCronTrigger_SkipMissedRun(t *testing.T) {
 		t.Error("Expected not to trigger for missed run (outside tolerance window)")
 	}
 
-	// Verify state was updated to current time (skipped the missed run)
+	// Verify state was updated to the skipped scheduled time
 	if err := state.Load(); err != nil {
 		t.Fatalf("Failed to load state: %v", err)
 	}
@@ -260,14 +269,15 @@ func TestCronTrigger_SkipMissedRun(t *testing.T) {
 		t.Error("Expected last_execution to be updated")
 	}
 
-	// Parse and verify it's recent (within last 5 seconds)
 	execTime, err := time.Parse(time.RFC3339, lastExec)
 	if err != nil {
 		t.Fatalf("Failed to parse execution time: %v", err)
 	}
 
-	if time.Since(execTime) > 5*time.Second {
-		t.Error("Execution time should be very recent (missed run was skipped and time updated to now)")
+	now := time.Now()
+	lastMidnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !execTime.Equal(lastMidnight) {
+		t.Errorf("Expected last_execution to be the skipped run %v, got %v", lastMidnight, execTime)
 	}
 }
 
@@ -278,14 +288,18 @@ func TestCronTrigger_WithinToleranceWindow(t *testing.T) {
 	state := NewState(stateFile)
 
 	// Create a cron trigger that runs every minute
-	trigger := NewCronTrigger(
+	trigger, err := NewCronTrigger(
 		"test-cron-tolerance",
 		"* * * * *",
 		state,
+		0,
 		[]string{"next-unit"},
 		nil,
 		nil,
 	)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
 
 	ctx := context.Background()
 
@@ -323,14 +337,18 @@ func TestCronTrigger_NoDoubleTrigger(t *testing.T) {
 
 	// Create a cron trigger that runs every minute
 	// This ensures the test works regardless of when it's run
-	trigger := NewCronTrigger(
+	trigger, err := NewCronTrigger(
 		"test-cron-double",
 		"* * * * *",
 		state,
+		0,
 		[]string{"next-unit"},
 		nil,
 		nil,
 	)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
 
 	ctx := context.Background()
 
@@ -382,3 +400,224 @@ func TestCronTrigger_NoDoubleTrigger(t *testing.T) {
 		t.Errorf("Expected last_execution to be saved with 0 seconds (scheduled time), got %d seconds", lastExecTime.Second())
 	}
 }
+
+func TestCronTrigger_Jitter(t *testing.T) {
+	// Offset must be stable and within the jitter window
+	offset := jitterOffset("test-cron-jitter", time.Hour)
+	if offset < 0 || offset >= time.Hour {
+		t.Errorf("Expected offset within [0, 1h), got %v", offset)
+	}
+	if again := jitterOffset("test-cron-jitter", time.Hour); again != offset {
+		t.Errorf("Expected stable offset %v, got %v", offset, again)
+	}
+	if jitterOffset("test-cron-jitter", 0) != 0 {
+		t.Error("Expected zero offset when jitter is disabled")
+	}
+
+	tempDir := t.TempDir()
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+
+	// Avoid straddling a minute boundary while the test runs
+	if time.Now().Second() >= 57 {
+		time.Sleep(4 * time.Second)
+	}
+
+	// Schedule is due in the current minute
+	now := time.Now()
+	schedule := fmt.Sprintf("%d %d * * *", now.Minute(), now.Hour())
+	lastExec := now.Add(-24 * time.Hour).Format(time.RFC3339)
+
+	trigger, err := NewCronTrigger("test-cron-jitter", schedule, state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+	// Force an offset that delays the scheduled time into the future
+	trigger.offset = 10 * time.Minute
+
+	if err := state.SetString("test-cron-jitter", "last_execution", lastExec); err != nil {
+		t.Fatalf("Failed to set last_execution: %v", err)
+	}
+
+	ctx := context.Background()
+	shouldTrigger, err := trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if shouldTrigger {
+		t.Error("Expected trigger to wait for the jitter offset")
+	}
+
+	// Without an offset the same schedule is due now
+	trigger.offset = 0
+	shouldTrigger, err = trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger to fire without a jitter offset")
+	}
+}
+
+// cronClock returns a function that reports the given times, one per call
+func cronClock(t *testing.T, times ...string) func() time.Time {
+	t.Helper()
+	parsed := make([]time.Time, len(times))
+	for i, s := range times {
+		tm, err := time.Parse("15:04:05.000", s)
+		if err != nil {
+			t.Fatalf("Invalid time %q: %v", s, err)
+		}
+		parsed[i] = time.Date(2025, 1, 1, tm.Hour(), tm.Minute(), tm.Second(), tm.Nanosecond(), time.UTC)
+	}
+	return func() time.Time {
+		tm := parsed[0]
+		parsed = parsed[1:]
+		return tm
+	}
+}
+
+// TestCronTrigger_MinuteBoundaries simulates 10s polling of a minute schedule
+// at various offsets from the minute boundary
+func TestCronTrigger_MinuteBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		lastExec string // empty for first run
+		checks   []string
+		fired    []bool
+		stored   string // expected last_execution after the checks
+	}{
+		{
+			name:   "first run fired at 00:00:05 does not fire 10s later",
+			checks: []string{"00:00:05.000", "00:00:15.000", "00:00:55.000", "00:01:05.000"},
+			fired:  []bool{true, false, false, true},
+			stored: "00:01:00",
+		},
+		{
+			name:     "check exactly on the boundary fires once",
+			lastExec: "00:00:00",
+			checks:   []string{"00:00:59.999", "00:01:00.000", "00:01:00.500", "00:01:10.000"},
+			fired:    []bool{false, true, false, false},
+			stored:   "00:01:00",
+		},
+		{
+			name:     "sub-second checks around the boundary",
+			lastExec: "00:00:00",
+			checks:   []string{"00:00:59.900", "00:01:00.100", "00:01:09.900"},
+			fired:    []bool{false, true, false},
+			stored:   "00:01:00",
+		},
+		{
+			name:     "delayed poll fires for the latest scheduled time",
+			lastExec: "00:00:00",
+			checks:   []string{"00:02:30.000", "00:02:40.000"},
+			fired:    []bool{true, false},
+			stored:   "00:02:00",
+		},
+		{
+			name:     "legacy last_execution stored as check time",
+			lastExec: "00:00:05",
+			checks:   []string{"00:00:15.000", "00:01:05.000"},
+			fired:    []bool{false, true},
+			stored:   "00:01:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+			trigger, err := NewCronTrigger("minutely", "* * * * *", state, 0, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("NewCronTrigger failed: %v", err)
+			}
+			trigger.now = cronClock(t, tt.checks...)
+
+			if tt.lastExec != "" {
+				if err := state.SetString("minutely", "last_execution", "2025-01-01T"+tt.lastExec+"Z"); err != nil {
+					t.Fatalf("Failed to set last_execution: %v", err)
+				}
+			}
+
+			for i, check := range tt.checks {
+				fired, err := trigger.Check(context.Background(), CheckModePolling)
+				if err != nil {
+					t.Fatalf("Check at %s failed: %v", check, err)
+				}
+				if fired != tt.fired[i] {
+					t.Errorf("Check at %s: fired = %v, want %v", check, fired, tt.fired[i])
+				}
+			}
+
+			stored, _ := state.GetString("minutely", "last_execution")
+			if stored != "2025-01-01T"+tt.stored+"Z" {
+				t.Errorf("Expected last_execution %s, got %s", tt.stored, stored)
+			}
+		})
+	}
+}
+
+func TestCronTrigger_SkipsRunMissedDuringDowntime(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger, err := NewCronTrigger("hourly", "0 * * * *", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+	trigger.now = cronClock(t, "05:10:00.000", "05:20:00.000", "06:00:05.000")
+
+	if err := state.SetString("hourly", "last_execution", "2025-01-01T01:00:00Z"); err != nil {
+		t.Fatalf("Failed to set last_execution: %v", err)
+	}
+
+	for i, want := range []bool{false, false, true} {
+		fired, err := trigger.Check(context.Background(), CheckModePolling)
+		if err != nil {
+			t.Fatalf("Check %d failed: %v", i, err)
+		}
+		if fired != want {
+			t.Errorf("Check %d: fired = %v, want %v", i, fired, want)
+		}
+		if i == 0 {
+			// The skipped run is recorded so it isn't considered again
+			if stored, _ := state.GetString("hourly", "last_execution"); stored != "2025-01-01T05:00:00Z" {
+				t.Errorf("Expected last_execution of skipped run, got %s", stored)
+			}
+		}
+	}
+}
+
+func TestCronTrigger_ScheduleFromState(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	if err := state.SetString("tuning", "schedule", "0 3 * * *"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	trigger, err := NewCronTrigger("nightly", "${state:tuning.schedule}", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+
+	after := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if next := trigger.NextRun(after); !next.Equal(time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextRun = %v, want 03:00 the next day", next)
+	}
+
+	// A new schedule in state applies without recreating the trigger
+	if err := state.SetString("tuning", "schedule", "30 * * * *"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if next := trigger.NextRun(after); !next.Equal(time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("NextRun = %v, want 12:30", next)
+	}
+
+	// An invalid schedule keeps the previous one
+	if err := state.SetString("tuning", "schedule", "bogus"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if next := trigger.NextRun(after); !next.Equal(time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("NextRun = %v, want previous schedule", next)
+	}
+
+	// The reference must resolve when the trigger is created
+	if _, err := NewCronTrigger("nightly", "${state:tuning.missing}", state, 0, nil, nil, nil); err == nil {
+		t.Error("Expected error for unset schedule reference")
+	}
+}
@@ -0,0 +1,68 @@
+package brun
+
+import (
+	"context"
+	"log"
+)
+
+// DaemonStartConfig represents the configuration for a DaemonStart trigger
+type DaemonStartConfig struct {
+	UnitConfig `yaml:",inline"`
+}
+
+// DaemonStartTrigger is a trigger that fires once each time the daemon
+// starts. Unlike the start trigger, it doesn't fire in one-shot runs, and
+// unlike the boot trigger, it fires again when the daemon is restarted.
+type DaemonStartTrigger struct {
+	name      string
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// NewDaemonStartTrigger creates a new DaemonStart trigger
+func NewDaemonStartTrigger(name string, onSuccess, onFailure, always []string) *DaemonStartTrigger {
+	return &DaemonStartTrigger{
+		name:      name,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}
+}
+
+// Name returns the trigger name
+func (d *DaemonStartTrigger) Name() string {
+	return d.name
+}
+
+// Type returns the trigger type
+func (d *DaemonStartTrigger) Type() string {
+	return "trigger.daemon_start"
+}
+
+// Check always returns true. The orchestrator only checks it in the startup
+// cycle of a daemon.
+func (d *DaemonStartTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	return true, nil
+}
+
+// Run executes when the trigger fires
+func (d *DaemonStartTrigger) Run(ctx context.Context) error {
+	log.Printf("Daemon start trigger '%s' activated", d.name)
+	return nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (d *DaemonStartTrigger) OnSuccess() []string {
+	return d.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (d *DaemonStartTrigger) OnFailure() []string {
+	return d.onFailure
+}
+
+// Always returns the list of units to always trigger
+func (d *DaemonStartTrigger) Always() []string {
+	return d.always
+}
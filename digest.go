@@ -0,0 +1,242 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DigestConfig represents the configuration for a Digest unit
+type DigestConfig struct {
+	UnitConfig `yaml:",inline"`
+	// FlushOn are the units, usually a cron trigger, that send the digest
+	// when they trigger it. Any other unit triggering it adds an event.
+	FlushOn []string `yaml:"flush_on"`
+}
+
+// maxDigestEvents is the number of events a digest keeps between flushes.
+// The oldest are dropped first.
+const maxDigestEvents = 1000
+
+// DigestUnit collects the results of the units that trigger it and reports
+// them in one summary when a flush unit triggers it. Events are kept in state
+// between flushes. The summary is its output, so a notification unit it
+// triggers sends one periodic rollup instead of a message per event.
+//
+// It is a trigger so that collecting an event doesn't fire its triggers; its
+// condition is only met when it is flushed.
+type DigestUnit struct {
+	name           string
+	flushOn        []string
+	state          *State
+	triggeringUnit string
+	triggerError   error
+	now            func() time.Time
+	onSuccess      []string
+	onFailure      []string
+	always         []string
+}
+
+// NewDigestUnit creates a new Digest unit that is flushed when one of the
+// flushOn units triggers it
+func NewDigestUnit(name string, flushOn []string, state *State, onSuccess, onFailure, always []string) *DigestUnit {
+	return &DigestUnit{
+		name:      name,
+		flushOn:   flushOn,
+		state:     state,
+		now:       time.Now,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}
+}
+
+// Name returns the unit name
+func (d *DigestUnit) Name() string {
+	return d.name
+}
+
+// Type returns the unit type
+func (d *DigestUnit) Type() string {
+	return "trigger.digest"
+}
+
+// SetTriggeringUnit sets the name of the unit that triggered the digest
+func (d *DigestUnit) SetTriggeringUnit(unitName string) {
+	d.triggeringUnit = unitName
+}
+
+// SetTriggerError sets the error from the triggering unit, nil if it
+// succeeded
+func (d *DigestUnit) SetTriggerError(err error) {
+	d.triggerError = err
+}
+
+// Check records the result of the triggering unit and returns false, or
+// returns true when a flush unit triggered the digest, or it is run by hand,
+// and there are events to send. Polling never fires it.
+func (d *DigestUnit) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	if mode != CheckModeManual {
+		return false, nil
+	}
+
+	// The orchestrator sets the source before each check
+	source, sourceErr := d.triggeringUnit, d.triggerError
+	d.triggeringUnit, d.triggerError = "", nil
+
+	if source == "" || slices.Contains(d.flushOn, source) {
+		events, _ := d.events()
+		if len(events) == 0 {
+			log.Printf("Digest unit '%s': no events to send", d.name)
+		}
+		return len(events) > 0, nil
+	}
+
+	if err := d.add(source, sourceErr); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// events returns the events collected since the last flush and the number
+// dropped because the digest was full
+func (d *DigestUnit) events() ([]map[string]any, int) {
+	var events []map[string]any
+	if queued, ok := d.state.Get(d.name, "events"); ok {
+		list, _ := queued.([]any)
+		for _, item := range list {
+			if entry, ok := item.(map[string]any); ok {
+				events = append(events, entry)
+			}
+		}
+	}
+
+	dropped := 0
+	if val, ok := d.state.Get(d.name, "dropped"); ok {
+		dropped, _ = val.(int)
+	}
+	return events, dropped
+}
+
+// add stores the result of unitName in the digest
+func (d *DigestUnit) add(unitName string, unitErr error) error {
+	errStr := ""
+	if unitErr != nil {
+		errStr = unitErr.Error()
+	}
+
+	queued, _ := d.state.Get(d.name, "events")
+	list, _ := queued.([]any)
+	list = append(list, map[string]any{
+		"unit":  unitName,
+		"error": errStr,
+		"time":  d.now().Format(time.RFC3339),
+	})
+	if len(list) > maxDigestEvents {
+		_, dropped := d.events()
+		if err := d.state.Set(d.name, "dropped", dropped+len(list)-maxDigestEvents); err != nil {
+			return fmt.Errorf("failed to save digest: %w", err)
+		}
+		list = list[len(list)-maxDigestEvents:]
+	}
+	if err := d.state.Set(d.name, "events", list); err != nil {
+		return fmt.Errorf("failed to save digest: %w", err)
+	}
+
+	log.Printf("Digest unit '%s': added result of '%s'", d.name, unitName)
+	return nil
+}
+
+// Run prints a summary of the events collected since the last flush and
+// clears them, e.g.
+//
+//	Digest of 4 events since 2026-01-02T10:00:00Z: 3 succeeded, 1 failed
+//	build: 2 ✓, 1 ✗
+//	deploy: 1 ✓
+//
+//	Failures:
+//	2026-01-02T10:20:00Z build: exit status 1
+func (d *DigestUnit) Run(ctx context.Context) error {
+	log.Printf("Running digest unit '%s'", d.name)
+
+	events, dropped := d.events()
+	if len(events) == 0 {
+		fmt.Println("No events since the last digest")
+		return nil
+	}
+
+	type tally struct{ passed, failed int }
+	var names []string
+	tallies := make(map[string]*tally)
+	var failures []string
+	for _, event := range events {
+		name, _ := event["unit"].(string)
+		errStr, _ := event["error"].(string)
+		timestamp, _ := event["time"].(string)
+
+		t, ok := tallies[name]
+		if !ok {
+			t = &tally{}
+			tallies[name] = t
+			names = append(names, name)
+		}
+		if errStr == "" {
+			t.passed++
+		} else {
+			t.failed++
+			failures = append(failures, fmt.Sprintf("%s %s: %s", timestamp, name, errStr))
+		}
+	}
+
+	since, _ := events[0]["time"].(string)
+	fmt.Printf("Digest of %d events since %s: %d succeeded, %d failed\n",
+		len(events), since, len(events)-len(failures), len(failures))
+	if dropped > 0 {
+		fmt.Printf("(%d older events dropped)\n", dropped)
+	}
+	for _, name := range names {
+		t := tallies[name]
+		var counts []string
+		if t.passed > 0 {
+			counts = append(counts, fmt.Sprintf("%d ✓", t.passed))
+		}
+		if t.failed > 0 {
+			counts = append(counts, fmt.Sprintf("%d ✗", t.failed))
+		}
+		fmt.Printf("%s: %s\n", name, strings.Join(counts, ", "))
+	}
+	if len(failures) > 0 {
+		fmt.Println("\nFailures:")
+		for _, failure := range failures {
+			fmt.Println(failure)
+		}
+	}
+
+	if err := d.state.DeleteKey(d.name, "events"); err != nil {
+		return fmt.Errorf("failed to clear digest: %w", err)
+	}
+	if dropped > 0 {
+		if err := d.state.DeleteKey(d.name, "dropped"); err != nil {
+			return fmt.Errorf("failed to clear digest: %w", err)
+		}
+	}
+	return nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (d *DigestUnit) OnSuccess() []string {
+	return d.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (d *DigestUnit) OnFailure() []string {
+	return d.onFailure
+}
+
+// Always returns the list of units to always trigger
+func (d *DigestUnit) Always() []string {
+	return d.always
+}
@@ -0,0 +1,96 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDigestUnit_CollectAndFlush(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	units := []Unit{
+		NewStartTrigger("start", []string{"build", "test"}, nil, nil),
+		NewRunUnit("build", "true", "", 0, "", false, nil, nil, []string{"digest"}),
+		NewRunUnit("test", "exit 3", "", 0, "", false, nil, nil, []string{"digest"}),
+		NewRunUnit("hourly", "true", "", 0, "", false, []string{"digest"}, nil, nil),
+		NewDigestUnit("digest", []string{"hourly"}, state, []string{"report"}, nil, nil),
+		NewRunUnit("report", "true", "", 0, "", false, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+	ctx := context.Background()
+
+	// Collecting events doesn't run the digest or its triggers
+	if err := orchestrator.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	results := orchestrator.GetResults()
+	if _, ok := results["digest"]; ok {
+		t.Error("Expected digest not to run while collecting")
+	}
+	if _, ok := results["report"]; ok {
+		t.Error("Expected digest triggers not to fire while collecting")
+	}
+
+	// Events survive a restart
+	state = NewState(state.filePath)
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	units[4] = NewDigestUnit("digest", []string{"hourly"}, state, []string{"report"}, nil, nil)
+	orchestrator = NewOrchestrator(units)
+
+	if err := orchestrator.RunSingleUnit(ctx, "hourly", true); err != nil {
+		t.Fatalf("RunSingleUnit failed: %v", err)
+	}
+	results = orchestrator.GetResults()
+	result, ok := results["digest"]
+	if !ok {
+		t.Fatal("Expected the flush unit to run the digest")
+	}
+	for _, want := range []string{
+		"Digest of 2 events since",
+		"1 succeeded, 1 failed",
+		"build: 1 ✓",
+		"test: 1 ✗",
+		"test: script exited with code 3",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("Output missing %q:\n%s", want, result.Output)
+		}
+	}
+	if _, ok := results["report"]; !ok {
+		t.Error("Expected the digest to trigger the report")
+	}
+
+	// The digest is empty after a flush, so flushing again sends nothing
+	if err := orchestrator.RunSingleUnit(ctx, "hourly", true); err != nil {
+		t.Fatalf("RunSingleUnit failed: %v", err)
+	}
+	if _, ok := orchestrator.GetResults()["digest"]; ok {
+		t.Error("Expected an empty digest not to run")
+	}
+}
+
+func TestLoadConfig_DigestRequiresFlushOn(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configData := `config:
+  state_location: state.yaml
+units:
+  - digest:
+      name: digest
+`
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if _, err := config.CreateUnits(); err == nil || !strings.Contains(err.Error(), "flush_on is required") {
+		t.Errorf("Expected flush_on required error, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDiskCooldown is how long a disk trigger waits before firing again
+// while free space stays below the threshold
+const defaultDiskCooldown = time.Hour
+
+// DiskTrigger is a trigger unit that fires when free disk space drops below
+// a threshold
+type DiskTrigger struct {
+	name      string
+	path      string
+	percent   float64 // threshold as percent free (0 if bytes is used)
+	bytes     uint64  // threshold as bytes free (0 if percent is used)
+	cooldown  time.Duration
+	state     *State
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// DiskConfig represents the configuration for a disk trigger. The common
+// cooldown field also sets how often the trigger fires again while space
+// stays low.
+type DiskConfig struct {
+	UnitConfig `yaml:",inline"`
+	Path       string `yaml:"path"`
+	Threshold  string `yaml:"threshold"`
+}
+
+// NewDiskTrigger creates a new disk trigger unit. threshold is either a
+// percentage of free space (e.g., "10%") or a size (e.g., "500MB", "2GiB").
+func NewDiskTrigger(name, path, threshold string, cooldown time.Duration, state *State, onSuccess, onFailure, always []string) (*DiskTrigger, error) {
+	percent, bytes, err := parseDiskThreshold(threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if cooldown == 0 {
+		cooldown = defaultDiskCooldown
+	}
+
+	return &DiskTrigger{
+		name:      name,
+		path:      path,
+		percent:   percent,
+		bytes:     bytes,
+		cooldown:  cooldown,
+		state:     state,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}, nil
+}
+
+// diskSizeUnits maps size suffixes to their multipliers
+var diskSizeUnits = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	// Longest suffixes first so "KiB" isn't matched as "B"
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseDiskThreshold parses a threshold as a percentage or a size in bytes
+func parseDiskThreshold(threshold string) (percent float64, bytes uint64, err error) {
+	threshold = strings.TrimSpace(threshold)
+
+	if value, ok := strings.CutSuffix(threshold, "%"); ok {
+		percent, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || percent <= 0 || percent >= 100 {
+			return 0, 0, fmt.Errorf("invalid threshold '%s': percent must be between 0 and 100", threshold)
+		}
+		return percent, 0, nil
+	}
+
+	multiplier := uint64(1)
+	value := threshold
+	for _, unit := range diskSizeUnits {
+		if v, ok := strings.CutSuffix(threshold, unit.suffix); ok {
+			value = v
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	size, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || size <= 0 {
+		return 0, 0, fmt.Errorf("invalid threshold '%s': expected a percent (10%%) or size (500MB)", threshold)
+	}
+
+	return 0, uint64(size * float64(multiplier)), nil
+}
+
+// Name returns the name of the unit
+func (d *DiskTrigger) Name() string {
+	return d.name
+}
+
+// Type returns the unit type
+func (d *DiskTrigger) Type() string {
+	return "trigger.disk"
+}
+
+// isLow returns true if free space is below the threshold
+func (d *DiskTrigger) isLow(free, total uint64) bool {
+	if d.percent > 0 {
+		return total > 0 && float64(free)/float64(total)*100 < d.percent
+	}
+	return free < d.bytes
+}
+
+// Check returns true if free space is below the threshold. While space stays
+// low, it fires again only after the cooldown has passed.
+func (d *DiskTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	free, total, err := diskSpace(d.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to get disk space for %s: %w", d.path, err)
+	}
+
+	lastFiredStr, fired := d.state.GetString(d.name, "last_fired")
+
+	if !d.isLow(free, total) {
+		// Recovered, so the next drop fires right away
+		if fired {
+			if err := d.state.DeleteKey(d.name, "last_fired"); err != nil {
+				return false, fmt.Errorf("failed to clear disk state: %w", err)
+			}
+		}
+		return false, nil
+	}
+
+	now := time.Now()
+	if fired {
+		if lastFired, err := time.Parse(time.RFC3339, lastFiredStr); err == nil && now.Sub(lastFired) < d.cooldown {
+			return false, nil
+		}
+	}
+
+	if err := d.state.SetString(d.name, "last_fired", now.Format(time.RFC3339)); err != nil {
+		return false, fmt.Errorf("failed to save disk state: %w", err)
+	}
+
+	return true, nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (d *DiskTrigger) OnSuccess() []string {
+	return d.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (d *DiskTrigger) OnFailure() []string {
+	return d.onFailure
+}
+
+// Always returns the list of units to trigger regardless of success/failure
+func (d *DiskTrigger) Always() []string {
+	return d.always
+}
+
+// Run executes the trigger unit
+// Note: Check() has already been called by the orchestrator before Run() is invoked
+func (d *DiskTrigger) Run(ctx context.Context) error {
+	free, total, _ := diskSpace(d.path)
+	log.Printf("Disk trigger '%s' activated (%s: %d MiB free of %d MiB)", d.name, d.path, free>>20, total>>20)
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package brun
+
+import "errors"
+
+// diskSpace is not supported on this platform
+func diskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.New("disk triggers are not supported on this platform")
+}
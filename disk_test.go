@@ -0,0 +1,129 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDiskThreshold(t *testing.T) {
+	tests := []struct {
+		threshold string
+		percent   float64
+		bytes     uint64
+		wantErr   bool
+	}{
+		{"10%", 10, 0, false},
+		{"2.5 %", 2.5, 0, false},
+		{"1024", 0, 1024, false},
+		{"500MB", 0, 500e6, false},
+		{"2GiB", 0, 2 << 30, false},
+		{"1.5 KiB", 0, 1536, false},
+		{"100B", 0, 100, false},
+		{"0%", 0, 0, true},
+		{"100%", 0, 0, true},
+		{"lots", 0, 0, true},
+		{"-5GB", 0, 0, true},
+		{"", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		percent, bytes, err := parseDiskThreshold(tt.threshold)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDiskThreshold(%q) error = %v, wantErr %v", tt.threshold, err, tt.wantErr)
+			continue
+		}
+		if percent != tt.percent || bytes != tt.bytes {
+			t.Errorf("parseDiskThreshold(%q) = %v, %v, want %v, %v", tt.threshold, percent, bytes, tt.percent, tt.bytes)
+		}
+	}
+}
+
+func TestDiskTrigger_Check(t *testing.T) {
+	tempDir := t.TempDir()
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+
+	// No disk has this much free space, so it is always low
+	trigger, err := NewDiskTrigger("disk-low", tempDir, "1000000TB", 0, state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDiskTrigger failed: %v", err)
+	}
+	ctx := context.Background()
+
+	check := func(want bool, msg string) {
+		t.Helper()
+		got, err := trigger.Check(ctx, CheckModePolling)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: Check() = %v, want %v", msg, got, want)
+		}
+	}
+
+	check(true, "space low")
+	check(false, "within cooldown")
+
+	trigger.cooldown = time.Nanosecond
+	check(true, "cooldown passed")
+
+	// Recovering clears the cooldown
+	trigger.cooldown = time.Hour
+	trigger.bytes = 1
+	check(false, "space recovered")
+	if _, ok := state.GetString("disk-low", "last_fired"); ok {
+		t.Error("last_fired should be cleared after recovery")
+	}
+
+	trigger.bytes = 1000000e12
+	check(true, "space low again")
+}
+
+func TestDiskTrigger_MissingPath(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger, err := NewDiskTrigger("disk-low", "/does/not/exist", "10%", 0, state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDiskTrigger failed: %v", err)
+	}
+
+	if _, err := trigger.Check(context.Background(), CheckModePolling); err == nil {
+		t.Error("Expected error for missing path")
+	}
+}
+
+func TestLoadConfig_DiskCooldown(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configData := `config:
+  state_location: state.yaml
+units:
+  - disk:
+      name: disk-low
+      path: /
+      threshold: 10%
+      cooldown: 30m
+`
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+	if trigger := units[0].(*DiskTrigger); trigger.cooldown != 30*time.Minute {
+		t.Errorf("Expected 30m cooldown, got %v", trigger.cooldown)
+	}
+
+	// The orchestrator applies the cooldown as it does for other triggers
+	cooldowns, err := config.UnitCooldowns()
+	if err != nil || cooldowns["disk-low"] != 30*time.Minute {
+		t.Errorf("Expected 30m orchestrator cooldown, got %v, %v", cooldowns, err)
+	}
+}
@@ -0,0 +1,190 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// doctorDialTimeout bounds each connectivity check
+const doctorDialTimeout = 5 * time.Second
+
+// DoctorCheck is the result of one environment check. The check passed if
+// Err is nil.
+type DoctorCheck struct {
+	Name string
+	Err  error
+}
+
+// doctorChecks collects checks, skipping duplicates such as the git binary
+// check for several git units
+type doctorChecks struct {
+	checks []DoctorCheck
+	seen   map[string]bool
+}
+
+func (d *doctorChecks) add(name string, check func() error) {
+	if d.seen[name] {
+		return
+	}
+	d.seen[name] = true
+	d.checks = append(d.checks, DoctorCheck{Name: name, Err: check()})
+}
+
+// Doctor checks that the environment has what the configured units need:
+// binaries they run, write access to the state file and output paths, and
+// connectivity to notification servers. It doesn't modify state.
+func (c *Config) Doctor(ctx context.Context) []DoctorCheck {
+	d := &doctorChecks{seen: make(map[string]bool)}
+
+	d.add("state file writable", func() error {
+		if c.ConfigBlock.StateLocation == "" {
+			return fmt.Errorf("config.state_location is required")
+		}
+		return checkWritable(c.ConfigBlock.StateLocation)
+	})
+
+	for _, w := range c.Units {
+		switch {
+		case w.Boot != nil:
+			d.add("/proc/uptime readable (boot)", func() error {
+				_, err := (&BootDetector{}).GetBootTime()
+				return err
+			})
+		case w.Compose != nil:
+			d.add("docker binary found (compose)", func() error { return checkBinary("docker") })
+		case w.Copy != nil:
+			d.add("rsync binary found (copy)", func() error { return checkBinary("rsync") })
+		case w.Email != nil:
+			cfg := w.Email
+			port := cfg.SMTPPort
+			if port == 0 {
+				port = 587
+			}
+			addr := net.JoinHostPort(cfg.SMTPHost, fmt.Sprint(port))
+			d.add("SMTP server reachable ("+addr+")", func() error { return checkDial(ctx, addr) })
+			if cfg.OutputDir != "" {
+				d.add("output_dir writable ("+cfg.OutputDir+")", func() error { return checkWritableDir(cfg.OutputDir) })
+			}
+		case w.Git != nil:
+			if w.Git.UseNativeGit == nil || *w.Git.UseNativeGit {
+				d.add("git binary found (git)", func() error { return checkBinary("git") })
+			}
+			repo := w.Git.Repository
+			d.add("git repository exists ("+repo+")", func() error {
+				_, err := os.Stat(filepath.Join(repo, ".git"))
+				return err
+			})
+		case w.Journal != nil:
+			d.add("journalctl binary found (journal)", func() error { return checkBinary("journalctl") })
+		case w.Log != nil:
+			file := w.Log.File
+			d.add("log file writable ("+file+")", func() error { return checkWritable(file) })
+		case w.Ntfy != nil:
+			cfg := w.Ntfy
+			server := cfg.Server
+			if server == "" {
+				server = "https://ntfy.sh"
+			}
+			d.add("ntfy server reachable ("+server+")", func() error {
+				addr, err := serverAddr(server)
+				if err != nil {
+					return err
+				}
+				return checkDial(ctx, addr)
+			})
+			if cfg.OutputDir != "" {
+				d.add("output_dir writable ("+cfg.OutputDir+")", func() error { return checkWritableDir(cfg.OutputDir) })
+			}
+		case w.Reboot != nil:
+			d.add("reboot binary found (reboot)", func() error { return checkBinary("reboot") })
+		case w.Run != nil:
+			shell := w.Run.Shell
+			if shell == "" {
+				shell = defaultShell
+			}
+			d.add(shell+" binary found (run)", func() error { return checkBinary(shell) })
+		}
+	}
+
+	return d.checks
+}
+
+// checkBinary returns an error if name isn't found in PATH
+func checkBinary(name string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found in PATH", name)
+	}
+	return nil
+}
+
+// checkWritable returns an error if the file at path can't be written, or
+// created if it doesn't exist. Missing parent directories must be creatable.
+func checkWritable(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	return checkWritableDir(filepath.Dir(path))
+}
+
+// checkWritableDir returns an error if a file can't be created in dir, or in
+// its nearest existing parent if dir doesn't exist yet
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".brun-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// serverAddr returns the host:port of a server URL, using the scheme's
+// default port if none is given
+func serverAddr(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// checkDial resolves addr and opens a TCP connection to it
+func checkDial(ctx context.Context, addr string) error {
+	dialer := net.Dialer{Timeout: doctorDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,88 @@
+package brun
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestConfig_Doctor(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// A port with nothing listening
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	config := Config{
+		ConfigBlock: ConfigBlock{StateLocation: filepath.Join(tmpDir, "state", "state.yaml")},
+		Units: []UnitConfigWrapper{
+			{Log: &LogConfig{UnitConfig: UnitConfig{Name: "log"}, File: filepath.Join(tmpDir, "logs", "brun.log")}},
+			{Git: &GitConfig{UnitConfig: UnitConfig{Name: "repo1"}, Repository: filepath.Join(tmpDir, "missing")}},
+			{Git: &GitConfig{UnitConfig: UnitConfig{Name: "repo2"}, Repository: filepath.Join(tmpDir, "missing")}},
+			{Ntfy: &NtfyConfig{UnitConfig: UnitConfig{Name: "ntfy"}, Topic: "builds", Server: server.URL}},
+			{Email: &EmailConfig{UnitConfig: UnitConfig{Name: "email"}, SMTPHost: "127.0.0.1", SMTPPort: closedPort}},
+		},
+	}
+
+	checks := config.Doctor(context.Background())
+
+	results := make(map[string]error)
+	for _, check := range checks {
+		if _, ok := results[check.Name]; ok {
+			t.Errorf("Duplicate check %q", check.Name)
+		}
+		results[check.Name] = check.Err
+	}
+
+	expectPass := []string{
+		"state file writable",
+		"log file writable (" + filepath.Join(tmpDir, "logs", "brun.log") + ")",
+		"ntfy server reachable (" + server.URL + ")",
+	}
+	for _, name := range expectPass {
+		err, ok := results[name]
+		if !ok {
+			t.Errorf("Missing check %q", name)
+		} else if err != nil {
+			t.Errorf("Expected %q to pass, got %v", name, err)
+		}
+	}
+
+	expectFail := []string{
+		"git repository exists (" + filepath.Join(tmpDir, "missing") + ")",
+		"SMTP server reachable (" + net.JoinHostPort("127.0.0.1", strconv.Itoa(closedPort)) + ")",
+	}
+	for _, name := range expectFail {
+		err, ok := results[name]
+		if !ok {
+			t.Errorf("Missing check %q", name)
+		} else if err == nil {
+			t.Errorf("Expected %q to fail", name)
+		}
+	}
+}
+
+func TestServerAddr(t *testing.T) {
+	tests := map[string]string{
+		"https://ntfy.sh":           "ntfy.sh:443",
+		"http://localhost":          "localhost:80",
+		"http://192.168.1.5:8080/x": "192.168.1.5:8080",
+	}
+	for server, want := range tests {
+		got, err := serverAddr(server)
+		if err != nil || got != want {
+			t.Errorf("serverAddr(%q) = %q, %v, want %q", server, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package brun
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dynamicRefRegex matches a config value that is read at check time, like
+// ${state:git-watch.poll}, ${env:GIT_POLL}, or ${state:git-watch.poll:-5m}
+var dynamicRefRegex = regexp.MustCompile(`^\$\{(state|env):([^}]+?)(?::-([^}]*))?\}$`)
+
+// isDynamicRef returns true if value is a state or environment reference
+func isDynamicRef(value string) bool {
+	return dynamicRefRegex.MatchString(value)
+}
+
+// resolveDynamic returns the current value of a ${state:<unit>.<key>} or
+// ${env:<NAME>} reference, or the default after :- if the value isn't set.
+// State values are read from the state file so changes made by another
+// process are seen. Values that aren't references are returned unchanged.
+func resolveDynamic(value string, state *State) (string, error) {
+	m := dynamicRefRegex.FindStringSubmatchIndex(value)
+	if m == nil {
+		return value, nil
+	}
+	source, name := value[m[2]:m[3]], value[m[4]:m[5]]
+	hasDefault := m[6] >= 0
+
+	switch source {
+	case "env":
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			return v, nil
+		}
+	case "state":
+		i := strings.LastIndex(name, ".")
+		if i <= 0 || i == len(name)-1 {
+			return "", fmt.Errorf("invalid state reference '%s', expected ${state:<unit>.<key>}", value)
+		}
+		if state == nil {
+			return "", fmt.Errorf("no state to resolve '%s'", value)
+		}
+		v, ok, err := state.Refresh(name[:i], name[i+1:])
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	if hasDefault {
+		return value[m[6]:m[7]], nil
+	}
+	return "", fmt.Errorf("'%s' is not set", value)
+}
+
+// embeddedRefRegex matches references within a value, like
+// /builds/${state:git-watch.last_commit_hash}, ${date:2006-01-02}, or
+// ${HOME}. A reference preceded by another $ is escaped.
+var embeddedRefRegex = regexp.MustCompile(`\$?\$\{(?:(state|env|date):([^}]*)|([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?)\}`)
+
+// expandRefs replaces ${state:<unit>.<key>}, ${env:<NAME>}, and
+// ${date:<layout>} references in value, where layout is a Go time layout. If
+// lookupVar is set, ${NAME} and ${NAME:-default} references are replaced with
+// its values; otherwise they are left for the shell. $${...} is kept as a
+// literal ${...} in either case.
+func expandRefs(value string, state *State, lookupVar func(name string) (string, bool)) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var expandErr error
+	expanded := embeddedRefRegex.ReplaceAllStringFunc(value, func(ref string) string {
+		m := embeddedRefRegex.FindStringSubmatch(ref)
+		source, name := m[1], m[3]
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		if name != "" && lookupVar == nil {
+			return ref
+		}
+
+		switch {
+		case source == "date":
+			return time.Now().Format(m[2])
+		case source != "":
+			v, err := resolveDynamic(ref, state)
+			if err != nil && expandErr == nil {
+				expandErr = err
+			}
+			return v
+		}
+
+		if v, ok := lookupVar(name); ok && v != "" {
+			return v
+		}
+		if strings.Contains(ref, ":-") {
+			return m[4]
+		}
+		if expandErr == nil {
+			expandErr = fmt.Errorf("'%s' is not set", ref)
+		}
+		return ""
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
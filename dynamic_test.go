@@ -0,0 +1,103 @@
+package brun
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveDynamic(t *testing.T) {
+	t.Setenv("BRUN_TEST_POLL", "2m")
+
+	stateFile := filepath.Join(t.TempDir(), "state.yaml")
+	state := NewState(stateFile)
+	if err := state.Set("git-watch", "poll", "30s"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "5m", want: "5m"},
+		{value: "${env:BRUN_TEST_POLL}", want: "2m"},
+		{value: "${env:BRUN_TEST_UNSET:-1h}", want: "1h"},
+		{value: "${env:BRUN_TEST_UNSET}", wantErr: true},
+		{value: "${state:git-watch.poll}", want: "30s"},
+		{value: "${state:git-watch.missing:-10s}", want: "10s"},
+		{value: "${state:git-watch.missing}", wantErr: true},
+		{value: "${state:poll}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveDynamic(tt.value, state)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("resolveDynamic(%q) = %q, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveDynamic(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+
+	// A value written to the state file by another process is picked up and
+	// kept when the state is saved again
+	if err := os.WriteFile(stateFile, []byte("git-watch:\n  poll: 45s\n"), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+	if got, err := resolveDynamic("${state:git-watch.poll}", state); err != nil || got != "45s" {
+		t.Errorf("Expected poll from state file, got %q, %v", got, err)
+	}
+	if err := state.Set("other", "key", 1); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if got, _ := state.GetString("git-watch", "poll"); got != "45s" {
+		t.Errorf("Expected refreshed poll to be kept, got %q", got)
+	}
+}
+
+func TestExpandRefs(t *testing.T) {
+	t.Setenv("BRUN_TEST_DIR", "/srv")
+
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	if err := state.Set("git-watch", "last_commit_hash", "abc123"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	vars := func(name string) (string, bool) {
+		v, ok := map[string]string{"TOOLCHAIN": "nightly"}[name]
+		return v, ok
+	}
+	today := time.Now().Format("2006-01-02")
+
+	tests := []struct {
+		value   string
+		lookup  func(string) (string, bool)
+		want    string
+		wantErr bool
+	}{
+		{value: "/builds/${state:git-watch.last_commit_hash}", want: "/builds/abc123"},
+		{value: "/builds/${date:2006-01-02}/${state:git-watch.last_commit_hash}", want: "/builds/" + today + "/abc123"},
+		{value: "${env:BRUN_TEST_DIR}/${state:git-watch.missing:-none}", want: "/srv/none"},
+		{value: "echo ${HOME} $$ $${state:git-watch.last_commit_hash}", want: "echo ${HOME} $$ ${state:git-watch.last_commit_hash}"},
+		{value: "${TOOLCHAIN}/${UNSET_VAR:-default}", lookup: vars, want: "nightly/default"},
+		{value: "$${TOOLCHAIN}", lookup: vars, want: "${TOOLCHAIN}"},
+		{value: "$${TOOLCHAIN}", want: "${TOOLCHAIN}"},
+		{value: "${UNSET_VAR}", lookup: vars, wantErr: true},
+		{value: "/builds/${state:git-watch.missing}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := expandRefs(tt.value, state, tt.lookup)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expandRefs(%q) = %q, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expandRefs(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
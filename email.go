@@ -3,47 +3,89 @@ package brun
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"html"
 	"log"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
 	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
 
 // EmailConfig represents the configuration for an Email unit
 type EmailConfig struct {
-	UnitConfig    `yaml:",inline"`
-	To            []string `yaml:"to"`
-	From          string   `yaml:"from"`
-	SubjectPrefix string   `yaml:"subject_prefix,omitempty"`
-	SMTPHost      string   `yaml:"smtp_host"`
-	SMTPPort      int      `yaml:"smtp_port,omitempty"`
-	SMTPUser      string   `yaml:"smtp_user,omitempty"`
-	SMTPPassword  string   `yaml:"smtp_password,omitempty"`
-	SMTPUseTLS    *bool    `yaml:"smtp_use_tls,omitempty"`
-	IncludeOutput *bool    `yaml:"include_output,omitempty"`
-	LimitLines    int      `yaml:"limit_lines,omitempty"`
+	UnitConfig      `yaml:",inline"`
+	To              []string          `yaml:"to"`
+	From            string            `yaml:"from"`
+	SubjectPrefix   string            `yaml:"subject_prefix,omitempty"`
+	ReplyTo         string            `yaml:"reply_to,omitempty"`
+	Headers         map[string]string `yaml:"headers,omitempty"`
+	SMTPHost        string            `yaml:"smtp_host"`
+	SMTPPort        int               `yaml:"smtp_port,omitempty"`
+	SMTPUser        string            `yaml:"smtp_user,omitempty"`
+	SMTPPassword    string            `yaml:"smtp_password,omitempty"`
+	SMTPAuth        string            `yaml:"smtp_auth,omitempty"`
+	SMTPUseTLS      *bool             `yaml:"smtp_use_tls,omitempty"`
+	IncludeOutput   *bool             `yaml:"include_output,omitempty"`
+	LimitLines      int               `yaml:"limit_lines,omitempty"`
+	OutputDir       string            `yaml:"output_dir,omitempty"`
+	OutputURL       string            `yaml:"output_url_template,omitempty"`
+	Timeout         string            `yaml:"timeout,omitempty"`
+	StderrOnFailure bool              `yaml:"stderr_on_failure,omitempty"`
+	Critical        bool              `yaml:"critical,omitempty"`
+	RetryTTL        string            `yaml:"retry_ttl,omitempty"`
+	HTML            bool              `yaml:"html,omitempty"`
+	CollapseOutput  bool              `yaml:"collapse_output,omitempty"`
+}
+
+// smtpSender delivers a message to an SMTP server. tlsConfig is nil when
+// STARTTLS is disabled.
+type smtpSender interface {
+	Send(ctx context.Context, addr string, auth smtp.Auth, tlsConfig *tls.Config, from string, to []string, msg []byte) error
 }
 
 // EmailUnit sends email notifications
 type EmailUnit struct {
-	name           string
-	to             []string
-	from           string
-	subjectPrefix  string
-	smtpHost       string
-	smtpPort       int
-	smtpUser       string
-	smtpPassword   string
-	smtpUseTLS     bool
-	includeOutput  bool
-	limitLines     int
-	output         string // Output from the triggering unit
-	triggeringUnit string // Name of the unit that triggered this email
-	triggerError   error  // Error from the triggering unit (if any)
-	onSuccess      []string
-	onFailure      []string
-	always         []string
+	name            string
+	to              []string
+	from            string
+	subjectPrefix   string
+	replyTo         string
+	headers         map[string]string // Extra headers, e.g. X-Priority
+	html            bool              // Send multipart/alternative with an HTML part
+	collapseOutput  bool              // Collapse the output in the HTML part
+	smtpHost        string
+	smtpPort        int
+	smtpUser        string
+	smtpPassword    string
+	smtpAuth        string // plain, login, or cram-md5
+	smtpUseTLS      bool
+	includeOutput   bool
+	limitLines      int
+	outputDir       string // Directory to store full output when over limitLines
+	outputURL       string // URL template for linking to stored output
+	timeout         time.Duration
+	output          string            // Output from the triggering unit
+	stderr          string            // Stderr from the triggering unit
+	stderrOnFailure bool              // Include only stderr when the triggering unit failed
+	count           int               // Count from a count unit earlier in the chain
+	env             map[string]string // Variables published earlier in the chain
+	quietHours      *QuietHours       // nil if quiet hours aren't configured
+	quietState      *State
+	critical        bool
+	outbox          *Outbox // nil if failed notifications aren't retried
+	triggeringUnit  string  // Name of the unit that triggered this email
+	triggerError    error   // Error from the triggering unit (if any)
+	sender          smtpSender
+	onSuccess       []string
+	onFailure       []string
+	always          []string
 }
 
 // NewEmailUnit creates a new Email unit
@@ -62,6 +104,7 @@ func NewEmailUnit(name string, to []string, from, subjectPrefix, smtpHost string
 		smtpUseTLS:    smtpUseTLS,
 		includeOutput: includeOutput,
 		limitLines:    limitLines,
+		sender:        netSMTPSender{},
 		onSuccess:     onSuccess,
 		onFailure:     onFailure,
 		always:        always,
@@ -78,11 +121,150 @@ func (e *EmailUnit) Type() string {
 	return "email"
 }
 
+// SetOutputLink configures storing the full output in dir and linking to it
+// with a URL built from urlTemplate when the output exceeds limit_lines
+func (e *EmailUnit) SetOutputLink(dir, urlTemplate string) {
+	e.outputDir = dir
+	e.outputURL = urlTemplate
+}
+
+// reservedEmailHeaders are set by the email unit and can't be overridden
+var reservedEmailHeaders = []string{
+	"From", "To", "Cc", "Bcc", "Subject", "Date", "Reply-To",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// validateEmailHeaders checks that custom headers are well formed and don't
+// override the headers set by the email unit
+func validateEmailHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+			return fmt.Errorf("invalid header name '%s'", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header '%s' must not contain line breaks", name)
+		}
+		for _, reserved := range reservedEmailHeaders {
+			if strings.EqualFold(name, reserved) {
+				return fmt.Errorf("header '%s' can't be overridden", name)
+			}
+		}
+	}
+	return nil
+}
+
+// SetHeaders sets the Reply-To address and extra headers added to the message
+func (e *EmailUnit) SetHeaders(replyTo string, headers map[string]string) {
+	e.replyTo = replyTo
+	e.headers = headers
+}
+
+// SetHTML sends the message as multipart/alternative with an HTML part
+// next to the plain text, so clients can pick the best rendering
+func (e *EmailUnit) SetHTML(html bool) {
+	e.html = html
+}
+
+// SetCollapseOutput shows the output in the HTML part as a collapsed section
+// below the summary. The plain text part has no collapsible construct and is
+// unchanged.
+func (e *EmailUnit) SetCollapseOutput(collapse bool) {
+	e.collapseOutput = collapse
+}
+
+// SetAuthMechanism sets the SMTP authentication mechanism: "plain" (the
+// default), "login", or "cram-md5"
+func (e *EmailUnit) SetAuthMechanism(mechanism string) {
+	e.smtpAuth = mechanism
+}
+
+// SetTimeout limits how long sending the email may take (0 = no limit)
+func (e *EmailUnit) SetTimeout(timeout time.Duration) {
+	e.timeout = timeout
+}
+
+// SetQuietHours holds back notifications during quiet hours. Queued
+// notifications are kept in state.
+func (e *EmailUnit) SetQuietHours(quietHours *QuietHours, state *State) {
+	e.quietHours = quietHours
+	e.quietState = state
+}
+
+// SetCritical makes notifications bypass quiet hours
+func (e *EmailUnit) SetCritical(critical bool) {
+	e.critical = critical
+}
+
+// SetOutbox keeps notifications that fail to send in outbox and retries
+// them on later cycles
+func (e *EmailUnit) SetOutbox(outbox *Outbox) {
+	e.outbox = outbox
+}
+
+// FlushQueued retries notifications that failed to send and sends the
+// notifications queued during quiet hours once they have ended
+func (e *EmailUnit) FlushQueued(ctx context.Context) error {
+	err := e.outbox.retry(e.name, func(title, body string) error {
+		return e.sendEmail(ctx, title, body)
+	})
+	if err != nil {
+		return err
+	}
+
+	queued, err := e.quietHours.takeQueued(e.quietState, e.name)
+	if err != nil {
+		return err
+	}
+	for i, q := range queued {
+		if err := e.sendEmail(ctx, q.Title, q.Body); err != nil {
+			if err := requeue(e.quietState, e.name, queued[i:]); err != nil {
+				log.Printf("Email unit '%s': failed to requeue notifications: %v", e.name, err)
+			}
+			return fmt.Errorf("failed to send queued email notification: %w", err)
+		}
+	}
+	if len(queued) > 0 {
+		log.Printf("Email unit '%s' sent %d notifications queued during quiet hours", e.name, len(queued))
+	}
+	return nil
+}
+
 // SetOutput sets the output data from the triggering unit
 func (e *EmailUnit) SetOutput(output string) {
 	e.output = output
 }
 
+// SetEnv sets variables published earlier in the chain, available to the
+// prefix template as {{.Env.NAME}}
+func (e *EmailUnit) SetEnv(env map[string]string) {
+	e.env = env
+}
+
+// SetCount sets the count from a count unit earlier in the chain, available
+// to the subject prefix as {{.Count}}
+func (e *EmailUnit) SetCount(count int) {
+	e.count = count
+}
+
+// SetStderr sets the stderr output from the triggering unit
+func (e *EmailUnit) SetStderr(stderr string) {
+	e.stderr = stderr
+}
+
+// SetStderrOnFailure includes only the triggering unit's stderr instead of
+// its full output when the triggering unit failed
+func (e *EmailUnit) SetStderrOnFailure(stderrOnFailure bool) {
+	e.stderrOnFailure = stderrOnFailure
+}
+
+// selectOutput returns the output to include in the message
+func (e *EmailUnit) selectOutput() string {
+	if e.stderrOnFailure && e.triggerError != nil {
+		return e.stderr
+	}
+	return e.output
+}
+
 // SetTriggeringUnit sets the name of the unit that triggered this email
 func (e *EmailUnit) SetTriggeringUnit(unitName string) {
 	e.triggeringUnit = unitName
@@ -97,6 +279,13 @@ func (e *EmailUnit) SetTriggerError(err error) {
 func (e *EmailUnit) Run(ctx context.Context) error {
 	log.Printf("Running email unit '%s'", e.name)
 
+	// Apply timeout if configured
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
 	// Prepare email content
 	timestamp := time.Now().Format(time.RFC3339)
 	unitName := e.triggeringUnit
@@ -112,28 +301,78 @@ func (e *EmailUnit) Run(ctx context.Context) error {
 
 	subject := ""
 	if e.subjectPrefix != "" {
-		subject = e.subjectPrefix + ": "
+		data := notificationData{Unit: unitName, Count: e.count, Env: e.env, BrunVersion: Version()}
+		subject = renderNotificationTemplate(e.subjectPrefix, data) + ": "
 	}
 	subject += fmt.Sprintf("%s:%s", unitName, status)
 
-	// Build body
+	body := e.buildBody(unitName, timestamp)
+
+	// Send anything queued during quiet hours first, in order
+	if err := e.FlushQueued(ctx); err != nil {
+		log.Printf("Email unit '%s': %v", e.name, err)
+	}
+
+	if !e.critical {
+		held, err := e.quietHours.hold(e.quietState, e.name, subject, body)
+		if err != nil {
+			return err
+		}
+		if held {
+			return nil
+		}
+	}
+
+	// Send email
+	if err := e.sendEmail(ctx, subject, body); err != nil {
+		if e.outbox != nil {
+			if err := e.outbox.add(e.name, subject, body); err != nil {
+				log.Printf("Email unit '%s': %v", e.name, err)
+			} else {
+				log.Printf("Email unit '%s': queued notification for retry", e.name)
+			}
+		}
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("Email unit '%s' completed, sent to %s", e.name, strings.Join(e.to, ", "))
+	return nil
+}
+
+// buildBody constructs the email body
+func (e *EmailUnit) buildBody(unitName, timestamp string) string {
 	var body strings.Builder
 	body.WriteString(fmt.Sprintf("Triggered by unit: %s\n", unitName))
-	body.WriteString(fmt.Sprintf("Timestamp: %s\n\n", timestamp))
+	body.WriteString(fmt.Sprintf("Timestamp: %s\n", timestamp))
+	body.WriteString(fmt.Sprintf("BRun version: %s\n", Version()))
+	if e.count > 0 {
+		body.WriteString(fmt.Sprintf("Count: %d\n", e.count))
+	}
+	body.WriteString("\n")
 
-	if e.includeOutput && e.output != "" {
-		body.WriteString("Output:\n")
-		body.WriteString("-------\n")
+	fullOutput := e.selectOutput()
+	if e.includeOutput && fullOutput != "" {
+		body.WriteString(emailOutputHeader)
 
 		// Apply line limiting if configured
-		output := e.output
+		output := fullOutput
 		if e.limitLines > 0 {
 			lines := strings.Split(output, "\n")
 			if len(lines) > e.limitLines {
+				// Link to the full output instead of inlining it if configured
+				if e.outputURL != "" {
+					url, err := storeOutput(e.outputDir, e.outputURL, unitName, fullOutput)
+					if err == nil {
+						body.WriteString(fmt.Sprintf("Full output (%d lines): %s\n", len(lines), url))
+						return body.String()
+					}
+					log.Printf("Email unit '%s': failed to store output, including tail instead: %v", e.name, err)
+				}
+
 				// Keep last N lines
 				lines = lines[len(lines)-e.limitLines:]
 				output = strings.Join(lines, "\n")
-				body.WriteString(fmt.Sprintf("(Showing last %d lines of %d total)\n", e.limitLines, len(strings.Split(e.output, "\n"))))
+				body.WriteString(fmt.Sprintf("(Showing last %d lines of %d total)\n", e.limitLines, len(strings.Split(fullOutput, "\n"))))
 			}
 		}
 
@@ -145,17 +384,11 @@ func (e *EmailUnit) Run(ctx context.Context) error {
 		body.WriteString("(No output captured)\n")
 	}
 
-	// Send email
-	if err := e.sendEmail(subject, body.String()); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	log.Printf("Email unit '%s' completed, sent to %s", e.name, strings.Join(e.to, ", "))
-	return nil
+	return body.String()
 }
 
 // sendEmail sends an email using SMTP
-func (e *EmailUnit) sendEmail(subject, body string) error {
+func (e *EmailUnit) sendEmail(ctx context.Context, subject, body string) error {
 	// Build the email message
 	message := e.buildMessage(subject, body)
 
@@ -165,16 +398,26 @@ func (e *EmailUnit) sendEmail(subject, body string) error {
 	// Prepare authentication if credentials provided
 	var auth smtp.Auth
 	if e.smtpUser != "" && e.smtpPassword != "" {
-		auth = smtp.PlainAuth("", e.smtpUser, e.smtpPassword, e.smtpHost)
+		switch e.smtpAuth {
+		case "login":
+			auth = &loginAuth{username: e.smtpUser, password: e.smtpPassword, host: e.smtpHost}
+		case "cram-md5":
+			auth = smtp.CRAMMD5Auth(e.smtpUser, e.smtpPassword)
+		default:
+			auth = smtp.PlainAuth("", e.smtpUser, e.smtpPassword, e.smtpHost)
+		}
 	}
 
 	// Send with or without TLS
+	var tlsConfig *tls.Config
 	if e.smtpUseTLS {
-		return e.sendEmailTLS(addr, auth, message)
+		tlsConfig = &tls.Config{
+			ServerName:         e.smtpHost,
+			InsecureSkipVerify: false,
+		}
 	}
 
-	// Send without TLS (plain SMTP)
-	return smtp.SendMail(addr, auth, e.from, e.to, []byte(message))
+	return e.sender.Send(ctx, addr, auth, tlsConfig, e.from, e.to, []byte(message))
 }
 
 // buildMessage constructs the RFC 5322 email message
@@ -184,32 +427,160 @@ func (e *EmailUnit) buildMessage(subject, body string) string {
 	msg.WriteString(fmt.Sprintf("From: %s\r\n", e.from))
 	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(e.to, ", ")))
 	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
+	if e.replyTo != "" {
+		msg.WriteString(fmt.Sprintf("Reply-To: %s\r\n", e.replyTo))
+	}
 	msg.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
 	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+
+	var parts *multipart.Writer
+	if e.html {
+		parts = multipart.NewWriter(&msg)
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", parts.Boundary()))
+	} else {
+		msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	}
+
+	// Sort custom headers for consistent output
+	names := make([]string, 0, len(e.headers))
+	for name := range e.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		msg.WriteString(fmt.Sprintf("%s: %s\r\n", name, e.headers[name]))
+	}
+
 	msg.WriteString("\r\n")
-	msg.WriteString(body)
+	if parts == nil {
+		msg.WriteString(body)
+		return msg.String()
+	}
+
+	// Clients show the last part they can render, so the HTML part goes last
+	writeQuotedPrintablePart(parts, "text/plain; charset=UTF-8", body)
+	writeQuotedPrintablePart(parts, "text/html; charset=UTF-8", bodyToHTML(body, e.collapseOutput))
+	parts.Close()
 
 	return msg.String()
 }
 
-// sendEmailTLS sends email with TLS encryption
-func (e *EmailUnit) sendEmailTLS(addr string, auth smtp.Auth, message string) error {
+// writeQuotedPrintablePart adds a quoted-printable encoded part to parts, which
+// keeps long output lines within the SMTP line length limit
+func writeQuotedPrintablePart(parts *multipart.Writer, contentType, content string) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	// Writes go to a strings.Builder, which can't fail
+	part, _ := parts.CreatePart(header)
+	qp := quotedprintable.NewWriter(part)
+	qp.Write([]byte(content))
+	qp.Close()
+}
+
+// urlRegex matches links in the message body, e.g. to stored output
+var urlRegex = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// emailOutputHeader separates the summary from the output in the email body
+const emailOutputHeader = "Output:\n-------\n"
+
+// bodyToHTML renders the plain text body as HTML. The body is kept
+// preformatted so output lines up, with links made clickable. If
+// collapseOutput is set, the output goes in a <details> section that is
+// closed by default, leaving the summary visible.
+func bodyToHTML(body string, collapseOutput bool) string {
+	summary, output, found := strings.Cut(body, emailOutputHeader)
+	if !collapseOutput || !found {
+		return "<!DOCTYPE html>\n<html>\n<body>\n" + preformattedHTML(body) + "\n</body>\n</html>\n"
+	}
+
+	return "<!DOCTYPE html>\n<html>\n<body>\n" + preformattedHTML(summary) +
+		"\n<details>\n<summary>Output</summary>\n" + preformattedHTML(output) +
+		"\n</details>\n</body>\n</html>\n"
+}
+
+// preformattedHTML escapes text and wraps it in a <pre> block, making links
+// clickable
+func preformattedHTML(text string) string {
+	escaped := html.EscapeString(text)
+	linked := urlRegex.ReplaceAllString(escaped, `<a href="$0">$0</a>`)
+	return "<pre style=\"font-family: monospace; white-space: pre-wrap;\">" + linked + "</pre>"
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which net/smtp
+// doesn't provide
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+// Start begins LOGIN authentication. Like smtp.PlainAuth, it refuses to send
+// credentials over an unencrypted connection except to localhost.
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	isLocalhost := server.Name == "localhost" || server.Name == "127.0.0.1" || server.Name == "::1"
+	if !server.TLS && !isLocalhost {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+// Next answers the server's username and password prompts
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN prompt: %s", fromServer)
+	}
+}
+
+// netSMTPSender sends email using net/smtp
+type netSMTPSender struct{}
+
+// Send sends the message, upgrading the connection with STARTTLS if
+// tlsConfig is set. The connection is closed if ctx is done first.
+func (netSMTPSender) Send(ctx context.Context, addr string, auth smtp.Auth, tlsConfig *tls.Config, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address: %w", err)
+	}
+
 	// Connect to the SMTP server
-	client, err := smtp.Dial(addr)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return fmt.Errorf("failed to connect to SMTP server: %w", err)
 	}
-	defer client.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
 
-	// Start TLS
-	tlsConfig := &tls.Config{
-		ServerName:         e.smtpHost,
-		InsecureSkipVerify: false,
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
 	}
+	defer client.Close()
 
-	if err = client.StartTLS(tlsConfig); err != nil {
-		return fmt.Errorf("failed to start TLS: %w", err)
+	if tlsConfig != nil {
+		// Start TLS
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	} else if ok, _ := client.Extension("STARTTLS"); ok {
+		// Use TLS opportunistically like smtp.SendMail
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
 	}
 
 	// Authenticate if credentials provided
@@ -220,12 +591,12 @@ func (e *EmailUnit) sendEmailTLS(addr string, auth smtp.Auth, message string) er
 	}
 
 	// Set sender
-	if err = client.Mail(e.from); err != nil {
+	if err = client.Mail(from); err != nil {
 		return fmt.Errorf("failed to set sender: %w", err)
 	}
 
 	// Set recipients
-	for _, recipient := range e.to {
+	for _, recipient := range to {
 		if err = client.Rcpt(recipient); err != nil {
 			return fmt.Errorf("failed to set recipient %s: %w", recipient, err)
 		}
@@ -237,7 +608,7 @@ func (e *EmailUnit) sendEmailTLS(addr string, auth smtp.Auth, message string) er
 		return fmt.Errorf("failed to get data writer: %w", err)
 	}
 
-	_, err = w.Write([]byte(message))
+	_, err = w.Write(msg)
 	if err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
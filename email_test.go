@@ -1,10 +1,20 @@
 package brun
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEmailUnit_Basic(t *testing.T) {
@@ -148,6 +158,470 @@ func TestEmailUnit_BuildMessage(t *testing.T) {
 	}
 }
 
+func TestEmailUnit_BuildMessage_Headers(t *testing.T) {
+	unit := NewEmailUnit("test-email", []string{"a@example.com"}, "brun@example.com", "",
+		"smtp.example.com", 587, "", "", true, true, 0, nil, nil, nil)
+	unit.SetHeaders("tickets@example.com", map[string]string{
+		"X-Priority":    "1",
+		"X-Ticket-Team": "firmware",
+	})
+
+	msg := unit.buildMessage("build:fail", "body")
+
+	for _, want := range []string{
+		"Reply-To: tickets@example.com\r\n",
+		"X-Priority: 1\r\nX-Ticket-Team: firmware\r\n\r\nbody",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestEmailUnit_BuildMessage_HTML(t *testing.T) {
+	unit := NewEmailUnit("test-email", []string{"a@example.com"}, "brun@example.com", "",
+		"smtp.example.com", 587, "", "", true, true, 0, nil, nil, nil)
+	unit.SetHTML(true)
+
+	body := "Output:\n<error> " + strings.Repeat("x", 200) + "\nFull output: https://logs.example.com/build.log\n"
+	msg, err := mail.ReadMessage(strings.NewReader(unit.buildMessage("build:fail", body)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+
+	// multipart.Reader decodes quoted-printable parts
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types, contents []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		types = append(types, part.Header.Get("Content-Type"))
+		contents = append(contents, string(content))
+	}
+
+	if len(types) != 2 || types[0] != "text/plain; charset=UTF-8" || types[1] != "text/html; charset=UTF-8" {
+		t.Fatalf("Expected text and HTML parts, got %v", types)
+	}
+	if strings.ReplaceAll(contents[0], "\r\n", "\n") != body {
+		t.Errorf("Text part = %q, want %q", contents[0], body)
+	}
+	for _, want := range []string{
+		"&lt;error&gt;",
+		`<a href="https://logs.example.com/build.log">https://logs.example.com/build.log</a>`,
+	} {
+		if !strings.Contains(contents[1], want) {
+			t.Errorf("HTML part missing %q:\n%s", want, contents[1])
+		}
+	}
+}
+
+func TestBodyToHTML_CollapseOutput(t *testing.T) {
+	body := "Triggered by unit: build\nTimestamp: now\n\n" + emailOutputHeader + "<line 1>\nline 2\n"
+
+	html := bodyToHTML(body, true)
+	summary, details, found := strings.Cut(html, "<details>")
+	if !found {
+		t.Fatalf("Expected a <details> section:\n%s", html)
+	}
+	if !strings.Contains(summary, "Triggered by unit: build") || strings.Contains(summary, "line 2") {
+		t.Errorf("Expected only the summary before <details>:\n%s", summary)
+	}
+	if !strings.Contains(details, "<summary>Output</summary>") || !strings.Contains(details, "&lt;line 1&gt;\nline 2") {
+		t.Errorf("Expected the output in <details>:\n%s", details)
+	}
+
+	// Without output there is nothing to collapse
+	if html := bodyToHTML("Triggered by unit: build\n(No output captured)\n", true); strings.Contains(html, "<details>") {
+		t.Errorf("Expected no <details> without output:\n%s", html)
+	}
+	if html := bodyToHTML(body, false); strings.Contains(html, "<details>") {
+		t.Errorf("Expected no <details> when collapse_output is off:\n%s", html)
+	}
+}
+
+func TestValidateEmailHeaders(t *testing.T) {
+	tests := []struct {
+		headers map[string]string
+		wantErr bool
+	}{
+		{map[string]string{"X-Priority": "1"}, false},
+		{nil, false},
+		{map[string]string{"subject": "override"}, true},
+		{map[string]string{"Content-Type": "text/html"}, true},
+		{map[string]string{"X-Bad Name": "1"}, true},
+		{map[string]string{"X-Inject": "1\r\nBcc: attacker@example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateEmailHeaders(tt.headers)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateEmailHeaders(%v) error = %v, wantErr %v", tt.headers, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEmailUnit_BuildBody_OutputLink(t *testing.T) {
+	outputDir := t.TempDir()
+
+	unit := NewEmailUnit("test-email", []string{"user@example.com"}, "brun@example.com", "",
+		"smtp.example.com", 587, "", "", true, true, 2, nil, nil, nil)
+	unit.SetOutputLink(outputDir, "https://logs.example.com/brun/{{.Filename}}")
+
+	body := unit.buildBody("build:on_start", "2025-01-01T00:00:00Z")
+
+	if !strings.Contains(body, "(No output captured)") {
+		t.Errorf("Expected no output notice, got: %s", body)
+	}
+
+	unit.SetOutput("Line 1\nLine 2\nLine 3")
+	body = unit.buildBody("build:on_start", "2025-01-01T00:00:00Z")
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("Failed to read output dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 stored output file, got %d", len(entries))
+	}
+
+	// Characters unsafe in filenames are replaced
+	filename := entries[0].Name()
+	if !strings.HasPrefix(filename, "build_on_start-") {
+		t.Errorf("Unexpected output filename '%s'", filename)
+	}
+	if !strings.Contains(body, "Full output (3 lines): https://logs.example.com/brun/"+filename) {
+		t.Errorf("Body missing output URL: %s", body)
+	}
+	if strings.Contains(body, "Line 3") {
+		t.Error("Body should not include output when linking")
+	}
+}
+
+func TestCreateUnits_EmailOutputURLRequiresDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - email:
+      name: notify
+      to:
+        - user@example.com
+      from: brun@example.com
+      smtp_host: smtp.example.com
+      output_url_template: https://logs.example.com/{{.Filename}}
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	_, err = config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "output_dir is required") {
+		t.Errorf("Expected output_dir error, got %v", err)
+	}
+}
+
+// mockSMTPSender records sends instead of talking to an SMTP server
+type mockSMTPSender struct {
+	sends []mockSMTPSend
+	err   error
+}
+
+type mockSMTPSend struct {
+	addr      string
+	auth      smtp.Auth
+	tlsConfig *tls.Config
+	from      string
+	to        []string
+	msg       string
+}
+
+func (m *mockSMTPSender) Send(ctx context.Context, addr string, auth smtp.Auth, tlsConfig *tls.Config, from string, to []string, msg []byte) error {
+	m.sends = append(m.sends, mockSMTPSend{addr, auth, tlsConfig, from, to, string(msg)})
+	return m.err
+}
+
+func TestEmailUnit_Run_Send(t *testing.T) {
+	unit := NewEmailUnit("test-email", []string{"a@example.com", "b@example.com"}, "brun@example.com", "[CI]",
+		"smtp.example.com", 587, "user", "secret", true, true, 0, nil, nil, nil)
+	sender := &mockSMTPSender{}
+	unit.sender = sender
+
+	unit.SetTriggeringUnit("build")
+	unit.SetTriggerError(errors.New("exit status 1"))
+	unit.SetOutput("build failed")
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(sender.sends) != 1 {
+		t.Fatalf("Expected 1 send, got %d", len(sender.sends))
+	}
+	send := sender.sends[0]
+
+	if send.addr != "smtp.example.com:587" {
+		t.Errorf("Expected addr 'smtp.example.com:587', got '%s'", send.addr)
+	}
+	if send.from != "brun@example.com" {
+		t.Errorf("Expected from 'brun@example.com', got '%s'", send.from)
+	}
+	if len(send.to) != 2 || send.to[0] != "a@example.com" || send.to[1] != "b@example.com" {
+		t.Errorf("Unexpected recipients: %v", send.to)
+	}
+	if send.auth == nil {
+		t.Error("Expected auth when credentials are configured")
+	}
+	if send.tlsConfig == nil || send.tlsConfig.ServerName != "smtp.example.com" {
+		t.Errorf("Expected TLS config for smtp.example.com, got %+v", send.tlsConfig)
+	}
+	if !strings.Contains(send.msg, "Subject: [CI]: build:fail\r\n") {
+		t.Errorf("Message missing subject: %s", send.msg)
+	}
+	if !strings.Contains(send.msg, "build failed") {
+		t.Errorf("Message missing output: %s", send.msg)
+	}
+}
+
+func TestEmailUnit_Run_PlainNoAuth(t *testing.T) {
+	unit := NewEmailUnit("test-email", []string{"a@example.com"}, "brun@example.com", "",
+		"localhost", 25, "", "", false, true, 0, nil, nil, nil)
+	sender := &mockSMTPSender{}
+	unit.sender = sender
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(sender.sends) != 1 {
+		t.Fatalf("Expected 1 send, got %d", len(sender.sends))
+	}
+	if sender.sends[0].tlsConfig != nil {
+		t.Error("Expected no TLS config when smtp_use_tls is false")
+	}
+	if sender.sends[0].auth != nil {
+		t.Error("Expected no auth without credentials")
+	}
+}
+
+func TestEmailUnit_AuthMechanism(t *testing.T) {
+	tests := []struct {
+		mechanism string
+		want      string
+	}{
+		{"", "PLAIN"},
+		{"plain", "PLAIN"},
+		{"login", "LOGIN"},
+		{"cram-md5", "CRAM-MD5"},
+	}
+
+	for _, tt := range tests {
+		unit := NewEmailUnit("test-email", []string{"a@example.com"}, "brun@example.com", "",
+			"smtp.example.com", 587, "user", "secret", true, true, 0, nil, nil, nil)
+		unit.SetAuthMechanism(tt.mechanism)
+		sender := &mockSMTPSender{}
+		unit.sender = sender
+
+		if err := unit.Run(context.Background()); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		proto, _, err := sender.sends[0].auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true})
+		if err != nil {
+			t.Fatalf("%q: auth Start failed: %v", tt.mechanism, err)
+		}
+		if proto != tt.want {
+			t.Errorf("%q: auth mechanism = %s, want %s", tt.mechanism, proto, tt.want)
+		}
+	}
+}
+
+func TestLoginAuth(t *testing.T) {
+	auth := &loginAuth{username: "user", password: "secret", host: "smtp.example.com"}
+
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com"}); err == nil {
+		t.Error("Expected error on unencrypted connection")
+	}
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "other.example.com", TLS: true}); err == nil {
+		t.Error("Expected error on wrong host name")
+	}
+
+	for prompt, want := range map[string]string{"Username:": "user", "Password:": "secret"} {
+		resp, err := auth.Next([]byte(prompt), true)
+		if err != nil || string(resp) != want {
+			t.Errorf("Next(%q) = %q, %v, want %q", prompt, resp, err, want)
+		}
+	}
+
+	if _, err := auth.Next([]byte("Token:"), true); err == nil {
+		t.Error("Expected error on unexpected prompt")
+	}
+	if resp, err := auth.Next(nil, false); resp != nil || err != nil {
+		t.Errorf("Next(done) = %q, %v, want nil, nil", resp, err)
+	}
+}
+
+func TestEmailUnit_Run_SendError(t *testing.T) {
+	unit := NewEmailUnit("test-email", []string{"a@example.com"}, "brun@example.com", "",
+		"smtp.example.com", 587, "", "", true, true, 0, nil, nil, nil)
+	sendErr := errors.New("connection refused")
+	unit.sender = &mockSMTPSender{err: sendErr}
+
+	err := unit.Run(context.Background())
+	if !errors.Is(err, sendErr) {
+		t.Errorf("Expected send error, got %v", err)
+	}
+}
+
+func TestEmailUnit_Run_Outbox(t *testing.T) {
+	unit := NewEmailUnit("test-email", []string{"a@example.com"}, "brun@example.com", "",
+		"smtp.example.com", 587, "", "", true, true, 0, nil, nil, nil)
+	sender := &mockSMTPSender{err: errors.New("connection refused")}
+	unit.sender = sender
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	unit.SetOutbox(NewOutbox(time.Hour, state))
+	unit.SetTriggeringUnit("build")
+
+	// The failed notification is kept for retry
+	if err := unit.Run(context.Background()); err == nil {
+		t.Fatal("Expected send error")
+	}
+	if err := unit.FlushQueued(context.Background()); err == nil {
+		t.Error("Expected retry to fail while the server is down")
+	}
+	queued, _ := state.Get("test-email", "outbox")
+	if list, _ := queued.([]any); len(list) != 1 {
+		t.Fatalf("Expected 1 notification in outbox, got %v", queued)
+	}
+
+	// Once the server is back, it is delivered and removed
+	sender.err = nil
+	sender.sends = nil
+	if err := unit.FlushQueued(context.Background()); err != nil {
+		t.Fatalf("FlushQueued failed: %v", err)
+	}
+	if len(sender.sends) != 1 || !strings.Contains(sender.sends[0].msg, "Subject: build:success") {
+		t.Errorf("Expected queued notification to be sent, got %v", sender.sends)
+	}
+	if _, ok := state.Get("test-email", "outbox"); ok {
+		t.Error("Expected outbox to be cleared")
+	}
+}
+
+func TestNetSMTPSender_Timeout(t *testing.T) {
+	// Server accepts connections but never sends a greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = netSMTPSender{}.Send(ctx, listener.Addr().String(), nil, nil, "brun@example.com",
+		[]string{"a@example.com"}, []byte("test"))
+	if err == nil {
+		t.Fatal("Expected error from hung SMTP server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send took %v, timeout not applied", elapsed)
+	}
+}
+
+func TestCreateUnits_EmailInvalidAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - email:
+      name: notify
+      to:
+        - user@example.com
+      from: brun@example.com
+      smtp_host: smtp.example.com
+      smtp_auth: ntlm
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	_, err = config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "invalid smtp_auth") {
+		t.Errorf("Expected smtp_auth error, got %v", err)
+	}
+}
+
+func TestCreateUnits_InvalidNotificationTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - email:
+      name: notify
+      to:
+        - user@example.com
+      from: brun@example.com
+      smtp_host: smtp.example.com
+      timeout: soon
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	_, err = config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "invalid timeout format") {
+		t.Errorf("Expected timeout format error, got %v", err)
+	}
+}
+
 func TestLoadConfig_WithEmailUnit(t *testing.T) {
 	tempDir := t.TempDir()
 	configFile := filepath.Join(tempDir, "config.yaml")
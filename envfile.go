@@ -0,0 +1,125 @@
+package brun
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envNameRegex matches valid environment variable names
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// loadEnvFile reads KEY=VALUE pairs from a dotenv file
+func loadEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	vars, err := parseEnvFile(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("env file %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// parseEnvFile parses dotenv content. Blank lines and lines starting with #
+// are skipped, and a leading "export " is ignored. Values may be:
+//   - unquoted, trimmed and ending at " #", which starts a comment
+//   - single quoted, taken literally
+//   - double quoted, with \n, \t, \", and \\ escapes
+//
+// Quoted values may span lines. Variables in values aren't expanded.
+func parseEnvFile(content string) (map[string]string, error) {
+	vars := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+		name = strings.TrimSpace(name)
+		if !envNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("line %d: invalid variable name '%s'", lineNum, name)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = value[:idx]
+			}
+			vars[name] = strings.TrimSpace(value)
+			continue
+		}
+
+		// Join lines until the closing quote
+		quote := value[0]
+		rest := value[1:]
+		for {
+			end := closingQuote(rest, quote)
+			if end >= 0 {
+				value = rest[:end]
+				break
+			}
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNum)
+			}
+			rest += "\n" + lines[i]
+		}
+
+		if quote == '"' {
+			value = unescapeEnvValue(value)
+		}
+		vars[name] = value
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns the index of the quote ending a value in s, skipping
+// escaped quotes in double quoted values, or -1 if there is none
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeEnvValue replaces the escapes allowed in double quoted values
+func unescapeEnvValue(value string) string {
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case '"', '\\':
+			out.WriteByte(value[i])
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,59 @@
+package brun
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	content := `# comment
+PLAIN=value
+export EXPORTED=yes
+SPACED = padded  
+COMMENTED=value # trailing comment
+HASH=a#b
+EMPTY=
+SINGLE='literal $HOME \n # kept'
+DOUBLE="line1\nline2 \"quoted\" \\ # kept"
+MULTI="first
+second"
+`
+	vars, err := parseEnvFile(content)
+	if err != nil {
+		t.Fatalf("parseEnvFile failed: %v", err)
+	}
+
+	want := map[string]string{
+		"PLAIN":     "value",
+		"EXPORTED":  "yes",
+		"SPACED":    "padded",
+		"COMMENTED": "value",
+		"HASH":      "a#b",
+		"EMPTY":     "",
+		"SINGLE":    `literal $HOME \n # kept`,
+		"DOUBLE":    "line1\nline2 \"quoted\" \\ # kept",
+		"MULTI":     "first\nsecond",
+	}
+	if !maps.Equal(vars, want) {
+		t.Errorf("parseEnvFile() = %q, want %q", vars, want)
+	}
+}
+
+func TestParseEnvFile_Errors(t *testing.T) {
+	tests := []struct {
+		content string
+		wantErr string
+	}{
+		{"VALID=1\nNOEQUALS\n", "line 2: expected KEY=VALUE"},
+		{"1BAD=x\n", "invalid variable name '1BAD'"},
+		{"OPEN=\"never closed\nNEXT=1\n", "line 1: unterminated quoted value"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseEnvFile(tt.content)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parseEnvFile(%q) error = %v, want %q", tt.content, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// EscalationConfig represents the configuration for an Escalation unit
+type EscalationConfig struct {
+	UnitConfig `yaml:",inline"`
+	Unit       string  `yaml:"unit"`
+	Attempts   int     `yaml:"attempts,omitempty"`
+	Delay      string  `yaml:"delay,omitempty"`
+	Backoff    float64 `yaml:"backoff,omitempty"`
+}
+
+// defaultEscalationAttempts is the number of attempts if none is configured
+const defaultEscalationAttempts = 3
+
+// EscalationUnit runs another unit, retrying it with a delay when it fails.
+// The escalation fails, firing its on_failure units, only when every attempt
+// has failed.
+type EscalationUnit struct {
+	name      string
+	unit      string
+	attempts  int
+	delay     time.Duration
+	backoff   float64
+	runUnit   func(ctx context.Context, name string) error
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// NewEscalationUnit creates a new Escalation unit that runs unit up to
+// attempts times (default 3), waiting delay after the first failure. Each
+// following delay is multiplied by backoff (default 1).
+func NewEscalationUnit(name, unit string, attempts int, delay time.Duration, backoff float64, onSuccess, onFailure, always []string) *EscalationUnit {
+	if attempts <= 0 {
+		attempts = defaultEscalationAttempts
+	}
+	if backoff <= 0 {
+		backoff = 1
+	}
+	return &EscalationUnit{
+		name:      name,
+		unit:      unit,
+		attempts:  attempts,
+		delay:     delay,
+		backoff:   backoff,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}
+}
+
+// SetUnitRunner sets the function used to run the escalated unit. The
+// orchestrator sets it so the unit's output is captured like any other unit's.
+func (e *EscalationUnit) SetUnitRunner(run func(ctx context.Context, name string) error) {
+	e.runUnit = run
+}
+
+// Name returns the unit name
+func (e *EscalationUnit) Name() string {
+	return e.name
+}
+
+// Type returns the unit type
+func (e *EscalationUnit) Type() string {
+	return "escalation"
+}
+
+// Run runs the escalated unit until it succeeds or all attempts have failed
+func (e *EscalationUnit) Run(ctx context.Context) error {
+	log.Printf("Running escalation unit '%s'", e.name)
+
+	if e.runUnit == nil {
+		return fmt.Errorf("no unit runner set")
+	}
+
+	delay := e.delay
+	var err error
+	for attempt := 1; attempt <= e.attempts; attempt++ {
+		if err = e.runUnit(ctx, e.unit); err == nil {
+			log.Printf("Escalation unit '%s': unit '%s' succeeded on attempt %d of %d", e.name, e.unit, attempt, e.attempts)
+			return nil
+		}
+		log.Printf("Escalation unit '%s': unit '%s' failed on attempt %d of %d: %v", e.name, e.unit, attempt, e.attempts, err)
+
+		if attempt == e.attempts {
+			break
+		}
+
+		log.Printf("Escalation unit '%s': retrying in %s", e.name, delay)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("escalation cancelled: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = time.Duration(float64(delay) * e.backoff)
+	}
+
+	return fmt.Errorf("unit '%s' failed after %d attempts: %w", e.unit, e.attempts, err)
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (e *EscalationUnit) OnSuccess() []string {
+	return e.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (e *EscalationUnit) OnFailure() []string {
+	return e.onFailure
+}
+
+// Always returns the list of units to always trigger
+func (e *EscalationUnit) Always() []string {
+	return e.always
+}
@@ -0,0 +1,129 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscalationUnit_Run(t *testing.T) {
+	var runs int
+	unit := NewEscalationUnit("retry", "build", 3, 10*time.Millisecond, 2, nil, nil, nil)
+	unit.SetUnitRunner(func(ctx context.Context, name string) error {
+		runs++
+		if name != "build" {
+			t.Errorf("Expected to run build, got %s", name)
+		}
+		if runs < 2 {
+			return os.ErrNotExist
+		}
+		return nil
+	})
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("Expected 2 attempts, got %d", runs)
+	}
+
+	// All attempts fail
+	runs = 0
+	unit.SetUnitRunner(func(ctx context.Context, name string) error {
+		runs++
+		return os.ErrNotExist
+	})
+	err := unit.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed after 3 attempts") {
+		t.Errorf("Expected failure after 3 attempts, got %v", err)
+	}
+	if runs != 3 {
+		t.Errorf("Expected 3 attempts, got %d", runs)
+	}
+}
+
+func TestEscalationUnit_Cancelled(t *testing.T) {
+	unit := NewEscalationUnit("retry", "build", 3, time.Hour, 1, nil, nil, nil)
+	unit.SetUnitRunner(func(ctx context.Context, name string) error {
+		return os.ErrNotExist
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := unit.Run(ctx); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected cancelled error, got %v", err)
+	}
+}
+
+// TestEscalation_Flow runs a full escalation: a failing build is retried with
+// a delay, and once all attempts fail a failure counter is incremented and
+// someone is paged
+func TestEscalation_Flow(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+	attemptsFile := filepath.Join(tempDir, "attempts")
+	pagedFile := filepath.Join(tempDir, "paged")
+
+	configContent := `config:
+  state_location: state.yaml
+
+units:
+  - escalation:
+      name: build-retry
+      unit: build
+      attempts: 3
+      delay: 10ms
+      on_failure: [count-failures, page]
+  - run:
+      name: build
+      script: echo attempt >> ` + attemptsFile + ` && exit 1
+      on_failure: [page]
+  - count:
+      name: count-failures
+  - run:
+      name: page
+      script: echo paged >> ` + pagedFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	orchestrator := NewOrchestrator(units)
+	err = orchestrator.RunSingleUnit(context.Background(), "build-retry", true)
+	if err == nil || !strings.Contains(err.Error(), "failed after 3 attempts") {
+		t.Fatalf("Expected escalation to fail after 3 attempts, got %v", err)
+	}
+
+	data, err := os.ReadFile(attemptsFile)
+	if err != nil {
+		t.Fatalf("Failed to read attempts: %v", err)
+	}
+	if attempts := strings.Count(string(data), "attempt"); attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	// The build's own on_failure doesn't fire for each attempt; only the
+	// escalation pages, once
+	data, err = os.ReadFile(pagedFile)
+	if err != nil {
+		t.Fatalf("Expected page after all attempts failed: %v", err)
+	}
+	if pages := strings.Count(string(data), "paged"); pages != 1 {
+		t.Errorf("Expected 1 page, got %d", pages)
+	}
+	if count, _ := config.State().Get("count-failures", "build-retry"); count != 1 {
+		t.Errorf("Expected failure count 1, got %v", count)
+	}
+}
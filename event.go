@@ -0,0 +1,41 @@
+package brun
+
+import "time"
+
+// EventPhase is the point in a unit's execution an Event reports
+type EventPhase string
+
+// Unit execution phases
+const (
+	EventStarted   EventPhase = "started"
+	EventCompleted EventPhase = "completed"
+)
+
+// Event reports a unit starting or completing. Result and Duration are only
+// set when the unit completed.
+type Event struct {
+	Unit     string
+	Phase    EventPhase
+	Result   *UnitResult
+	Duration time.Duration
+}
+
+// OnEvent registers a function called when each unit starts and completes.
+// Functions are called synchronously in the order registered, so they should
+// return quickly.
+func (o *Orchestrator) OnEvent(fn func(Event)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.eventHandlers = append(o.eventHandlers, fn)
+}
+
+// emit calls the registered event handlers
+func (o *Orchestrator) emit(event Event) {
+	o.mu.RLock()
+	handlers := o.eventHandlers
+	o.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(event)
+	}
+}
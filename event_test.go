@@ -0,0 +1,52 @@
+package brun
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOrchestrator_OnEvent(t *testing.T) {
+	units := []Unit{
+		NewStartTrigger("start", []string{"build"}, nil, nil),
+		NewRunUnit("build", "exit 1", "", 0, "", false, nil, nil, nil),
+	}
+
+	orchestrator := NewOrchestrator(units)
+	var events []Event
+	orchestrator.OnEvent(func(e Event) {
+		events = append(events, e)
+	})
+
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	// build is triggered by start, so it starts and completes first
+	expected := []struct {
+		unit  string
+		phase EventPhase
+	}{
+		{"start", EventStarted},
+		{"start", EventCompleted},
+		{"build", EventStarted},
+		{"build", EventCompleted},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, want := range expected {
+		if events[i].Unit != want.unit || events[i].Phase != want.phase {
+			t.Errorf("Event %d = %s %s, want %s %s", i, events[i].Unit, events[i].Phase, want.unit, want.phase)
+		}
+	}
+
+	if events[0].Result != nil {
+		t.Error("Expected no result for started event")
+	}
+	if result := events[3].Result; result == nil || result.Error == nil {
+		t.Errorf("Expected failed result for build, got %+v", result)
+	}
+	if events[3].Duration <= 0 {
+		t.Error("Expected a duration for the completed event")
+	}
+}
@@ -7,40 +7,76 @@ import (
 	"io"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
 )
 
-// FileTrigger is a trigger unit that fires when files matching a pattern change
+// FileTrigger is a trigger unit that fires when files matching any of its
+// patterns change
 type FileTrigger struct {
-	name      string
-	pattern   string
-	state     *State
-	onSuccess []string
-	onFailure []string
-	always    []string
+	name     string
+	patterns []string
+	state    *State
+	// initialTrigger fires on the first check, when there is no prior state
+	initialTrigger bool
+	// followSymlinks globs with globFollowingSymlinks, which skips symlink
+	// loops
+	followSymlinks bool
+	onSuccess      []string
+	onFailure      []string
+	always         []string
 }
 
 // FileConfig represents the configuration for a file trigger
 type FileConfig struct {
 	UnitConfig `yaml:",inline"`
-	Pattern    string `yaml:"pattern"`
+	// Pattern is a single glob pattern or a list of patterns
+	Pattern StringList `yaml:"pattern"`
+	// InitialTrigger set to false records the first file state seen without
+	// firing (default true)
+	InitialTrigger *bool `yaml:"initial_trigger,omitempty"`
+	// FollowSymlinks descends into symlinked directories when matching the
+	// patterns (default false)
+	FollowSymlinks bool `yaml:"follow_symlinks,omitempty"`
 }
 
 // NewFileTrigger creates a new file trigger unit
 func NewFileTrigger(name, pattern string, state *State, onSuccess, onFailure, always []string) *FileTrigger {
 	return &FileTrigger{
-		name:      name,
-		pattern:   pattern,
-		state:     state,
-		onSuccess: onSuccess,
-		onFailure: onFailure,
-		always:    always,
+		name:           name,
+		patterns:       []string{pattern},
+		initialTrigger: true,
+		state:          state,
+		onSuccess:      onSuccess,
+		onFailure:      onFailure,
+		always:         always,
 	}
 }
 
+// SetPatterns replaces the trigger's pattern with several patterns. Files
+// matching any of them are watched.
+func (f *FileTrigger) SetPatterns(patterns []string) {
+	f.patterns = patterns
+}
+
+// SetInitialTrigger sets whether the trigger fires on its first check, when
+// there is no recorded file state. If false, the first check only records it.
+func (f *FileTrigger) SetInitialTrigger(initialTrigger bool) {
+	f.initialTrigger = initialTrigger
+}
+
+// SetFollowSymlinks sets whether patterns are matched with a walker that
+// follows symlinked directories but skips links back to a parent directory.
+// Otherwise doublestar also follows symlinked directories, loops included.
+func (f *FileTrigger) SetFollowSymlinks(followSymlinks bool) {
+	f.followSymlinks = followSymlinks
+}
+
 // Name returns the name of the unit
 func (f *FileTrigger) Name() string {
 	return f.name
@@ -67,17 +103,33 @@ func (f *FileTrigger) getFileHash(path string) (string, error) {
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
-// getFilesState returns a map of file paths to their hashes
+// getFilesState returns a map of file paths matching any pattern to their
+// hashes
 func (f *FileTrigger) getFilesState() (map[string]string, error) {
 	// Use doublestar for recursive glob support (supports **)
 	// This works with both relative and absolute patterns
-	matches, err := doublestar.FilepathGlob(f.pattern)
-	if err != nil {
-		return nil, fmt.Errorf("failed to glob pattern '%s': %w", f.pattern, err)
+	var matches []string
+	for _, pattern := range f.patterns {
+		var patternMatches []string
+		var err error
+		if f.followSymlinks {
+			patternMatches, err = globFollowingSymlinks(pattern)
+		} else {
+			patternMatches, err = doublestar.FilepathGlob(pattern)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob pattern '%s': %w", pattern, err)
+		}
+		matches = append(matches, patternMatches...)
 	}
 
+	// Files matching several patterns are only recorded once
 	filesState := make(map[string]string)
 	for _, path := range matches {
+		if _, ok := filesState[path]; ok {
+			continue
+		}
+
 		// Check if it's a regular file
 		info, err := os.Stat(path)
 		if err != nil {
@@ -98,6 +150,59 @@ func (f *FileTrigger) getFilesState() (map[string]string, error) {
 	return filesState, nil
 }
 
+// globFollowingSymlinks returns the paths matching pattern like
+// doublestar.FilepathGlob, descending into symlinked directories. A directory
+// that is one of its own parents, e.g. through a link to "..", isn't entered
+// again, so symlink loops can't recurse forever.
+func globFollowingSymlinks(pattern string) ([]string, error) {
+	base, rest := doublestar.SplitPattern(filepath.ToSlash(filepath.Clean(pattern)))
+	if !doublestar.ValidatePattern(rest) {
+		return nil, doublestar.ErrBadPattern
+	}
+
+	// Without ** matches can't be deeper than the pattern has path elements
+	maxDepth := -1
+	if !strings.Contains(rest, "**") {
+		maxDepth = strings.Count(rest, "/") + 1
+	}
+
+	var matches []string
+	var walk func(dir, rel string, depth int, parents []os.FileInfo)
+	walk = func(dir, rel string, depth int, parents []os.FileInfo) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			entryPath := filepath.Join(dir, entry.Name())
+			entryRel := path.Join(rel, entry.Name())
+			if ok, _ := doublestar.Match(rest, entryRel); ok {
+				matches = append(matches, entryPath)
+			}
+			if depth+1 == maxDepth {
+				continue
+			}
+
+			info, err := os.Stat(entryPath)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			if slices.ContainsFunc(parents, func(parent os.FileInfo) bool { return os.SameFile(parent, info) }) {
+				continue
+			}
+			walk(entryPath, entryRel, depth+1, append(parents, info))
+		}
+	}
+
+	base = filepath.FromSlash(base)
+	info, err := os.Stat(base)
+	if err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	walk(base, "", 0, []os.FileInfo{info})
+	return matches, nil
+}
+
 // filesStateToString converts file state map to a sortable string representation
 func (f *FileTrigger) filesStateToString(filesState map[string]string) string {
 	// Sort keys for consistent output
@@ -135,7 +240,7 @@ func (f *FileTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
 		if err := f.state.SetString(f.name, "files_state", currentStateStr); err != nil {
 			return false, fmt.Errorf("failed to save files state: %w", err)
 		}
-		return true, nil
+		return f.initialTrigger, nil
 	}
 
 	// Check if state has changed
@@ -171,6 +276,6 @@ func (f *FileTrigger) Run(ctx context.Context) error {
 	// Get current files for logging
 	currentState, _ := f.getFilesState()
 	fileCount := len(currentState)
-	log.Printf("File trigger '%s' activated (%d file(s) matching '%s')", f.name, fileCount, f.pattern)
+	log.Printf("File trigger '%s' activated (%d file(s) matching '%s')", f.name, fileCount, strings.Join(f.patterns, "', '"))
 	return nil
 }
@@ -229,6 +229,7 @@ units:
   - file:
       name: watch-files
       pattern: "**/*.go"
+      follow_symlinks: true
       on_success:
         - build
 `
@@ -265,8 +266,14 @@ units:
 		t.Fatal("Unit is not a FileTrigger")
 	}
 
-	if fileTrigger.pattern != "**/*.go" {
-		t.Errorf("Expected pattern '**/*.go', got '%s'", fileTrigger.pattern)
+	// Relative patterns are resolved against the config file's directory
+	wantPattern := filepath.Join(tempDir, "**/*.go")
+	if len(fileTrigger.patterns) != 1 || fileTrigger.patterns[0] != wantPattern {
+		t.Errorf("Expected pattern '%s', got %v", wantPattern, fileTrigger.patterns)
+	}
+
+	if !fileTrigger.followSymlinks {
+		t.Error("Expected follow_symlinks to be set")
 	}
 
 	if len(fileTrigger.onSuccess) != 1 || fileTrigger.onSuccess[0] != "build" {
@@ -370,3 +377,168 @@ func TestFileTrigger_IgnoresDirectories(t *testing.T) {
 		t.Error("Directory should not be in files state")
 	}
 }
+
+func TestFileTrigger_MultiplePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: state.yaml
+
+units:
+  - file:
+      name: watch-sources
+      pattern:
+        - "*.go"
+        - "*.proto"
+        - "main.*"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+	trigger := units[0].(*FileTrigger)
+
+	for _, name := range []string{"main.go", "api.proto", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	files, err := trigger.getFilesState()
+	if err != nil {
+		t.Fatalf("getFilesState failed: %v", err)
+	}
+	// main.go matches two patterns but is only recorded once
+	if len(files) != 2 {
+		t.Errorf("Expected 2 files, got %v", files)
+	}
+
+	ctx := context.Background()
+	if _, err := trigger.Check(ctx, CheckModePolling); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	// Changing a file matching the second pattern fires the trigger
+	if err := os.WriteFile(filepath.Join(tempDir, "api.proto"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to write api.proto: %v", err)
+	}
+	if fired, err := trigger.Check(ctx, CheckModePolling); err != nil || !fired {
+		t.Errorf("Expected trigger to fire on .proto change, fired=%v err=%v", fired, err)
+	}
+
+	// Files matching no pattern are ignored
+	if err := os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to write notes.txt: %v", err)
+	}
+	if fired, err := trigger.Check(ctx, CheckModePolling); err != nil || fired {
+		t.Errorf("Expected trigger not to fire on .txt change, fired=%v err=%v", fired, err)
+	}
+}
+
+func TestFileTrigger_NoInitialTrigger(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	trigger := NewFileTrigger("test-file", filepath.Join(tempDir, "*.txt"), state, nil, nil, nil)
+	trigger.SetInitialTrigger(false)
+
+	ctx := context.Background()
+
+	shouldTrigger, err := trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if shouldTrigger {
+		t.Error("Expected no trigger on first check with initial_trigger false")
+	}
+
+	// The baseline was recorded, so a change is still detected
+	if err := os.WriteFile(testFile, []byte("modified"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	shouldTrigger, err = trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger after file change")
+	}
+}
+
+func TestFileTrigger_FollowSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+
+	// A release layout: watched/current links to a release directory
+	release := filepath.Join(tempDir, "releases", "v1")
+	if err := os.MkdirAll(filepath.Join(release, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create release: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "src", "main.go"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	watched := filepath.Join(tempDir, "watched")
+	if err := os.Mkdir(watched, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(release, filepath.Join(watched, "current")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	pattern := filepath.Join(watched, "**", "*.go")
+	want := filepath.Join(watched, "current", "src", "main.go")
+
+	// By default symlinked directories are searched too
+	trigger := NewFileTrigger("default", pattern, state, nil, nil, nil)
+	files, err := trigger.getFilesState()
+	if err != nil {
+		t.Fatalf("getFilesState failed: %v", err)
+	}
+	if len(files) != 1 || files[want] == "" {
+		t.Errorf("Expected only %s by default, got %v", want, files)
+	}
+
+	// With follow_symlinks, a link back to a parent isn't followed again
+	if err := os.Symlink("..", filepath.Join(release, "src", "loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	trigger = NewFileTrigger("follow", pattern, state, nil, nil, nil)
+	trigger.SetFollowSymlinks(true)
+	files, err = trigger.getFilesState()
+	if err != nil {
+		t.Fatalf("getFilesState failed: %v", err)
+	}
+	if len(files) != 1 || files[want] == "" {
+		t.Errorf("Expected only %s, got %v", want, files)
+	}
+
+	ctx := context.Background()
+	if _, err := trigger.Check(ctx, CheckModePolling); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "src", "main.go"), []byte("v2"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if shouldTrigger, err := trigger.Check(ctx, CheckModePolling); err != nil || !shouldTrigger {
+		t.Errorf("Expected trigger after change behind symlink, got %v, %v", shouldTrigger, err)
+	}
+
+	// Patterns without ** match through symlinks too
+	trigger.SetPatterns([]string{filepath.Join(watched, "*", "src", "*.go")})
+	if files, err := trigger.getFilesState(); err != nil || len(files) != 1 {
+		t.Errorf("Expected 1 file, got %v, %v", files, err)
+	}
+}
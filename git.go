@@ -4,53 +4,156 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 // GitTrigger is a trigger unit that fires when git repository changes are detected
 type GitTrigger struct {
-	name          string
-	repository    string
-	branch        string
-	reset         bool
-	pollInterval  time.Duration
-	debug         bool
-	state         *State
-	lastCheckTime time.Time
-	onSuccess     []string
-	onFailure     []string
-	always        []string
+	name         string
+	repository   string
+	branch       string
+	reset        bool
+	pollInterval time.Duration
+	pollRef      string // state or env reference the poll interval is read from
+	debug        bool
+	state        *State
+	fetchDepth   int
+	fetchRefspec string
+	paths        []string
+	timeout      time.Duration
+	env          map[string]string // commit info from the last Run
+	// nativeGit updates local workspaces with the git binary instead of
+	// go-git (default true)
+	nativeGit bool
+	// submodules updates the workspace's submodules (default true)
+	submodules bool
+	// currentBranch is the branch the last check updated, the configured
+	// branch or the detected default branch
+	currentBranch string
+	// initialTrigger fires on the first check, when there is no prior state
+	initialTrigger bool
+	onSuccess      []string
+	onFailure      []string
+	always         []string
 }
 
 // GitConfig represents the configuration for a git trigger
 type GitConfig struct {
 	UnitConfig `yaml:",inline"`
 	Repository string `yaml:"repository"`
-	Branch     string `yaml:"branch"`
-	Reset      bool   `yaml:"reset"`
-	Poll       string `yaml:"poll"`
-	Debug      bool   `yaml:"debug"`
+	// Branch omitted or HEAD follows the default branch of origin
+	Branch       string   `yaml:"branch,omitempty"`
+	Reset        bool     `yaml:"reset"`
+	Debug        bool     `yaml:"debug"`
+	FetchDepth   int      `yaml:"fetch_depth,omitempty"`
+	FetchRefspec string   `yaml:"fetch_refspec,omitempty"`
+	Paths        []string `yaml:"paths,omitempty"`
+	Timeout      string   `yaml:"timeout,omitempty"`
+	// InitialTrigger set to false records the first commit seen without
+	// firing (default true)
+	InitialTrigger *bool `yaml:"initial_trigger,omitempty"`
+	// UseNativeGit set to false updates local workspaces with go-git, so the
+	// git binary isn't needed (default true)
+	UseNativeGit *bool `yaml:"use_native_git,omitempty"`
+	// Submodules set to false skips updating submodules (default true)
+	Submodules *bool `yaml:"submodules,omitempty"`
 }
 
 // NewGitTrigger creates a new git trigger unit
 func NewGitTrigger(name, repository, branch string, reset bool, pollInterval time.Duration, debug bool, state *State, onSuccess, onFailure, always []string) *GitTrigger {
 	return &GitTrigger{
-		name:         name,
-		repository:   repository,
-		branch:       branch,
-		reset:        reset,
-		pollInterval: pollInterval,
-		debug:        debug,
-		state:        state,
-		onSuccess:    onSuccess,
-		onFailure:    onFailure,
-		always:       always,
+		initialTrigger: true,
+		nativeGit:      true,
+		submodules:     true,
+		name:           name,
+		repository:     repository,
+		branch:         branch,
+		currentBranch:  branch,
+		reset:          reset,
+		pollInterval:   pollInterval,
+		debug:          debug,
+		state:          state,
+		onSuccess:      onSuccess,
+		onFailure:      onFailure,
+		always:         always,
 	}
 }
 
+// SetFetchOptions configures shallow fetches. depth limits the number of
+// commits fetched (0 = unlimited) and refspec limits which refs are fetched
+// (empty = all).
+func (g *GitTrigger) SetFetchOptions(depth int, refspec string) {
+	g.fetchDepth = depth
+	g.fetchRefspec = refspec
+}
+
+// SetInitialTrigger sets whether the trigger fires on its first check, when
+// there is no recorded commit. If false, the first check only records it.
+func (g *GitTrigger) SetInitialTrigger(initialTrigger bool) {
+	g.initialTrigger = initialTrigger
+}
+
+// SetNativeGit sets whether local workspaces are updated with the git binary.
+// If false, they are updated with go-git, which doesn't need git installed but
+// can only fast-forward when reset is off.
+func (g *GitTrigger) SetNativeGit(native bool) {
+	g.nativeGit = native
+}
+
+// SetSubmodules sets whether a local workspace's submodules are updated
+// after the branch. They are only updated if the repository has a .gitmodules
+// file.
+func (g *GitTrigger) SetSubmodules(submodules bool) {
+	g.submodules = submodules
+}
+
+// SetPaths limits the trigger to commits that change files matching one of
+// the given glob patterns (relative to the repository root). An empty list
+// matches all changes.
+func (g *GitTrigger) SetPaths(paths []string) {
+	g.paths = paths
+}
+
+// SetPollRef makes the trigger read its poll interval from a
+// ${state:<unit>.<key>} or ${env:<NAME>} reference before each polling check,
+// so it can be tuned without a restart. If the reference can't be resolved or
+// parsed, the previous interval is kept.
+func (g *GitTrigger) SetPollRef(ref string) {
+	g.pollRef = ref
+}
+
+// refreshPollInterval updates the poll interval from the poll reference
+func (g *GitTrigger) refreshPollInterval() {
+	value, err := resolveDynamic(g.pollRef, g.state)
+	if err == nil {
+		var interval time.Duration
+		interval, err = time.ParseDuration(value)
+		if err == nil && interval != g.pollInterval {
+			log.Printf("GitTrigger '%s': poll interval changed from %v to %v", g.name, g.pollInterval, interval)
+			g.pollInterval = interval
+		}
+	}
+	if err != nil {
+		log.Printf("GitTrigger '%s': keeping poll interval %v, failed to read %s: %v", g.name, g.pollInterval, g.pollRef, err)
+	}
+}
+
+// SetTimeout limits how long a check, including updating a local workspace,
+// may take (0 = no limit)
+func (g *GitTrigger) SetTimeout(timeout time.Duration) {
+	g.timeout = timeout
+}
+
 // Name returns the name of the unit
 func (g *GitTrigger) Name() string {
 	return g.name
@@ -61,6 +164,49 @@ func (g *GitTrigger) Type() string {
 	return "trigger.git"
 }
 
+// followsDefaultBranch returns true if the trigger follows the default branch
+// of origin instead of a configured branch
+func (g *GitTrigger) followsDefaultBranch() bool {
+	return g.branch == "" || g.branch == "HEAD"
+}
+
+// defaultBranch returns the branch origin's HEAD points to. If origin doesn't
+// report it, refs/remotes/origin/HEAD (set by git clone) is used. A
+// repository without an origin remote uses its checked out branch.
+func defaultBranch(ctx context.Context, repo *git.Repository) (string, error) {
+	remote, err := repo.Remote("origin")
+	if err == git.ErrRemoteNotFound {
+		head, err := repo.Head()
+		if err != nil {
+			return "", fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		if !head.Name().IsBranch() {
+			return "", fmt.Errorf("HEAD is detached and there is no origin remote")
+		}
+		return head.Name().Short(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	refs, listErr := remote.ListContext(ctx, &git.ListOptions{})
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+			return ref.Target().Short(), nil
+		}
+	}
+
+	originHead, err := repo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), false)
+	if err == nil && originHead.Type() == plumbing.SymbolicReference && originHead.Target().IsRemote() {
+		return strings.TrimPrefix(originHead.Target().Short(), "origin/"), nil
+	}
+
+	if listErr != nil {
+		return "", fmt.Errorf("failed to list origin references: %w", listErr)
+	}
+	return "", fmt.Errorf("origin doesn't report its HEAD branch")
+}
+
 // isLocalWorkspace checks if the repository path is a local Git workspace
 func (g *GitTrigger) isLocalWorkspace() bool {
 	// Try to open as a local repository
@@ -69,7 +215,8 @@ func (g *GitTrigger) isLocalWorkspace() bool {
 }
 
 // updateWorkspace updates a local Git workspace to the latest commit on the specified branch
-// Uses native git commands for reliability with SSH, submodules, etc.
+// Uses native git commands for reliability with SSH, submodules, etc., unless
+// native git is turned off.
 func (g *GitTrigger) updateWorkspace(ctx context.Context) error {
 	// Verify repository exists using go-git
 	repo, err := git.PlainOpen(g.repository)
@@ -83,25 +230,41 @@ func (g *GitTrigger) updateWorkspace(ctx context.Context) error {
 		return fmt.Errorf("failed to get remotes: %w", err)
 	}
 
+	branch := g.branch
+	if g.followsDefaultBranch() {
+		branch, err = defaultBranch(ctx, repo)
+		if err != nil {
+			return fmt.Errorf("failed to detect default branch: %w", err)
+		}
+		if branch != g.currentBranch && g.debug {
+			log.Printf("GitTrigger '%s': following default branch %s", g.name, branch)
+		}
+	}
+	g.currentBranch = branch
+
 	// If no remotes, skip update (local-only repository)
 	if len(remotes) == 0 {
 		return nil
 	}
 
+	if !g.nativeGit {
+		return g.updateWorkspaceGoGit(ctx, repo, branch)
+	}
+
 	// Use native git commands for the update operations
 	if g.debug {
 		log.Printf("Fetching updates for repository %s", g.repository)
 	}
 
-	// git fetch origin
-	fetchCmd := exec.CommandContext(ctx, "git", "fetch", "origin")
+	// git fetch origin [--depth=N] [refspec]
+	fetchCmd := exec.CommandContext(ctx, "git", g.fetchArgs()...)
 	fetchCmd.Dir = g.repository
 	if output, err := fetchCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to fetch updates: %w\nOutput: %s", err, output)
 	}
 
 	// git checkout <branch>
-	checkoutCmd := exec.CommandContext(ctx, "git", "checkout", g.branch)
+	checkoutCmd := exec.CommandContext(ctx, "git", "checkout", branch)
 	checkoutCmd.Dir = g.repository
 	if output, err := checkoutCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to checkout branch: %w\nOutput: %s", err, output)
@@ -109,7 +272,7 @@ func (g *GitTrigger) updateWorkspace(ctx context.Context) error {
 
 	// git reset --hard origin/<branch> (if reset enabled) or git merge origin/<branch>
 	if g.reset {
-		remoteBranch := fmt.Sprintf("origin/%s", g.branch)
+		remoteBranch := fmt.Sprintf("origin/%s", branch)
 		resetCmd := exec.CommandContext(ctx, "git", "reset", "--hard", remoteBranch)
 		resetCmd.Dir = g.repository
 		if output, err := resetCmd.CombinedOutput(); err != nil {
@@ -119,7 +282,7 @@ func (g *GitTrigger) updateWorkspace(ctx context.Context) error {
 			log.Printf("Reset workspace to %s", remoteBranch)
 		}
 	} else {
-		remoteBranch := fmt.Sprintf("origin/%s", g.branch)
+		remoteBranch := fmt.Sprintf("origin/%s", branch)
 		mergeCmd := exec.CommandContext(ctx, "git", "merge", remoteBranch)
 		mergeCmd.Dir = g.repository
 		if output, err := mergeCmd.CombinedOutput(); err != nil {
@@ -127,6 +290,10 @@ func (g *GitTrigger) updateWorkspace(ctx context.Context) error {
 		}
 	}
 
+	if !g.updateSubmodules() {
+		return nil
+	}
+
 	// git submodule update --init --recursive
 	if g.debug {
 		log.Printf("Updating submodules for repository %s", g.repository)
@@ -136,10 +303,187 @@ func (g *GitTrigger) updateWorkspace(ctx context.Context) error {
 	if output, err := submoduleCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to update submodules: %w\nOutput: %s", err, output)
 	}
+	g.logSubmoduleStatus(repo)
 
 	return nil
 }
 
+// updateWorkspaceGoGit updates a local Git workspace like updateWorkspace, but
+// with go-git instead of the git binary. Without reset, the branch can only be
+// fast-forwarded to origin/<branch>.
+func (g *GitTrigger) updateWorkspaceGoGit(ctx context.Context, repo *git.Repository, branch string) error {
+	if g.debug {
+		log.Printf("Fetching updates for repository %s (go-git)", g.repository)
+	}
+
+	fetchOptions := &git.FetchOptions{RemoteName: "origin", Depth: g.fetchDepth}
+	if g.fetchRefspec != "" {
+		refspec := goGitRefSpec(g.fetchRefspec)
+		if err := refspec.Validate(); err != nil {
+			return fmt.Errorf("invalid fetch_refspec '%s': %w", g.fetchRefspec, err)
+		}
+		fetchOptions.RefSpecs = []config.RefSpec{refspec}
+	}
+	if err := repo.FetchContext(ctx, fetchOptions); err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch updates: %w", err)
+	}
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return fmt.Errorf("failed to find origin/%s: %w", branch, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	// Check out the branch, creating it from origin/<branch> like git
+	// checkout does if it only exists on the remote
+	branchRef := plumbing.NewBranchReferenceName(branch)
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Name() != branchRef {
+		_, err := repo.Reference(branchRef, false)
+		checkout := &git.CheckoutOptions{Branch: branchRef}
+		if err == plumbing.ErrReferenceNotFound {
+			checkout.Create = true
+			checkout.Hash = remoteRef.Hash()
+		} else if err != nil {
+			return fmt.Errorf("failed to checkout branch: %w", err)
+		}
+		if err := worktree.Checkout(checkout); err != nil {
+			return fmt.Errorf("failed to checkout branch: %w", err)
+		}
+		if head, err = repo.Head(); err != nil {
+			return fmt.Errorf("failed to get HEAD: %w", err)
+		}
+	}
+
+	if g.reset {
+		if err := worktree.Reset(&git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.HardReset}); err != nil {
+			return fmt.Errorf("failed to reset workspace: %w", err)
+		}
+		if g.debug {
+			log.Printf("Reset workspace to origin/%s", branch)
+		}
+	} else if head.Hash() != remoteRef.Hash() {
+		headCommit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to merge updates: %w", err)
+		}
+		remoteCommit, err := repo.CommitObject(remoteRef.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to merge updates: %w", err)
+		}
+		isAncestor, err := headCommit.IsAncestor(remoteCommit)
+		if err != nil {
+			return fmt.Errorf("failed to merge updates: %w", err)
+		}
+		if !isAncestor {
+			// Already contains origin/<branch>, as git merge would report,
+			// or diverged, which go-git can't merge
+			if ahead, err := remoteCommit.IsAncestor(headCommit); err == nil && ahead {
+				return nil
+			}
+			return fmt.Errorf("failed to merge updates: %s has diverged from origin/%s, which needs reset or use_native_git", branch, branch)
+		}
+		if err := worktree.Reset(&git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.MergeReset}); err != nil {
+			return fmt.Errorf("failed to merge updates: %w", err)
+		}
+	}
+
+	if !g.updateSubmodules() {
+		return nil
+	}
+
+	if g.debug {
+		log.Printf("Updating submodules for repository %s (go-git)", g.repository)
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return fmt.Errorf("failed to update submodules: %w", err)
+	}
+	if err := submodules.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	}); err != nil {
+		return fmt.Errorf("failed to update submodules: %w", err)
+	}
+	g.logSubmoduleStatus(repo)
+
+	return nil
+}
+
+// updateSubmodules returns true if the workspace's submodules should be
+// updated: submodules are enabled and the repository has a .gitmodules file
+func (g *GitTrigger) updateSubmodules() bool {
+	if !g.submodules {
+		if g.debug {
+			log.Printf("Skipping submodule update for repository %s, submodules disabled", g.repository)
+		}
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(g.repository, ".gitmodules")); err != nil {
+		if g.debug {
+			log.Printf("Skipping submodule update for repository %s, no .gitmodules", g.repository)
+		}
+		return false
+	}
+	return true
+}
+
+// logSubmoduleStatus logs the status of each submodule in debug mode, e.g.
+// " 1a2b3c4 lib/foo" or "+1a2b3c4 lib/foo (expected 5d6e7f8)" when the
+// checked out commit isn't the recorded one
+func (g *GitTrigger) logSubmoduleStatus(repo *git.Repository) {
+	if !g.debug {
+		return
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		log.Printf("Failed to get submodule status: %v", err)
+		return
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		log.Printf("Failed to get submodule status: %v", err)
+		return
+	}
+	status, err := submodules.Status()
+	if err != nil {
+		log.Printf("Failed to get submodule status: %v", err)
+		return
+	}
+	for _, s := range status {
+		log.Printf("Submodule status: %s", s)
+	}
+}
+
+// goGitRefSpec returns the go-git refspec for a fetch_refspec. A branch name,
+// which git fetch accepts, becomes a refspec updating origin/<branch>.
+func goGitRefSpec(refspec string) config.RefSpec {
+	if strings.Contains(refspec, ":") {
+		return config.RefSpec(refspec)
+	}
+	branch := strings.TrimPrefix(strings.TrimPrefix(refspec, "+"), "refs/heads/")
+	return config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch))
+}
+
+// fetchArgs returns the git arguments used to fetch updates from origin
+func (g *GitTrigger) fetchArgs() []string {
+	args := []string{"fetch", "origin"}
+	if g.fetchDepth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", g.fetchDepth))
+	}
+	if g.fetchRefspec != "" {
+		args = append(args, g.fetchRefspec)
+	}
+	return args
+}
+
 // getCurrentCommitHash gets the current HEAD commit hash from the repository
 func (g *GitTrigger) getCurrentCommitHash() (string, error) {
 	// Open the repository
@@ -159,6 +503,10 @@ func (g *GitTrigger) getCurrentCommitHash() (string, error) {
 
 // Check returns true if the git repository has new commits since last check
 func (g *GitTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	if g.pollRef != "" {
+		g.refreshPollInterval()
+	}
+
 	if g.debug {
 		log.Printf("GitTrigger Check (mode: %s), poll interval: %v", mode, g.pollInterval)
 	}
@@ -173,18 +521,22 @@ func (g *GitTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
 			return false, nil
 		}
 
-		// Check if enough time has passed since last check
+		// Check if enough time has passed since last poll. The poll time is
+		// kept in state so the interval is honored across daemon restarts.
 		now := time.Now()
-		if !g.lastCheckTime.IsZero() {
-			timeSinceLastCheck := now.Sub(g.lastCheckTime)
-			if timeSinceLastCheck < g.pollInterval {
-				// Not enough time has passed, skip check
-				return false, nil
+		if lastPollStr, ok := g.state.GetString(g.name, "last_poll_time"); ok {
+			if lastPoll, err := time.Parse(time.RFC3339Nano, lastPollStr); err == nil {
+				if now.Sub(lastPoll) < g.pollInterval {
+					// Not enough time has passed, skip check
+					return false, nil
+				}
 			}
 		}
 
-		// Update last check time
-		g.lastCheckTime = now
+		// Update last poll time
+		if err := g.state.SetString(g.name, "last_poll_time", now.Format(time.RFC3339Nano)); err != nil {
+			return false, fmt.Errorf("failed to save poll time: %w", err)
+		}
 
 		if g.debug {
 			log.Println("GitTrigger: poll interval elapsed, checking for git updates...")
@@ -196,6 +548,13 @@ func (g *GitTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
 		}
 	}
 
+	// Apply timeout if configured
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
 	// Perform the actual git check
 	return g.checkForGitUpdates(ctx)
 }
@@ -219,25 +578,97 @@ func (g *GitTrigger) checkForGitUpdates(ctx context.Context) (bool, error) {
 	lastHash, ok := g.state.GetString(g.name, "last_commit_hash")
 	if !ok {
 		// No previous commit hash, this is the first run
-		// Save current hash and trigger
+		// Save current hash and trigger unless only a baseline is wanted
 		if err := g.state.SetString(g.name, "last_commit_hash", currentHash); err != nil {
 			return false, fmt.Errorf("failed to save commit hash: %w", err)
 		}
-		return true, nil
+		return g.initialTrigger, nil
 	}
 
 	// Check if commit hash has changed
 	if currentHash != lastHash {
-		// Repository has new commits, update state and trigger
+		// Repository has new commits, update state
 		if err := g.state.SetString(g.name, "last_commit_hash", currentHash); err != nil {
 			return false, fmt.Errorf("failed to save commit hash: %w", err)
 		}
+
+		// Only trigger if the new commits touch the watched paths
+		if len(g.paths) > 0 {
+			matched, err := g.changesMatchPaths(lastHash, currentHash)
+			if err != nil {
+				// Can't compare (e.g., old commit missing from a shallow clone),
+				// so err on the side of triggering
+				log.Printf("Git trigger '%s': unable to diff commits, triggering: %v", g.name, err)
+				return true, nil
+			}
+			if !matched {
+				if g.debug {
+					log.Printf("GitTrigger: no changes under %v, skipping", g.paths)
+				}
+				return false, nil
+			}
+		}
+
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// changesMatchPaths returns true if any file changed between the two commits
+// matches one of the configured path patterns
+func (g *GitTrigger) changesMatchPaths(fromHash, toHash string) (bool, error) {
+	repo, err := git.PlainOpen(g.repository)
+	if err != nil {
+		return false, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	fromTree, err := commitTree(repo, fromHash)
+	if err != nil {
+		return false, err
+	}
+	toTree, err := commitTree(repo, toHash)
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return false, fmt.Errorf("failed to diff commits: %w", err)
+	}
+
+	for _, change := range changes {
+		// Check both names to catch additions, deletions, and renames
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name == "" {
+				continue
+			}
+			for _, pattern := range g.paths {
+				if ok, _ := doublestar.Match(pattern, name); ok {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// commitTree returns the tree of the commit with the given hash
+func commitTree(repo *git.Repository, hash string) (*object.Tree, error) {
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree for commit %s: %w", hash, err)
+	}
+
+	return tree, nil
+}
+
 // OnSuccess returns the list of units to trigger on success
 func (g *GitTrigger) OnSuccess() []string {
 	return g.onSuccess
@@ -256,7 +687,7 @@ func (g *GitTrigger) Always() []string {
 // Run executes the trigger unit
 // Note: Check() has already been called by the orchestrator before Run() is invoked
 func (g *GitTrigger) Run(ctx context.Context) error {
-	// Get current commit hash for logging
+	// Get current commit hash for logging and downstream units
 	currentHash, _ := g.getCurrentCommitHash()
 	shortHash := currentHash
 	if len(shortHash) > 7 {
@@ -264,5 +695,17 @@ func (g *GitTrigger) Run(ctx context.Context) error {
 	}
 	log.Printf("Git trigger '%s' activated (commit: %s)", g.name, shortHash)
 
+	g.env = map[string]string{
+		"BRUN_GIT_COMMIT":       currentHash,
+		"BRUN_GIT_COMMIT_SHORT": shortHash,
+		"BRUN_GIT_BRANCH":       g.currentBranch,
+	}
+
 	return nil
 }
+
+// Env returns the commit and branch of the last Run as BRUN_GIT_COMMIT,
+// BRUN_GIT_COMMIT_SHORT, and BRUN_GIT_BRANCH, for the units it triggers
+func (g *GitTrigger) Env() map[string]string {
+	return g.env
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -168,7 +170,7 @@ func TestGitTrigger_Run(t *testing.T) {
 		t.Fatalf("Failed to add file: %v", err)
 	}
 
-	_, err = worktree.Commit("Test commit", &git.CommitOptions{
+	commit, err := worktree.Commit("Test commit", &git.CommitOptions{
 		Author: &object.Signature{
 			Name:  "Test",
 			Email: "test@example.com",
@@ -208,6 +210,29 @@ func TestGitTrigger_Run(t *testing.T) {
 		t.Errorf("Run failed: %v", err)
 	}
 
+	env := trigger.Env()
+	if env["BRUN_GIT_COMMIT"] != commit.String() {
+		t.Errorf("Expected BRUN_GIT_COMMIT %s, got %s", commit.String(), env["BRUN_GIT_COMMIT"])
+	}
+	if env["BRUN_GIT_COMMIT_SHORT"] != commit.String()[:7] {
+		t.Errorf("Expected BRUN_GIT_COMMIT_SHORT %s, got %s", commit.String()[:7], env["BRUN_GIT_COMMIT_SHORT"])
+	}
+	if env["BRUN_GIT_BRANCH"] != "main" {
+		t.Errorf("Expected BRUN_GIT_BRANCH main, got %s", env["BRUN_GIT_BRANCH"])
+	}
+
+	// Triggered run units see the commit in their environment
+	build := NewRunUnit("build", "echo commit=$BRUN_GIT_COMMIT_SHORT branch=$BRUN_GIT_BRANCH", "", 0, "", false, nil, nil, nil)
+	orchestrator := NewOrchestrator([]Unit{trigger, build})
+	orchestrator.resetActivation()
+	if err := orchestrator.executeUnit(ctx, trigger, []string{trigger.Name()}); err != nil {
+		t.Fatalf("executeUnit failed: %v", err)
+	}
+	want := "commit=" + commit.String()[:7] + " branch=main"
+	if output := orchestrator.GetResults()["build"].Output; !strings.Contains(output, want) {
+		t.Errorf("Expected build output to contain %q, got %q", want, output)
+	}
+
 	onSuccess := trigger.OnSuccess()
 	if len(onSuccess) != 1 || onSuccess[0] != "build" {
 		t.Errorf("Expected on_success [build], got %v", onSuccess)
@@ -244,6 +269,8 @@ units:
       name: watch-repo
       repository: ` + repoPath + `
       branch: main
+      fetch_depth: 1
+      fetch_refspec: main
       on_success:
         - build
 `
@@ -287,6 +314,27 @@ units:
 	if len(gitTrigger.onSuccess) != 1 || gitTrigger.onSuccess[0] != "build" {
 		t.Errorf("Expected on_success [build], got %v", gitTrigger.onSuccess)
 	}
+
+	if gitTrigger.fetchDepth != 1 || gitTrigger.fetchRefspec != "main" {
+		t.Errorf("Expected fetch depth 1 and refspec 'main', got %d and '%s'", gitTrigger.fetchDepth, gitTrigger.fetchRefspec)
+	}
+}
+
+func TestGitTrigger_FetchArgs(t *testing.T) {
+	trigger := NewGitTrigger("test-git", "/tmp/repo", "main", false, 0, false, nil, nil, nil, nil)
+
+	// Default is a full fetch for compatibility
+	args := strings.Join(trigger.fetchArgs(), " ")
+	if args != "fetch origin" {
+		t.Errorf("Expected 'fetch origin', got '%s'", args)
+	}
+
+	trigger.SetFetchOptions(1, "+refs/heads/main:refs/remotes/origin/main")
+	args = strings.Join(trigger.fetchArgs(), " ")
+	expected := "fetch origin --depth=1 +refs/heads/main:refs/remotes/origin/main"
+	if args != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, args)
+	}
 }
 
 func TestCreateUnits_GitMissingRepository(t *testing.T) {
@@ -604,6 +652,105 @@ func TestGitTrigger_CheckModePolling_IntervalNotElapsed(t *testing.T) {
 	}
 }
 
+// TestGitTrigger_CheckModePolling_IntervalPersisted tests that the poll
+// interval is honored across a state reload (e.g., daemon restart)
+func TestGitTrigger_CheckModePolling_IntervalPersisted(t *testing.T) {
+	tempDir := t.TempDir()
+	repoPath := filepath.Join(tempDir, "repo")
+	stateFile := filepath.Join(tempDir, "state.yaml")
+
+	// Initialize a git repository
+	repo, err := git.PlainInit(repoPath, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	// Create and commit a test file
+	testFile := filepath.Join(repoPath, "test.txt")
+	if err := os.WriteFile(testFile, []byte("initial content"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	if _, err := worktree.Add("test.txt"); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+
+	_, err = worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "Test",
+			Email: "test@example.com",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	state := NewState(stateFile)
+	trigger := NewGitTrigger("test-git-persist", repoPath, "main", false, time.Hour, false, state, nil, nil, nil)
+
+	ctx := context.Background()
+
+	// First check should trigger (new repository)
+	shouldTrigger, err := trigger.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger on first CheckModePolling")
+	}
+
+	if _, ok := state.GetString("test-git-persist", "last_poll_time"); !ok {
+		t.Error("Expected last_poll_time to be saved in state")
+	}
+
+	// Make a new commit
+	if err := os.WriteFile(testFile, []byte("updated content"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := worktree.Add("test.txt"); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	_, err = worktree.Commit("Second commit", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "Test",
+			Email: "test@example.com",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	// Simulate a restart: reload state from disk into a new trigger
+	reloaded := NewState(stateFile)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	restarted := NewGitTrigger("test-git-persist", repoPath, "main", false, time.Hour, false, reloaded, nil, nil, nil)
+
+	// The interval has not elapsed, so the new commit should not be detected
+	shouldTrigger, err = restarted.Check(ctx, CheckModePolling)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if shouldTrigger {
+		t.Error("Expected no trigger after restart before interval elapsed")
+	}
+
+	lastHash, _ := reloaded.GetString("test-git-persist", "last_commit_hash")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	if lastHash == head.Hash().String() {
+		t.Error("Expected no fetch/check of the new commit before interval elapsed")
+	}
+}
+
 // TestGitTrigger_CheckModePolling_IntervalElapsed tests active mode polling with interval elapsed
 // Git unit SHOULD check when poll interval has passed
 func TestGitTrigger_CheckModePolling_IntervalElapsed(t *testing.T) {
@@ -979,3 +1126,429 @@ func TestGitTrigger_MultipleCheckModeManual(t *testing.T) {
 		t.Error("Expected trigger in CheckModeManual after new commit")
 	}
 }
+
+// TestGitTrigger_Paths tests that a git trigger with paths only fires for
+// commits that change matching files
+func TestGitTrigger_Paths(t *testing.T) {
+	tempDir := t.TempDir()
+	repoPath := filepath.Join(tempDir, "repo")
+	stateFile := filepath.Join(tempDir, "state.yaml")
+
+	repo, err := git.PlainInit(repoPath, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	commitFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repoPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatalf("Failed to add file: %v", err)
+		}
+		_, err := worktree.Commit("Update "+name, &git.CommitOptions{
+			Author: &object.Signature{
+				Name:  "Test",
+				Email: "test@example.com",
+				When:  time.Now(),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+
+	commitFile("README.md", "initial")
+
+	state := NewState(stateFile)
+	trigger := NewGitTrigger("test-git-paths", repoPath, "main", false, 0, false, state, nil, nil, nil)
+	trigger.SetPaths([]string{"firmware/**"})
+
+	ctx := context.Background()
+
+	// First check always triggers
+	shouldTrigger, err := trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger on first check")
+	}
+
+	// Commit outside the watched paths should not trigger
+	commitFile("docs/guide.md", "docs")
+	shouldTrigger, err = trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if shouldTrigger {
+		t.Error("Expected no trigger for changes outside watched paths")
+	}
+
+	// Commit under the watched paths should trigger
+	commitFile("firmware/src/main.c", "int main() {}")
+	shouldTrigger, err = trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger for changes under watched paths")
+	}
+}
+
+func TestGitTrigger_NoInitialTrigger(t *testing.T) {
+	tempDir := t.TempDir()
+	repoPath := filepath.Join(tempDir, "repo")
+
+	repo, err := git.PlainInit(repoPath, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := worktree.Add("test.txt"); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	commit, err := worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	trigger := NewGitTrigger("test-git", repoPath, "main", false, 0, false, state, nil, nil, nil)
+	trigger.SetInitialTrigger(false)
+
+	shouldTrigger, err := trigger.Check(context.Background(), CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if shouldTrigger {
+		t.Error("Expected no trigger on first check with initial_trigger false")
+	}
+
+	storedHash, ok := state.GetString("test-git", "last_commit_hash")
+	if !ok || storedHash != commit.String() {
+		t.Errorf("Expected baseline hash %s to be stored, got %q", commit.String(), storedHash)
+	}
+}
+
+func TestGitTrigger_GoGitWorkspace(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamPath := filepath.Join(tempDir, "upstream")
+	workspacePath := filepath.Join(tempDir, "workspace")
+
+	upstream, err := git.PlainInit(upstreamPath, false)
+	if err != nil {
+		t.Fatalf("Failed to init upstream repo: %v", err)
+	}
+	upstreamTree, err := upstream.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	commit := func(worktree *git.Worktree, dir, name string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatalf("Failed to add file: %v", err)
+		}
+		hash, err := worktree.Commit("Add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		return hash.String()
+	}
+	commit(upstreamTree, upstreamPath, "a.txt")
+
+	if _, err := git.PlainClone(workspacePath, false, &git.CloneOptions{URL: upstreamPath}); err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	trigger := NewGitTrigger("test-git", workspacePath, "master", false, 0, false, state, nil, nil, nil)
+	trigger.SetNativeGit(false)
+
+	ctx := context.Background()
+	if _, err := trigger.Check(ctx, CheckModeManual); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	// New upstream commits are fast-forwarded into the workspace
+	hash := commit(upstreamTree, upstreamPath, "b.txt")
+	shouldTrigger, err := trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger for new upstream commit")
+	}
+	if got, _ := state.GetString("test-git", "last_commit_hash"); got != hash {
+		t.Errorf("Expected workspace at %s, got %s", hash, got)
+	}
+	if _, err := os.Stat(filepath.Join(workspacePath, "b.txt")); err != nil {
+		t.Errorf("Expected b.txt checked out: %v", err)
+	}
+
+	// A diverged workspace can't be merged without reset. The workspace is
+	// opened after the trigger's fetch so the fetched objects are seen.
+	workspace, err := git.PlainOpen(workspacePath)
+	if err != nil {
+		t.Fatalf("Failed to open workspace: %v", err)
+	}
+	workspaceTree, err := workspace.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	commit(workspaceTree, workspacePath, "local.txt")
+	hash = commit(upstreamTree, upstreamPath, "c.txt")
+	if _, err := trigger.Check(ctx, CheckModeManual); err == nil || !strings.Contains(err.Error(), "diverged") {
+		t.Errorf("Expected diverged error, got %v", err)
+	}
+
+	// With reset, the workspace is reset to the upstream branch
+	trigger.reset = true
+	shouldTrigger, err = trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger after reset")
+	}
+	if got, _ := state.GetString("test-git", "last_commit_hash"); got != hash {
+		t.Errorf("Expected workspace reset to %s, got %s", hash, got)
+	}
+}
+
+// TestGitTrigger_Forced verifies that a forced git trigger still updates its
+// workspace before the units it triggers run
+func TestGitTrigger_Forced(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamPath := filepath.Join(tempDir, "upstream")
+	workspacePath := filepath.Join(tempDir, "workspace")
+	outFile := filepath.Join(tempDir, "out.txt")
+
+	upstream, err := git.PlainInit(upstreamPath, false)
+	if err != nil {
+		t.Fatalf("Failed to init upstream repo: %v", err)
+	}
+	upstreamTree, err := upstream.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	commit := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(upstreamPath, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := upstreamTree.Add(name); err != nil {
+			t.Fatalf("Failed to add file: %v", err)
+		}
+		if _, err := upstreamTree.Commit("Add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		}); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+	commit("a.txt")
+	if _, err := git.PlainClone(workspacePath, false, &git.CloneOptions{URL: upstreamPath}); err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	trigger := NewGitTrigger("git-watch", workspacePath, "master", false, 0, false, state, []string{"build"}, nil, nil)
+	trigger.SetNativeGit(false)
+	trigger.SetInitialTrigger(false)
+	build := NewRunUnit("build", `ls b.txt && echo "$BRUN_GIT_BRANCH" > `+outFile, workspacePath, 0, "", false, nil, nil, nil)
+
+	orchestrator := NewOrchestrator([]Unit{NewStartTrigger("start", []string{"git-watch"}, nil, nil), trigger, build})
+	orchestrator.SetForcedTriggers(map[string][]string{"start": {"git-watch"}})
+
+	commit("b.txt")
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+	if result := orchestrator.GetResults()["build"]; result == nil || result.Error != nil {
+		t.Fatalf("Expected build to run in an updated workspace, got %+v", result)
+	}
+	if out, _ := os.ReadFile(outFile); strings.TrimSpace(string(out)) != "master" {
+		t.Errorf("Expected BRUN_GIT_BRANCH=master, got %q", out)
+	}
+}
+
+func TestGitTrigger_Submodules(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamPath := filepath.Join(tempDir, "upstream")
+	workspacePath := filepath.Join(tempDir, "workspace")
+
+	upstream, err := git.PlainInit(upstreamPath, false)
+	if err != nil {
+		t.Fatalf("Failed to init upstream repo: %v", err)
+	}
+	worktree, err := upstream.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	commit := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(upstreamPath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatalf("Failed to add file: %v", err)
+		}
+		_, err := worktree.Commit("Add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+	commit("README.md", "readme")
+
+	if _, err := git.PlainClone(workspacePath, false, &git.CloneOptions{URL: upstreamPath}); err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	trigger := NewGitTrigger("test-git", workspacePath, "master", false, 0, false, state, nil, nil, nil)
+	trigger.SetNativeGit(false)
+
+	// Without .gitmodules the submodule step is skipped
+	ctx := context.Background()
+	if _, err := trigger.Check(ctx, CheckModeManual); err != nil {
+		t.Fatalf("Check failed without .gitmodules: %v", err)
+	}
+
+	// A submodule that can't be updated fails the check unless submodules
+	// are disabled
+	commit(".gitmodules", "[submodule \"lib\"]\n\tpath = lib\n\turl = "+filepath.Join(tempDir, "missing")+"\n")
+	if _, err := trigger.Check(ctx, CheckModeManual); err == nil || !strings.Contains(err.Error(), "submodules") {
+		t.Errorf("Expected submodule update error, got %v", err)
+	}
+
+	trigger.SetSubmodules(false)
+	shouldTrigger, err := trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed with submodules disabled: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger for the commit the failed check didn't record")
+	}
+}
+
+func TestGitTrigger_DefaultBranch(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamPath := filepath.Join(tempDir, "upstream")
+	workspacePath := filepath.Join(tempDir, "workspace")
+
+	upstream, err := git.PlainInitWithOptions(upstreamPath, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("trunk")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to init upstream repo: %v", err)
+	}
+	worktree, err := upstream.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	commit := func(name string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(upstreamPath, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatalf("Failed to add file: %v", err)
+		}
+		hash, err := worktree.Commit("Add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		return hash.String()
+	}
+	commit("a.txt")
+
+	if _, err := git.PlainClone(workspacePath, false, &git.CloneOptions{URL: upstreamPath}); err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+
+	configFile := filepath.Join(tempDir, "config.yaml")
+	configContent := `config:
+  state_location: state.yaml
+units:
+  - git:
+      name: default-branch
+      repository: workspace
+      use_native_git: false
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+	trigger := units[0].(*GitTrigger)
+
+	ctx := context.Background()
+	if _, err := trigger.Check(ctx, CheckModeManual); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	hash := commit("b.txt")
+	shouldTrigger, err := trigger.Check(ctx, CheckModeManual)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !shouldTrigger {
+		t.Error("Expected trigger for new commit on the default branch")
+	}
+	if got, _ := config.State().GetString("default-branch", "last_commit_hash"); got != hash {
+		t.Errorf("Expected workspace at %s, got %s", hash, got)
+	}
+
+	if err := trigger.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if branch := trigger.Env()["BRUN_GIT_BRANCH"]; branch != "trunk" {
+		t.Errorf("Expected BRUN_GIT_BRANCH trunk, got %q", branch)
+	}
+
+	// Without a branch, the repository must be a local workspace
+	configContent = strings.Replace(configContent, "repository: workspace", "repository: https://example.com/repo.git", 1)
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err = LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if _, err := config.CreateUnits(); err == nil || !strings.Contains(err.Error(), "branch is required") {
+		t.Errorf("Expected branch required error, got %v", err)
+	}
+}
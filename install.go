@@ -1,10 +1,13 @@
 package brun
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 const (
@@ -17,7 +20,11 @@ const (
 // If run as root, installs system-wide service
 // Otherwise, installs user service
 // daemonMode determines whether the service runs in daemon mode (continuous) or oneshot mode
-func Install(daemonMode bool) error {
+// An existing service file that differs is shown as a diff and only replaced
+// while the service is active if force is set
+// systemdTimers installs a systemd timer for each cron trigger, which runs it
+// with run -trigger, and the service skips those triggers
+func Install(daemonMode, force, systemdTimers bool) error {
 	// Get the path to the current executable
 	execPath, err := os.Executable()
 	if err != nil {
@@ -30,17 +37,176 @@ func Install(daemonMode bool) error {
 		return fmt.Errorf("failed to resolve executable path: %w", err)
 	}
 
+	// The service would break once a temporary binary is removed
+	if reason := unstableExecPath(execPath); reason != "" {
+		fmt.Printf("Warning: %s is %s; install brun to a stable location such as /usr/local/bin first\n", execPath, reason)
+	}
+
 	// Check if running as root
 	isRoot := os.Geteuid() == 0
 
 	if isRoot {
-		return installSystemService(execPath, daemonMode)
+		return installSystemService(execPath, daemonMode, force, systemdTimers)
+	}
+	return installUserService(execPath, daemonMode, force, systemdTimers)
+}
+
+// unstableExecPath returns why execPath is a transient location, such as a
+// go run build directory, or "" if it isn't
+func unstableExecPath(execPath string) string {
+	if strings.Contains(execPath, string(filepath.Separator)+"go-build") {
+		return "a temporary go build binary"
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil && isWithin(execPath, cacheDir) {
+		return "in the cache directory"
+	}
+	for _, dir := range []string{os.TempDir(), "/tmp", "/var/tmp"} {
+		if isWithin(execPath, dir) {
+			return "in a temporary directory"
+		}
+	}
+	return ""
+}
+
+// isWithin returns true if path is inside dir
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// errServiceActive is returned when an active service would be changed
+// without force
+var errServiceActive = errors.New("service is active, rerun with -force to overwrite it")
+
+// writeServiceFile writes content to path. It returns false if an identical
+// file already exists. If the file differs, the changes are printed, and an
+// active service is only overwritten if force is set.
+func writeServiceFile(path, content string, active func() bool, force bool) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read existing service file: %w", err)
+	}
+
+	if err == nil {
+		if string(existing) == content {
+			fmt.Printf("Service file %s is up to date\n", path)
+			return false, nil
+		}
+
+		fmt.Printf("Existing service file %s will change:\n", path)
+		fmt.Print(diffLines(string(existing), content))
+
+		if active() && !force {
+			return false, errServiceActive
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write service file: %w", err)
+	}
+
+	fmt.Printf("Service file written to %s\n", path)
+	return true, nil
+}
+
+// diffLines returns the lines removed from oldText ("-") and added in newText
+// ("+")
+func diffLines(oldText, newText string) string {
+	oldLines := strings.Split(strings.TrimSuffix(oldText, "\n"), "\n")
+	newLines := strings.Split(strings.TrimSuffix(newText, "\n"), "\n")
+
+	var diff strings.Builder
+	for _, line := range oldLines {
+		if !slices.Contains(newLines, line) {
+			fmt.Fprintf(&diff, "- %s\n", line)
+		}
+	}
+	for _, line := range newLines {
+		if !slices.Contains(oldLines, line) {
+			fmt.Fprintf(&diff, "+ %s\n", line)
+		}
+	}
+	return diff.String()
+}
+
+// serviceActive returns true if systemctl reports the service as active.
+// args select the systemd instance (e.g., --user).
+func serviceActive(args ...string) bool {
+	return systemdUnitActive(userServiceName, args...)
+}
+
+// systemdUnitActive returns true if systemctl reports the unit as active.
+// args select the systemd instance (e.g., --user).
+func systemdUnitActive(unit string, args ...string) bool {
+	args = append(slices.Clone(args), "is-active", "--quiet", unit)
+	return exec.Command("systemctl", args...).Run() == nil
+}
+
+// DefaultConfigPath returns the config file used by the service Install
+// installs: the system config when run as root and the user config otherwise
+func DefaultConfigPath() (string, error) {
+	if os.Geteuid() == 0 {
+		return "/etc/brun/config.yaml", nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "brun", "config.yaml"), nil
+}
+
+// ServiceStatus returns the state of the brun service reported by systemctl
+// is-active, such as "active", "inactive", or "failed". Like Install, it uses
+// the system service when run as root and the user service otherwise.
+func ServiceStatus() string {
+	var args []string
+	if os.Geteuid() != 0 {
+		args = append(args, "--user")
+	}
+	args = append(args, "is-active", userServiceName)
+
+	// is-active exits non-zero for any state but active, so only the output
+	// matters
+	output, err := exec.Command("systemctl", args...).Output()
+	if status := strings.TrimSpace(string(output)); status != "" {
+		return status
+	}
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+	return "unknown"
+}
+
+// Logs shows the brun service logs with journalctl, following new entries if
+// follow is set. Like Install, it uses the system service when run as root
+// and the user service otherwise.
+func Logs(follow bool) error {
+	cmd := exec.Command("journalctl", journalctlArgs(os.Geteuid() == 0, follow)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("journalctl failed: %w", err)
+	}
+	return nil
+}
+
+// journalctlArgs returns the journalctl arguments that show the logs of the
+// system or user brun service
+func journalctlArgs(isRoot, follow bool) []string {
+	var args []string
+	if !isRoot {
+		args = append(args, "--user")
 	}
-	return installUserService(execPath, daemonMode)
+	args = append(args, "-u", userServiceName)
+	if follow {
+		args = append(args, "-f")
+	}
+	return args
 }
 
 // installSystemService installs a system-wide systemd service
-func installSystemService(execPath string, daemonMode bool) error {
+func installSystemService(execPath string, daemonMode, force, systemdTimers bool) error {
 	fmt.Println("Installing system-wide systemd service...")
 
 	configPath := "/etc/brun/config.yaml"
@@ -49,32 +215,45 @@ func installSystemService(execPath string, daemonMode bool) error {
 	if err := createDefaultConfigIfNeeded(configPath); err != nil {
 		return fmt.Errorf("failed to create config: %w", err)
 	}
+	timers, err := prepareInstall(configPath, daemonMode, systemdTimers)
+	if err != nil {
+		return err
+	}
 
-	serviceContent := generateSystemServiceFile(execPath, daemonMode)
+	serviceContent := generateSystemServiceFile(execPath, daemonMode, timerNames(timers))
 
 	// Write service file
-	if err := os.WriteFile(systemServicePath, []byte(serviceContent), 0644); err != nil {
-		return fmt.Errorf("failed to write service file: %w", err)
+	changed, err := writeServiceFile(systemServicePath, serviceContent, func() bool { return serviceActive() }, force)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Service file written to %s\n", systemServicePath)
+	timersChanged, err := writeTimerFiles(filepath.Dir(systemServicePath), execPath, configPath, timers, false, force)
+	if err != nil {
+		return err
+	}
 
 	// Reload systemd
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
-		return fmt.Errorf("failed to reload systemd: %w", err)
+	if changed || timersChanged {
+		if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
 	}
 
 	// Enable service
 	if err := exec.Command("systemctl", "enable", "brun.service").Run(); err != nil {
 		return fmt.Errorf("failed to enable service: %w", err)
 	}
+	if err := enableTimers(timers); err != nil {
+		return err
+	}
 
 	fmt.Println("Service enabled. Start it with: systemctl start brun.service")
 	return nil
 }
 
 // installUserService installs a user systemd service
-func installUserService(execPath string, daemonMode bool) error {
+func installUserService(execPath string, daemonMode, force, systemdTimers bool) error {
 	fmt.Println("Installing user systemd service...")
 
 	homeDir, err := os.UserHomeDir()
@@ -88,6 +267,10 @@ func installUserService(execPath string, daemonMode bool) error {
 	if err := createDefaultConfigIfNeeded(configPath); err != nil {
 		return fmt.Errorf("failed to create config: %w", err)
 	}
+	timers, err := prepareInstall(configPath, daemonMode, systemdTimers)
+	if err != nil {
+		return err
+	}
 
 	serviceDir := filepath.Join(homeDir, userServiceDir)
 	servicePath := filepath.Join(serviceDir, userServiceName)
@@ -97,40 +280,165 @@ func installUserService(execPath string, daemonMode bool) error {
 		return fmt.Errorf("failed to create service directory: %w", err)
 	}
 
-	serviceContent := generateUserServiceFile(execPath, daemonMode)
+	serviceContent := generateUserServiceFile(execPath, daemonMode, timerNames(timers))
 
 	// Write service file
-	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
-		return fmt.Errorf("failed to write service file: %w", err)
+	changed, err := writeServiceFile(servicePath, serviceContent, func() bool { return serviceActive("--user") }, force)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Service file written to %s\n", servicePath)
+	timersChanged, err := writeTimerFiles(serviceDir, execPath, configPath, timers, true, force)
+	if err != nil {
+		return err
+	}
 
 	// Reload user systemd
-	if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
-		return fmt.Errorf("failed to reload systemd: %w", err)
+	if changed || timersChanged {
+		if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
 	}
 
 	// Enable user service
 	if err := exec.Command("systemctl", "--user", "enable", userServiceName).Run(); err != nil {
 		return fmt.Errorf("failed to enable service: %w", err)
 	}
+	if err := enableTimers(timers, "--user"); err != nil {
+		return err
+	}
 
 	fmt.Println("Service enabled. Start it with: systemctl --user start brun.service")
 	return nil
 }
 
+// prepareInstall checks the config at configPath before installing. With
+// systemdTimers, it returns a timer for each cron trigger. A oneshot install
+// warns about the remaining triggers that need polling.
+func prepareInstall(configPath string, daemonMode, systemdTimers bool) ([]cronTimer, error) {
+	if daemonMode && !systemdTimers {
+		return nil, nil
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		if systemdTimers {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		fmt.Printf("Warning: can't check %s for polling triggers: %v\n", configPath, err)
+		return nil, nil
+	}
+
+	var timers []cronTimer
+	if systemdTimers {
+		timers, err = config.cronTimers()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !daemonMode {
+		var polling []string
+		for _, name := range config.PollingTriggers() {
+			if !slices.Contains(timerNames(timers), name) {
+				polling = append(polling, name)
+			}
+		}
+		if msg := oneshotPollingWarning(polling, systemdTimers); msg != "" {
+			fmt.Println(msg)
+		}
+	}
+
+	return timers, nil
+}
+
+// oneshotPollingWarning returns the warning for a oneshot install of a config
+// with the given polling triggers, or "" if there are none
+func oneshotPollingWarning(triggers []string, systemdTimers bool) string {
+	if len(triggers) == 0 {
+		return ""
+	}
+	hint := "install with -daemon to poll them"
+	if !systemdTimers {
+		hint += ", or with -use-systemd-timers to run cron triggers from systemd timers"
+	}
+	return fmt.Sprintf("Warning: the oneshot service only checks triggers once each boot, so %s won't fire "+
+		"between boots; %s", strings.Join(triggers, ", "), hint)
+}
+
+// timerNames returns the names of the cron triggers the timers run
+func timerNames(timers []cronTimer) []string {
+	var names []string
+	for _, timer := range timers {
+		names = append(names, timer.Name)
+	}
+	return names
+}
+
+// writeTimerFiles writes a brun-<name>.timer and brun-<name>.service file to
+// dir for each timer. It returns true if any file changed.
+func writeTimerFiles(dir, execPath, configPath string, timers []cronTimer, userService, force bool) (bool, error) {
+	var args []string
+	if userService {
+		args = append(args, "--user")
+	}
+
+	changed := false
+	for _, timer := range timers {
+		unitName := timerUnitName(timer.Name)
+		active := func() bool { return systemdUnitActive(unitName+".timer", args...) }
+
+		serviceContent := generateTimerServiceFile(execPath, configPath, timer.Name, userService)
+		serviceChanged, err := writeServiceFile(filepath.Join(dir, unitName+".service"), serviceContent, active, force)
+		if err != nil {
+			return false, err
+		}
+		timerChanged, err := writeServiceFile(filepath.Join(dir, unitName+".timer"), generateTimerFile(timer), active, force)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || serviceChanged || timerChanged
+	}
+	return changed, nil
+}
+
+// enableTimers enables and starts the timers. args select the systemd
+// instance (e.g., --user).
+func enableTimers(timers []cronTimer, args ...string) error {
+	for _, timer := range timers {
+		unit := timerUnitName(timer.Name) + ".timer"
+		enableArgs := append(slices.Clone(args), "enable", "--now", unit)
+		if err := exec.Command("systemctl", enableArgs...).Run(); err != nil {
+			return fmt.Errorf("failed to enable timer %s: %w", unit, err)
+		}
+		fmt.Printf("Timer %s enabled\n", unit)
+	}
+	return nil
+}
+
+// serviceExecCommand returns the brun command the service runs. Cron
+// triggers run by systemd timers are skipped so they don't fire twice.
+func serviceExecCommand(execPath, configPath string, daemonMode bool, skipTriggers []string) string {
+	execCommand := fmt.Sprintf("%s run %s", execPath, configPath)
+	if daemonMode {
+		execCommand += " -daemon"
+	}
+	for _, name := range skipTriggers {
+		execCommand += " -skip-trigger " + systemdQuote(name)
+	}
+	return execCommand
+}
+
 // generateSystemServiceFile generates the systemd service file content for system service
-func generateSystemServiceFile(execPath string, daemonMode bool) string {
+func generateSystemServiceFile(execPath string, daemonMode bool, skipTriggers []string) string {
 	serviceType := "oneshot"
-	execCommand := fmt.Sprintf("%s run /etc/brun/config.yaml", execPath)
 	restart := "no"
 
 	if daemonMode {
 		serviceType = "simple"
-		execCommand = fmt.Sprintf("%s run /etc/brun/config.yaml -daemon", execPath)
 		restart = "always"
 	}
+	execCommand := serviceExecCommand(execPath, "/etc/brun/config.yaml", daemonMode, skipTriggers)
 
 	return fmt.Sprintf(`[Unit]
 Description=BRun - Bare-OS Runner
@@ -149,19 +457,18 @@ WantedBy=multi-user.target
 }
 
 // generateUserServiceFile generates the systemd service file content for user service
-func generateUserServiceFile(execPath string, daemonMode bool) string {
+func generateUserServiceFile(execPath string, daemonMode bool, skipTriggers []string) string {
 	homeDir, _ := os.UserHomeDir()
 	configPath := filepath.Join(homeDir, ".config", "brun", "config.yaml")
 
 	serviceType := "oneshot"
-	execCommand := fmt.Sprintf("%s run %s", execPath, configPath)
 	restart := "no"
 
 	if daemonMode {
 		serviceType = "simple"
-		execCommand = fmt.Sprintf("%s run %s -daemon", execPath, configPath)
 		restart = "always"
 	}
+	execCommand := serviceExecCommand(execPath, configPath, daemonMode, skipTriggers)
 
 	return fmt.Sprintf(`[Unit]
 Description=BRun - Bare-OS Runner
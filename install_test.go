@@ -0,0 +1,133 @@
+package brun
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteServiceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brun.service")
+	active := func() bool { return true }
+
+	// New file is written
+	changed, err := writeServiceFile(path, "ExecStart=/usr/bin/brun\n", active, false)
+	if err != nil || !changed {
+		t.Fatalf("Expected new file to be written, changed=%v err=%v", changed, err)
+	}
+
+	// Identical content is left alone
+	changed, err = writeServiceFile(path, "ExecStart=/usr/bin/brun\n", active, false)
+	if err != nil || changed {
+		t.Errorf("Expected identical file to be unchanged, changed=%v err=%v", changed, err)
+	}
+
+	// Active service isn't overwritten without force
+	_, err = writeServiceFile(path, "ExecStart=/tmp/brun\n", active, false)
+	if !errors.Is(err, errServiceActive) {
+		t.Errorf("Expected errServiceActive, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "ExecStart=/usr/bin/brun\n" {
+		t.Errorf("Expected service file to be unchanged, got %q", string(data))
+	}
+
+	// Force overwrites
+	changed, err = writeServiceFile(path, "ExecStart=/usr/local/bin/brun\n", active, true)
+	if err != nil || !changed {
+		t.Errorf("Expected forced overwrite, changed=%v err=%v", changed, err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := diffLines("Type=oneshot\nExecStart=/a\n", "Type=oneshot\nExecStart=/b\n")
+	if diff != "- ExecStart=/a\n+ ExecStart=/b\n" {
+		t.Errorf("Unexpected diff: %q", diff)
+	}
+}
+
+func TestUnstableExecPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		unstable bool
+	}{
+		{"/usr/local/bin/brun", false},
+		{"/home/user/go/bin/brun", false},
+		{"/tmp/brun", true},
+		{filepath.Join(os.TempDir(), "go-build123", "b001", "exe", "brun"), true},
+	}
+
+	for _, tt := range tests {
+		if got := unstableExecPath(tt.path) != ""; got != tt.unstable {
+			t.Errorf("unstableExecPath(%q) unstable = %v, want %v", tt.path, got, tt.unstable)
+		}
+	}
+}
+
+func TestJournalctlArgs(t *testing.T) {
+	if got := strings.Join(journalctlArgs(true, false), " "); got != "-u brun.service" {
+		t.Errorf("journalctlArgs(root) = %q", got)
+	}
+	if got := strings.Join(journalctlArgs(false, true), " "); got != "--user -u brun.service -f" {
+		t.Errorf("journalctlArgs(user, follow) = %q", got)
+	}
+}
+
+func TestOneshotPollingWarning(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `config:
+  state_location: state.yaml
+units:
+  - boot:
+      name: on-boot
+  - cron:
+      name: nightly
+      schedule: "0 2 * * *"
+  - git:
+      name: passive-repo
+      repository: /src/repo
+      branch: main
+  - git:
+      name: polled-repo
+      repository: /src/repo
+      branch: main
+      poll: 5m
+  - run:
+      name: build
+      script: make
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	triggers := config.PollingTriggers()
+	if strings.Join(triggers, ",") != "nightly,polled-repo" {
+		t.Errorf("Expected nightly and polled-repo, got %v", triggers)
+	}
+
+	warning := oneshotPollingWarning(triggers, false)
+	if !strings.Contains(warning, "nightly, polled-repo") || !strings.Contains(warning, "-daemon") ||
+		!strings.Contains(warning, "-use-systemd-timers") {
+		t.Errorf("Unexpected warning: %q", warning)
+	}
+	if warning := oneshotPollingWarning(triggers, true); strings.Contains(warning, "-use-systemd-timers") {
+		t.Errorf("Expected no timer hint when installing timers, got %q", warning)
+	}
+	if warning := oneshotPollingWarning(nil, false); warning != "" {
+		t.Errorf("Expected no warning without polling triggers, got %q", warning)
+	}
+}
+
+func TestGenerateServiceFile_SkipTriggers(t *testing.T) {
+	content := generateSystemServiceFile("/usr/local/bin/brun", true, []string{"nightly", "weekly"})
+	want := "ExecStart=/usr/local/bin/brun run /etc/brun/config.yaml -daemon -skip-trigger nightly -skip-trigger weekly\n"
+	if !strings.Contains(content, want) {
+		t.Errorf("Expected %q in service file, got:\n%s", want, content)
+	}
+}
@@ -0,0 +1,197 @@
+package brun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"time"
+)
+
+// journalTimeFormat is the timestamp format accepted by journalctl --since
+const journalTimeFormat = "2006-01-02 15:04:05"
+
+// JournalTrigger is a trigger unit that fires on new systemd journal entries.
+// It runs journalctl, so brun has no build dependency on systemd.
+type JournalTrigger struct {
+	name        string
+	systemdUnit string
+	priority    string
+	matches     []string
+	pattern     *regexp.Regexp
+	command     string // journalctl binary, overridable for tests
+	state       *State
+	lastMatch   string
+	onSuccess   []string
+	onFailure   []string
+	always      []string
+}
+
+// JournalConfig represents the configuration for a journal trigger
+type JournalConfig struct {
+	UnitConfig  `yaml:",inline"`
+	SystemdUnit string   `yaml:"systemd_unit,omitempty"`
+	Priority    string   `yaml:"priority,omitempty"`
+	Matches     []string `yaml:"matches,omitempty"`
+	Pattern     string   `yaml:"pattern,omitempty"`
+}
+
+// NewJournalTrigger creates a new journal trigger unit. systemdUnit,
+// priority, and matches are passed to journalctl as -u, -p, and field
+// matches (e.g., "_COMM=sshd"). pattern is an optional regular expression
+// matched against the message of each entry.
+func NewJournalTrigger(name, systemdUnit, priority string, matches []string, pattern string, state *State, onSuccess, onFailure, always []string) (*JournalTrigger, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+
+	return &JournalTrigger{
+		name:        name,
+		systemdUnit: systemdUnit,
+		priority:    priority,
+		matches:     matches,
+		pattern:     re,
+		command:     "journalctl",
+		state:       state,
+		onSuccess:   onSuccess,
+		onFailure:   onFailure,
+		always:      always,
+	}, nil
+}
+
+// Name returns the name of the unit
+func (j *JournalTrigger) Name() string {
+	return j.name
+}
+
+// Type returns the unit type
+func (j *JournalTrigger) Type() string {
+	return "trigger.journal"
+}
+
+// journalEntry is the subset of a journalctl JSON entry used by the trigger
+type journalEntry struct {
+	Cursor  string          `json:"__CURSOR"`
+	Message json.RawMessage `json:"MESSAGE"`
+}
+
+// message returns the entry message. journalctl encodes non-UTF-8 messages
+// as an array of bytes.
+func (e journalEntry) message() string {
+	var s string
+	if err := json.Unmarshal(e.Message, &s); err == nil {
+		return s
+	}
+
+	var ints []int
+	if err := json.Unmarshal(e.Message, &ints); err != nil {
+		return ""
+	}
+	b := make([]byte, len(ints))
+	for i, v := range ints {
+		b[i] = byte(v)
+	}
+	return string(b)
+}
+
+// args returns the journalctl arguments for reading entries after the given
+// cursor, or since the given time if there is no cursor yet
+func (j *JournalTrigger) args(cursor, since string) []string {
+	args := []string{"--output=json", "--no-pager", "--quiet"}
+	if cursor != "" {
+		args = append(args, "--after-cursor="+cursor)
+	} else {
+		args = append(args, "--since="+since)
+	}
+	if j.systemdUnit != "" {
+		args = append(args, "--unit="+j.systemdUnit)
+	}
+	if j.priority != "" {
+		args = append(args, "--priority="+j.priority)
+	}
+	return append(args, j.matches...)
+}
+
+// Check returns true if matching entries were added to the journal since the
+// last check. The position is tracked with a journal cursor in state. On the
+// first check, only entries logged from then on are considered.
+func (j *JournalTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	// Get last position from state (state is already loaded at startup)
+	cursor, _ := j.state.GetString(j.name, "cursor")
+	since, ok := j.state.GetString(j.name, "since")
+	if cursor == "" && !ok {
+		// Start from now rather than firing on the whole journal history
+		since = time.Now().Format(journalTimeFormat)
+		if err := j.state.SetString(j.name, "since", since); err != nil {
+			return false, fmt.Errorf("failed to save journal position: %w", err)
+		}
+		return false, nil
+	}
+
+	cmd := exec.CommandContext(ctx, j.command, j.args(cursor, since)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to read journal: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	matched := false
+	lastCursor := cursor
+	// The output is already in memory, so split it into lines directly rather
+	// than with a bufio.Scanner, whose line limit would silently end the scan
+	// at a large entry and leave the cursor stuck before it
+	for line := range bytes.Lines(output) {
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue // Skip anything that isn't an entry
+		}
+		if entry.Cursor != "" {
+			lastCursor = entry.Cursor
+		}
+
+		message := entry.message()
+		if !matched && (j.pattern == nil || j.pattern.MatchString(message)) {
+			matched = true
+			j.lastMatch = message
+		}
+	}
+
+	if lastCursor != cursor {
+		if err := j.state.SetString(j.name, "cursor", lastCursor); err != nil {
+			return false, fmt.Errorf("failed to save journal position: %w", err)
+		}
+	}
+
+	return matched, nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (j *JournalTrigger) OnSuccess() []string {
+	return j.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (j *JournalTrigger) OnFailure() []string {
+	return j.onFailure
+}
+
+// Always returns the list of units to trigger regardless of success/failure
+func (j *JournalTrigger) Always() []string {
+	return j.always
+}
+
+// Run executes the trigger unit
+// Note: Check() has already been called by the orchestrator before Run() is invoked
+func (j *JournalTrigger) Run(ctx context.Context) error {
+	log.Printf("Journal trigger '%s' activated (%s)", j.name, j.lastMatch)
+	return nil
+}
@@ -0,0 +1,131 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeJournalctl creates a script that records its arguments and prints the
+// contents of the returned output file
+func fakeJournalctl(t *testing.T) (command, argsFile, outputFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	command = filepath.Join(dir, "journalctl")
+	argsFile = filepath.Join(dir, "args")
+	outputFile = filepath.Join(dir, "output")
+
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat " + outputFile + "\n"
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake journalctl: %v", err)
+	}
+	if err := os.WriteFile(outputFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	return command, argsFile, outputFile
+}
+
+func TestJournalTrigger_Check(t *testing.T) {
+	command, argsFile, outputFile := fakeJournalctl(t)
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+
+	trigger, err := NewJournalTrigger("ssh-failures", "sshd.service", "warning", []string{"_COMM=sshd"}, "Failed password", state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewJournalTrigger failed: %v", err)
+	}
+	trigger.command = command
+	ctx := context.Background()
+
+	check := func(output string, want bool, msg string) {
+		t.Helper()
+		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+			t.Fatalf("Failed to write output: %v", err)
+		}
+		got, err := trigger.Check(ctx, CheckModePolling)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: Check() = %v, want %v", msg, got, want)
+		}
+	}
+
+	// The first check only records the starting point
+	check(`{"__CURSOR":"c1","MESSAGE":"Failed password for root"}`, false, "first check")
+	if _, err := os.Stat(argsFile); err == nil {
+		t.Error("journalctl should not run on the first check")
+	}
+
+	check(`{"__CURSOR":"c1","MESSAGE":"Accepted publickey for admin"}`+"\n", false, "no match")
+	args, _ := os.ReadFile(argsFile)
+	for _, want := range []string{"--output=json", "--since=", "--unit=sshd.service", "--priority=warning", "_COMM=sshd"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("journalctl args %q missing %q", args, want)
+		}
+	}
+
+	check(`{"__CURSOR":"c2","MESSAGE":"Accepted publickey for admin"}
+{"__CURSOR":"c3","MESSAGE":[70,97,105,108,101,100,32,112,97,115,115,119,111,114,100]}
+`, true, "match")
+	if trigger.lastMatch != "Failed password" {
+		t.Errorf("lastMatch = %q, want 'Failed password'", trigger.lastMatch)
+	}
+
+	check("", false, "no new entries")
+	args, _ = os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "--after-cursor=c3") {
+		t.Errorf("Expected journalctl to resume after c3, got %q", args)
+	}
+}
+
+func TestJournalTrigger_LargeEntry(t *testing.T) {
+	command, argsFile, outputFile := fakeJournalctl(t)
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger, err := NewJournalTrigger("journal", "", "", nil, "panic", state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewJournalTrigger failed: %v", err)
+	}
+	trigger.command = command
+	if err := state.SetString("journal", "cursor", "c1"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	// An entry larger than a typical line buffer doesn't hide the ones after it
+	output := `{"__CURSOR":"c2","MESSAGE":"` + strings.Repeat("x", 2*1024*1024) + `"}
+{"__CURSOR":"c3","MESSAGE":"kernel panic"}
+`
+	if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	if got, err := trigger.Check(context.Background(), CheckModePolling); err != nil || !got {
+		t.Errorf("Check() = %v, %v, want true", got, err)
+	}
+
+	if err := os.WriteFile(outputFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	if _, err := trigger.Check(context.Background(), CheckModePolling); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if args, _ := os.ReadFile(argsFile); !strings.Contains(string(args), "--after-cursor=c3") {
+		t.Errorf("Expected journalctl to resume after c3, got %q", args)
+	}
+}
+
+func TestJournalTrigger_CommandError(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger, err := NewJournalTrigger("journal", "", "", nil, "", state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewJournalTrigger failed: %v", err)
+	}
+	trigger.command = filepath.Join(t.TempDir(), "missing-journalctl")
+
+	if err := state.SetString("journal", "cursor", "c1"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if _, err := trigger.Check(context.Background(), CheckModePolling); err == nil {
+		t.Error("Expected error when journalctl can't run")
+	}
+}
@@ -13,6 +13,7 @@ import (
 type LogConfig struct {
 	UnitConfig `yaml:",inline"`
 	File       string `yaml:"file"`
+	Mode       string `yaml:"mode,omitempty"`
 }
 
 // LogUnit writes log messages to a file
@@ -21,6 +22,8 @@ type LogUnit struct {
 	file           string
 	output         string // Output from the triggering unit
 	triggeringUnit string // Name of the unit that triggered this log
+	triggerError   error  // Error from the triggering unit (if any)
+	mode           os.FileMode
 	onSuccess      []string
 	onFailure      []string
 	always         []string
@@ -47,6 +50,12 @@ func (l *LogUnit) Type() string {
 	return "log"
 }
 
+// SetMode sets the permissions of the log file, applied regardless of the
+// umask. A mode of 0 creates the file as 0644 less the umask.
+func (l *LogUnit) SetMode(mode os.FileMode) {
+	l.mode = mode
+}
+
 // SetOutput sets the output data from the triggering unit
 func (l *LogUnit) SetOutput(output string) {
 	l.output = output
@@ -57,6 +66,11 @@ func (l *LogUnit) SetTriggeringUnit(unitName string) {
 	l.triggeringUnit = unitName
 }
 
+// SetTriggerError sets the error from the triggering unit
+func (l *LogUnit) SetTriggerError(err error) {
+	l.triggerError = err
+}
+
 // Run executes the log unit
 func (l *LogUnit) Run(ctx context.Context) error {
 	log.Printf("Running log unit '%s'", l.name)
@@ -74,6 +88,12 @@ func (l *LogUnit) Run(ctx context.Context) error {
 	}
 	defer f.Close()
 
+	if l.mode != 0 {
+		if err := f.Chmod(l.mode); err != nil {
+			return fmt.Errorf("failed to set log file mode: %w", err)
+		}
+	}
+
 	// Write log entry
 	var logEntry string
 	unitName := l.triggeringUnit
@@ -84,13 +104,22 @@ func (l *LogUnit) Run(ctx context.Context) error {
 	timestamp := time.Now().Format(time.RFC3339)
 
 	if l.output != "" {
-		// Write the captured output from the triggering unit
-		logEntry = fmt.Sprintf("=== Unit '%s' - %s ===\n%s\n", unitName, timestamp, l.output)
+		logEntry = fmt.Sprintf("=== Unit '%s' - %s ===\n", unitName, timestamp)
 	} else {
-		// Fallback if no output was captured
+		// Note that no output was captured
 		logEntry = fmt.Sprintf("=== Unit '%s' - %s (no output) ===\n", unitName, timestamp)
 	}
 
+	// Include the failure reason right after the header
+	if l.triggerError != nil {
+		logEntry += fmt.Sprintf("Error: %v\n", l.triggerError)
+	}
+
+	if l.output != "" {
+		// Write the captured output from the triggering unit
+		logEntry += l.output + "\n"
+	}
+
 	if _, err := f.WriteString(logEntry); err != nil {
 		return fmt.Errorf("failed to write to log file: %w", err)
 	}
@@ -2,6 +2,7 @@ package brun
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -213,3 +214,55 @@ func TestCreateUnits_LogMissingFile(t *testing.T) {
 		t.Error("Expected error for missing file")
 	}
 }
+
+func TestLogUnit_TriggerError(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+
+	unit := NewLogUnit("test-log", logFile, nil, nil, nil)
+	unit.SetTriggeringUnit("build")
+	unit.SetOutput("compiling\nerror: boom")
+	unit.SetTriggerError(errors.New("script exited with code 2"))
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "=== Unit 'build'") ||
+		lines[1] != "Error: script exited with code 2" || lines[2] != "compiling" {
+		t.Errorf("Unexpected log entry: %q", string(content))
+	}
+
+	// Successful runs don't include an error line
+	unit.SetTriggerError(nil)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	content, _ = os.ReadFile(logFile)
+	if strings.Count(string(content), "Error:") != 1 {
+		t.Errorf("Expected a single error line, got: %q", string(content))
+	}
+}
+
+func TestLogUnit_Mode(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	unit := NewLogUnit("test-log", logFile, nil, nil, nil)
+	unit.SetMode(0o640)
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	info, err := os.Stat(logFile)
+	if err != nil {
+		t.Fatalf("Failed to stat log file: %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("Expected mode 0640, got %04o", info.Mode().Perm())
+	}
+}
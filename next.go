@@ -0,0 +1,113 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TriggerForecast describes what a trigger would do next
+type TriggerForecast struct {
+	Unit string
+	Type string // Unit type without the "trigger." prefix, e.g. "cron"
+	// Next is when a scheduled trigger fires next, zero for other triggers
+	Next time.Time
+	// Last is the last scheduled time a scheduled trigger handled, zero if
+	// none is recorded
+	Last time.Time
+	// Fire is true if a polled trigger would fire if checked now
+	Fire bool
+	// Note explains why a trigger wasn't checked, e.g. for git triggers
+	Note string
+	Err  error
+}
+
+// Next reports, for each trigger, when it fires next if it's scheduled, or
+// whether it would fire if checked at now. Triggers are checked against a
+// copy of the state file, so the state isn't modified. Git triggers aren't
+// checked since checking fetches the repository.
+func (c *Config) Next(ctx context.Context, now time.Time) ([]TriggerForecast, error) {
+	if c.ConfigBlock.StateLocation == "" {
+		return nil, fmt.Errorf("config.state_location is required in config file")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "brun-next-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	preview := *c
+	preview.ConfigBlock.StateLocation = filepath.Join(tmpDir, "state.yaml")
+	preview.ConfigBlock.PruneState = false
+
+	data, err := os.ReadFile(c.ConfigBlock.StateLocation)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err == nil {
+		if err := os.WriteFile(preview.ConfigBlock.StateLocation, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to copy state file: %w", err)
+		}
+	}
+
+	units, err := preview.CreateUnits()
+	if err != nil {
+		return nil, err
+	}
+	cooldowns, err := preview.UnitCooldowns()
+	if err != nil {
+		return nil, err
+	}
+
+	state := preview.State()
+	o := NewOrchestrator(units)
+	o.SetCooldowns(cooldowns, state)
+	o.SetEdgeTriggers(preview.EdgeTriggerUnits(), state)
+
+	var forecasts []TriggerForecast
+	for _, unit := range units {
+		trigger, ok := unit.(TriggerUnit)
+		if !ok {
+			continue
+		}
+
+		f := TriggerForecast{
+			Unit: trigger.Name(),
+			Type: strings.TrimPrefix(trigger.Type(), "trigger."),
+		}
+
+		switch t := trigger.(type) {
+		case scheduledTrigger:
+			f.Next = t.NextRun(now)
+			if last, ok := state.GetString(f.Unit, "last_execution"); ok {
+				f.Last, _ = time.Parse(time.RFC3339, last)
+			}
+		case *GitTrigger:
+			f.Note = "not checked, checking fetches the repository"
+		case *StartTrigger:
+			f.Note = "fires each time brun starts"
+		case *DaemonStartTrigger:
+			f.Note = "fires each time the daemon starts"
+		case *DigestUnit:
+			events, _ := t.events()
+			f.Note = fmt.Sprintf("%d events collected, sent when triggered by %s", len(events), strings.Join(t.flushOn, ", "))
+		default:
+			if until, ok := o.cooldownUntil(f.Unit); ok && now.Before(until) {
+				f.Note = "in cooldown until " + until.Format(time.RFC3339)
+				break
+			}
+			f.Fire, f.Err = checkUnit(ctx, trigger, CheckModePolling)
+			if f.Err == nil && o.edgeTriggers[f.Unit] {
+				f.Fire = o.edgeTransition(f.Unit, f.Fire)
+			}
+		}
+
+		forecasts = append(forecasts, f)
+	}
+
+	return forecasts, nil
+}
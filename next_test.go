@@ -0,0 +1,74 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_Next(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "src.txt"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	stateFile := filepath.Join(tmpDir, "state.yaml")
+	stateData := []byte("nightly:\n  last_execution: \"2025-01-01T00:00:00Z\"\n")
+	if err := os.WriteFile(stateFile, stateData, 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	configData := `config:
+  state_location: state.yaml
+units:
+  - cron:
+      name: nightly
+      schedule: "0 0 * * *"
+  - file:
+      name: watch
+      pattern: "*.txt"
+  - start:
+      name: start
+`
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)
+	forecasts, err := config.Next(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if len(forecasts) != 3 {
+		t.Fatalf("Expected 3 forecasts, got %+v", forecasts)
+	}
+
+	nightly := forecasts[0]
+	if nightly.Type != "cron" || !nightly.Next.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Unexpected cron forecast: %+v", nightly)
+	}
+	if !nightly.Last.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected last scheduled run from state, got %v", nightly.Last)
+	}
+
+	// The file trigger has no baseline in state, so it would fire
+	if watch := forecasts[1]; watch.Type != "file" || !watch.Fire || watch.Err != nil {
+		t.Errorf("Unexpected file forecast: %+v", watch)
+	}
+	if start := forecasts[2]; start.Note == "" {
+		t.Errorf("Expected a note for the start trigger, got %+v", start)
+	}
+
+	// The state file is left alone
+	data, err := os.ReadFile(stateFile)
+	if err != nil || string(data) != string(stateData) {
+		t.Errorf("State file changed: %q, %v", data, err)
+	}
+}
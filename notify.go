@@ -0,0 +1,311 @@
+package brun
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// unsafeFilenameRegex matches characters that are replaced in stored output filenames
+var unsafeFilenameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// storeOutput writes the full output of a triggering unit to a file in dir and
+// returns the URL built from urlTemplate. The template can reference the file
+// name as {{.Filename}}.
+func storeOutput(dir, urlTemplate, unitName, output string) (string, error) {
+	tmpl, err := template.New("output_url").Parse(urlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid output URL template: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	filename := fmt.Sprintf("%s-%s.log",
+		unsafeFilenameRegex.ReplaceAllString(unitName, "_"),
+		time.Now().Format("20060102-150405"))
+
+	if err := os.WriteFile(filepath.Join(dir, filename), []byte(output), 0644); err != nil {
+		return "", fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	var url strings.Builder
+	if err := tmpl.Execute(&url, struct{ Filename string }{filename}); err != nil {
+		return "", fmt.Errorf("failed to build output URL: %w", err)
+	}
+
+	return url.String(), nil
+}
+
+// notificationData is the data available to notification title and subject
+// templates
+type notificationData struct {
+	Unit  string            // Name of the triggering unit
+	Count int               // Count from a count unit earlier in the chain, 0 if none
+	Env   map[string]string // Variables published earlier in the chain, e.g., BRUN_GIT_COMMIT
+	// Version of brun sending the notification
+	BrunVersion string
+}
+
+// renderNotificationTemplate expands references like {{.Count}} in text. If
+// the template can't be rendered, text is returned unchanged.
+func renderNotificationTemplate(text string, data notificationData) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return text
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return text
+	}
+	return out.String()
+}
+
+// validateNotificationTemplate returns an error if text isn't a valid
+// notification template
+func validateNotificationTemplate(text string) error {
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(io.Discard, notificationData{})
+}
+
+// QuietHoursConfig represents config.quiet_hours, a daily window during which
+// non-critical notifications are held back
+type QuietHoursConfig struct {
+	Start    string `yaml:"start"`
+	End      string `yaml:"end"`
+	Timezone string `yaml:"timezone,omitempty"`
+	// Queue keeps notifications in the state file and sends them when quiet
+	// hours end instead of dropping them
+	Queue bool `yaml:"queue,omitempty"`
+}
+
+// QuietHours is a daily window, which may span midnight, during which
+// notification units hold back non-critical notifications
+type QuietHours struct {
+	start    int // minutes after midnight
+	end      int
+	location *time.Location
+	queue    bool
+}
+
+// NewQuietHours creates quiet hours from start and end times like "22:00" in
+// timezone (local time if empty). If queue is true, held back notifications
+// are queued and sent when quiet hours end; otherwise they are dropped.
+func NewQuietHours(start, end, timezone string, queue bool) (*QuietHours, error) {
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start '%s' (expected HH:MM)", start)
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end '%s' (expected HH:MM)", end)
+	}
+
+	location := time.Local
+	if timezone != "" {
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+		}
+	}
+
+	q := &QuietHours{
+		start:    startTime.Hour()*60 + startTime.Minute(),
+		end:      endTime.Hour()*60 + endTime.Minute(),
+		location: location,
+		queue:    queue,
+	}
+	if q.start == q.end {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	return q, nil
+}
+
+// Active returns true if t is within quiet hours
+func (q *QuietHours) Active(t time.Time) bool {
+	t = t.In(q.location)
+	minute := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	// The window spans midnight
+	return minute >= q.start || minute < q.end
+}
+
+// queuedNotification is a notification held back during quiet hours
+type queuedNotification struct {
+	Title string
+	Body  string
+}
+
+// hold returns true if a notification from unitName must not be sent now
+// because of quiet hours. The notification is queued in state if configured.
+func (q *QuietHours) hold(state *State, unitName, title, body string) (bool, error) {
+	if q == nil || !q.Active(time.Now()) {
+		return false, nil
+	}
+
+	if !q.queue {
+		log.Printf("Quiet hours: dropped notification '%s' from unit '%s'", title, unitName)
+		return true, nil
+	}
+
+	queued, _ := state.Get(unitName, "quiet_queue")
+	list, _ := queued.([]any)
+	list = append(list, map[string]any{"title": title, "body": body})
+	if err := state.Set(unitName, "quiet_queue", list); err != nil {
+		return true, fmt.Errorf("failed to queue notification: %w", err)
+	}
+	log.Printf("Quiet hours: queued notification '%s' from unit '%s'", title, unitName)
+	return true, nil
+}
+
+// takeQueued removes and returns the notifications unitName queued during
+// quiet hours, or nil if quiet hours are still active
+func (q *QuietHours) takeQueued(state *State, unitName string) ([]queuedNotification, error) {
+	if q == nil || !q.queue || q.Active(time.Now()) {
+		return nil, nil
+	}
+
+	queued, ok := state.Get(unitName, "quiet_queue")
+	if !ok {
+		return nil, nil
+	}
+
+	var notifications []queuedNotification
+	list, _ := queued.([]any)
+	for _, item := range list {
+		entry, _ := item.(map[string]any)
+		title, _ := entry["title"].(string)
+		body, _ := entry["body"].(string)
+		notifications = append(notifications, queuedNotification{Title: title, Body: body})
+	}
+
+	if err := state.DeleteKey(unitName, "quiet_queue"); err != nil {
+		return nil, fmt.Errorf("failed to clear notification queue: %w", err)
+	}
+	return notifications, nil
+}
+
+// requeue puts notifications that couldn't be sent back in unitName's queue
+func requeue(state *State, unitName string, notifications []queuedNotification) error {
+	list := make([]any, len(notifications))
+	for i, n := range notifications {
+		list[i] = map[string]any{"title": n.Title, "body": n.Body}
+	}
+	return state.Set(unitName, "quiet_queue", list)
+}
+
+// maxOutbox is the number of failed notifications a unit keeps for retry.
+// The oldest are dropped first.
+const maxOutbox = 100
+
+// Outbox keeps notifications that failed to send in state and retries them
+// on later cycles until they are delivered or older than the TTL
+type Outbox struct {
+	ttl   time.Duration
+	state *State
+	now   func() time.Time
+}
+
+// NewOutbox creates an outbox that retries failed notifications for up to
+// ttl, keeping them in state
+func NewOutbox(ttl time.Duration, state *State) *Outbox {
+	return &Outbox{ttl: ttl, state: state, now: time.Now}
+}
+
+// add stores a notification from unitName that failed to send
+func (o *Outbox) add(unitName, title, body string) error {
+	if o == nil {
+		return nil
+	}
+
+	queued, _ := o.state.Get(unitName, "outbox")
+	list, _ := queued.([]any)
+	list = append(list, map[string]any{
+		"title":  title,
+		"body":   body,
+		"failed": o.now().Format(time.RFC3339),
+	})
+	if len(list) > maxOutbox {
+		log.Printf("Outbox of unit '%s' full, dropped %d oldest notifications", unitName, len(list)-maxOutbox)
+		list = list[len(list)-maxOutbox:]
+	}
+	if err := o.state.Set(unitName, "outbox", list); err != nil {
+		return fmt.Errorf("failed to queue notification for retry: %w", err)
+	}
+	return nil
+}
+
+// retry sends unitName's failed notifications in order with send, dropping
+// expired ones. It stops at the first failure, since the channel is likely
+// still down, and keeps the rest for the next retry.
+func (o *Outbox) retry(unitName string, send func(title, body string) error) error {
+	if o == nil {
+		return nil
+	}
+
+	queued, ok := o.state.Get(unitName, "outbox")
+	if !ok {
+		return nil
+	}
+	list, _ := queued.([]any)
+
+	sent, expired := 0, 0
+	var sendErr error
+	var remaining []any
+	for i, item := range list {
+		entry, _ := item.(map[string]any)
+		title, _ := entry["title"].(string)
+		body, _ := entry["body"].(string)
+		failedStr, _ := entry["failed"].(string)
+
+		if failed, err := time.Parse(time.RFC3339, failedStr); err != nil || o.now().Sub(failed) > o.ttl {
+			expired++
+			continue
+		}
+		if err := send(title, body); err != nil {
+			sendErr = err
+			remaining = list[i:]
+			break
+		}
+		sent++
+	}
+
+	if expired > 0 {
+		log.Printf("Outbox of unit '%s' dropped %d notifications older than %s", unitName, expired, o.ttl)
+	}
+	if sent > 0 {
+		log.Printf("Outbox of unit '%s' delivered %d notifications that failed before", unitName, sent)
+	}
+
+	var err error
+	if len(remaining) == 0 {
+		err = o.state.DeleteKey(unitName, "outbox")
+	} else if sent > 0 || expired > 0 {
+		err = o.state.Set(unitName, "outbox", remaining)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update outbox: %w", err)
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("failed to retry notification, %d still queued: %w", len(remaining), sendErr)
+	}
+	return nil
+}
@@ -6,40 +6,146 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 )
 
 // NtfyConfig represents the configuration for an Ntfy unit
 type NtfyConfig struct {
-	UnitConfig    `yaml:",inline"`
-	Topic         string `yaml:"topic"`
-	Server        string `yaml:"server,omitempty"`
-	TitlePrefix   string `yaml:"title_prefix,omitempty"`
-	Priority      string `yaml:"priority,omitempty"`
-	Tags          string `yaml:"tags,omitempty"`
-	IncludeOutput *bool  `yaml:"include_output,omitempty"`
-	LimitLines    int    `yaml:"limit_lines,omitempty"`
+	UnitConfig      `yaml:",inline"`
+	Topic           string       `yaml:"topic"`
+	Server          string       `yaml:"server,omitempty"`
+	TitlePrefix     string       `yaml:"title_prefix,omitempty"`
+	Priority        string       `yaml:"priority,omitempty"`
+	Tags            string       `yaml:"tags,omitempty"`
+	Delay           string       `yaml:"delay,omitempty"`
+	IncludeOutput   *bool        `yaml:"include_output,omitempty"`
+	LimitLines      int          `yaml:"limit_lines,omitempty"`
+	OutputDir       string       `yaml:"output_dir,omitempty"`
+	OutputURL       string       `yaml:"output_url_template,omitempty"`
+	Timeout         string       `yaml:"timeout,omitempty"`
+	HTTPTimeout     string       `yaml:"http_timeout,omitempty"`
+	Markdown        bool         `yaml:"markdown,omitempty"`
+	CollapseOutput  bool         `yaml:"collapse_output,omitempty"`
+	Actions         []NtfyAction `yaml:"actions,omitempty"`
+	StderrOnFailure bool         `yaml:"stderr_on_failure,omitempty"`
+	Critical        bool         `yaml:"critical,omitempty"`
+	RetryTTL        string       `yaml:"retry_ttl,omitempty"`
+}
+
+// NtfyAction is an action button shown on a notification
+// (see https://docs.ntfy.sh/publish/#action-buttons)
+type NtfyAction struct {
+	Action  string            `yaml:"action"` // view, http, or broadcast
+	Label   string            `yaml:"label"`
+	URL     string            `yaml:"url,omitempty"`
+	Method  string            `yaml:"method,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Clear   bool              `yaml:"clear,omitempty"`
+}
+
+// maxNtfyActions is the number of actions ntfy allows per notification
+const maxNtfyActions = 3
+
+// validate checks that the action has the fields ntfy requires
+func (a NtfyAction) validate() error {
+	switch a.Action {
+	case "view", "http":
+		if a.URL == "" {
+			return fmt.Errorf("%s action '%s' requires url", a.Action, a.Label)
+		}
+	case "broadcast":
+	default:
+		return fmt.Errorf("invalid action '%s' (expected view, http, or broadcast)", a.Action)
+	}
+	if a.Label == "" {
+		return fmt.Errorf("%s action requires label", a.Action)
+	}
+	return nil
+}
+
+// header returns the action in ntfy's simple header format, e.g.
+// "http, Rebuild, https://example.com/build, method=PUT"
+func (a NtfyAction) header() string {
+	parts := []string{a.Action, ntfyQuote(a.Label)}
+	if a.URL != "" {
+		parts = append(parts, ntfyQuote(a.URL))
+	}
+	if a.Method != "" {
+		parts = append(parts, "method="+ntfyQuote(a.Method))
+	}
+
+	// Sort headers for consistent output
+	names := make([]string, 0, len(a.Headers))
+	for name := range a.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, "headers."+name+"="+ntfyQuote(a.Headers[name]))
+	}
+
+	if a.Body != "" {
+		parts = append(parts, "body="+ntfyQuote(a.Body))
+	}
+	if a.Clear {
+		parts = append(parts, "clear=true")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ntfyQuote quotes a value for the ntfy action header if it contains
+// separator characters
+func ntfyQuote(value string) string {
+	if !strings.ContainsAny(value, ",;=\"'") {
+		return value
+	}
+	if strings.Contains(value, `"`) {
+		return "'" + value + "'"
+	}
+	return `"` + value + `"`
 }
 
 // NtfyUnit sends notifications via ntfy.sh
 type NtfyUnit struct {
-	name           string
-	topic          string
-	server         string
-	titlePrefix    string
-	priority       string
-	tags           string
-	includeOutput  bool
-	limitLines     int
-	output         string
-	triggeringUnit string
-	triggerError   error
-	onSuccess      []string
-	onFailure      []string
-	always         []string
+	name            string
+	topic           string
+	server          string
+	titlePrefix     string
+	priority        string
+	tags            string
+	delay           string
+	includeOutput   bool
+	limitLines      int
+	outputDir       string
+	outputURL       string
+	timeout         time.Duration
+	httpTimeout     time.Duration
+	markdown        bool
+	collapseOutput  bool
+	actions         []NtfyAction
+	output          string
+	stderr          string
+	stderrOnFailure bool
+	count           int
+	env             map[string]string
+	quietHours      *QuietHours // nil if quiet hours aren't configured
+	quietState      *State
+	critical        bool
+	outbox          *Outbox // nil if failed notifications aren't retried
+	triggeringUnit  string
+	triggerError    error
+	onSuccess       []string
+	onFailure       []string
+	always          []string
 }
 
+// defaultNtfyHTTPTimeout is the default timeout of the request to the ntfy
+// server
+const defaultNtfyHTTPTimeout = 30 * time.Second
+
 // NewNtfyUnit creates a new Ntfy unit
 func NewNtfyUnit(name, topic, server, titlePrefix, priority, tags string,
 	includeOutput bool, limitLines int,
@@ -53,6 +159,7 @@ func NewNtfyUnit(name, topic, server, titlePrefix, priority, tags string,
 		tags:          tags,
 		includeOutput: includeOutput,
 		limitLines:    limitLines,
+		httpTimeout:   defaultNtfyHTTPTimeout,
 		onSuccess:     onSuccess,
 		onFailure:     onFailure,
 		always:        always,
@@ -69,11 +176,131 @@ func (n *NtfyUnit) Type() string {
 	return "ntfy"
 }
 
+// SetOutputLink configures storing the full output in dir and linking to it
+// with a URL built from urlTemplate when the output exceeds limit_lines
+func (n *NtfyUnit) SetOutputLink(dir, urlTemplate string) {
+	n.outputDir = dir
+	n.outputURL = urlTemplate
+}
+
+// SetMarkdown enables markdown formatting of the notification body
+func (n *NtfyUnit) SetMarkdown(markdown bool) {
+	n.markdown = markdown
+}
+
+// SetCollapseOutput puts the output in a collapsed <details> section below the
+// summary. It only applies to markdown notifications; plain text has no
+// collapsible construct.
+func (n *NtfyUnit) SetCollapseOutput(collapse bool) {
+	n.collapseOutput = collapse
+}
+
+// SetActions sets the action buttons shown on the notification
+func (n *NtfyUnit) SetActions(actions []NtfyAction) {
+	n.actions = actions
+}
+
+// SetTimeout limits how long sending the notification may take (0 = no limit)
+func (n *NtfyUnit) SetTimeout(timeout time.Duration) {
+	n.timeout = timeout
+}
+
+// SetDelay schedules the notification for later delivery by the server. delay
+// is passed in the Delay header, e.g., "30m", "1h", or "tomorrow, 10am".
+func (n *NtfyUnit) SetDelay(delay string) {
+	n.delay = delay
+}
+
+// SetHTTPTimeout sets the timeout of the HTTP request to the ntfy server.
+// A timeout of 0 keeps the default of 30 seconds.
+func (n *NtfyUnit) SetHTTPTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		n.httpTimeout = timeout
+	}
+}
+
+// SetQuietHours holds back notifications during quiet hours. Queued
+// notifications are kept in state.
+func (n *NtfyUnit) SetQuietHours(quietHours *QuietHours, state *State) {
+	n.quietHours = quietHours
+	n.quietState = state
+}
+
+// SetCritical makes notifications bypass quiet hours
+func (n *NtfyUnit) SetCritical(critical bool) {
+	n.critical = critical
+}
+
+// SetOutbox keeps notifications that fail to send in outbox and retries
+// them on later cycles
+func (n *NtfyUnit) SetOutbox(outbox *Outbox) {
+	n.outbox = outbox
+}
+
+// FlushQueued retries notifications that failed to send and sends the
+// notifications queued during quiet hours once they have ended
+func (n *NtfyUnit) FlushQueued(ctx context.Context) error {
+	err := n.outbox.retry(n.name, func(title, body string) error {
+		return n.sendNotification(ctx, title, body)
+	})
+	if err != nil {
+		return err
+	}
+
+	queued, err := n.quietHours.takeQueued(n.quietState, n.name)
+	if err != nil {
+		return err
+	}
+	for i, q := range queued {
+		if err := n.sendNotification(ctx, q.Title, q.Body); err != nil {
+			if err := requeue(n.quietState, n.name, queued[i:]); err != nil {
+				log.Printf("Ntfy unit '%s': failed to requeue notifications: %v", n.name, err)
+			}
+			return fmt.Errorf("failed to send queued ntfy notification: %w", err)
+		}
+	}
+	if len(queued) > 0 {
+		log.Printf("Ntfy unit '%s' sent %d notifications queued during quiet hours", n.name, len(queued))
+	}
+	return nil
+}
+
 // SetOutput sets the output data from the triggering unit
 func (n *NtfyUnit) SetOutput(output string) {
 	n.output = output
 }
 
+// SetEnv sets variables published earlier in the chain, available to the
+// prefix template as {{.Env.NAME}}
+func (n *NtfyUnit) SetEnv(env map[string]string) {
+	n.env = env
+}
+
+// SetCount sets the count from a count unit earlier in the chain, available
+// to the title prefix as {{.Count}}
+func (n *NtfyUnit) SetCount(count int) {
+	n.count = count
+}
+
+// SetStderr sets the stderr output from the triggering unit
+func (n *NtfyUnit) SetStderr(stderr string) {
+	n.stderr = stderr
+}
+
+// SetStderrOnFailure includes only the triggering unit's stderr instead of
+// its full output when the triggering unit failed
+func (n *NtfyUnit) SetStderrOnFailure(stderrOnFailure bool) {
+	n.stderrOnFailure = stderrOnFailure
+}
+
+// selectOutput returns the output to include in the message
+func (n *NtfyUnit) selectOutput() string {
+	if n.stderrOnFailure && n.triggerError != nil {
+		return n.stderr
+	}
+	return n.output
+}
+
 // SetTriggeringUnit sets the name of the unit that triggered this notification
 func (n *NtfyUnit) SetTriggeringUnit(unitName string) {
 	n.triggeringUnit = unitName
@@ -88,6 +315,13 @@ func (n *NtfyUnit) SetTriggerError(err error) {
 func (n *NtfyUnit) Run(ctx context.Context) error {
 	log.Printf("Running ntfy unit '%s'", n.name)
 
+	// Apply timeout if configured
+	if n.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.timeout)
+		defer cancel()
+	}
+
 	// Build notification body
 	body := n.buildBody()
 
@@ -103,12 +337,35 @@ func (n *NtfyUnit) Run(ctx context.Context) error {
 
 	title := ""
 	if n.titlePrefix != "" {
-		title = n.titlePrefix + ": "
+		data := notificationData{Unit: unitName, Count: n.count, Env: n.env, BrunVersion: Version()}
+		title = renderNotificationTemplate(n.titlePrefix, data) + ": "
 	}
 	title += fmt.Sprintf("%s:%s", unitName, status)
 
+	// Send anything queued during quiet hours first, in order
+	if err := n.FlushQueued(ctx); err != nil {
+		log.Printf("Ntfy unit '%s': %v", n.name, err)
+	}
+
+	if !n.critical {
+		held, err := n.quietHours.hold(n.quietState, n.name, title, body)
+		if err != nil {
+			return err
+		}
+		if held {
+			return nil
+		}
+	}
+
 	// Send notification
 	if err := n.sendNotification(ctx, title, body); err != nil {
+		if n.outbox != nil {
+			if err := n.outbox.add(n.name, title, body); err != nil {
+				log.Printf("Ntfy unit '%s': %v", n.name, err)
+			} else {
+				log.Printf("Ntfy unit '%s': queued notification for retry", n.name)
+			}
+		}
 		return fmt.Errorf("failed to send ntfy notification: %w", err)
 	}
 
@@ -128,24 +385,51 @@ func (n *NtfyUnit) buildBody() string {
 
 	body.WriteString(fmt.Sprintf("Triggered by: %s\n", unitName))
 	body.WriteString(fmt.Sprintf("Timestamp: %s\n", timestamp))
+	body.WriteString(fmt.Sprintf("BRun version: %s\n", Version()))
+	if n.count > 0 {
+		body.WriteString(fmt.Sprintf("Count: %d\n", n.count))
+	}
 
 	if n.triggerError != nil {
 		body.WriteString(fmt.Sprintf("Error: %v\n", n.triggerError))
 	}
 
-	if n.includeOutput && n.output != "" {
-		body.WriteString("\nOutput:\n")
+	fullOutput := n.selectOutput()
+	if n.includeOutput && fullOutput != "" {
+		collapse := n.markdown && n.collapseOutput
+		if collapse {
+			body.WriteString("\n<details>\n<summary>Output</summary>\n\n")
+		} else {
+			body.WriteString("\nOutput:\n")
+		}
 
-		output := n.output
+		output := fullOutput
 		if n.limitLines > 0 {
 			lines := strings.Split(output, "\n")
 			if len(lines) > n.limitLines {
+				// Link to the full output instead of inlining it if configured
+				if n.outputURL != "" {
+					url, err := storeOutput(n.outputDir, n.outputURL, unitName, fullOutput)
+					if err == nil {
+						body.WriteString(fmt.Sprintf("Full output (%d lines): %s", len(lines), url))
+						if collapse {
+							body.WriteString("\n\n</details>")
+						}
+						return body.String()
+					}
+					log.Printf("Ntfy unit '%s': failed to store output, including tail instead: %v", n.name, err)
+				}
+
 				lines = lines[len(lines)-n.limitLines:]
 				output = strings.Join(lines, "\n")
-				body.WriteString(fmt.Sprintf("(last %d of %d lines)\n", n.limitLines, len(strings.Split(n.output, "\n"))))
+				body.WriteString(fmt.Sprintf("(last %d of %d lines)\n", n.limitLines, len(strings.Split(fullOutput, "\n"))))
 			}
 		}
 
+		if collapse {
+			body.WriteString("```\n" + output + "\n```\n\n</details>")
+			return body.String()
+		}
 		body.WriteString(output)
 	} else if !n.includeOutput {
 		body.WriteString("\n(Output not included)")
@@ -175,9 +459,22 @@ func (n *NtfyUnit) sendNotification(ctx context.Context, title, body string) err
 	if n.tags != "" {
 		req.Header.Set("Tags", n.tags)
 	}
+	if n.delay != "" {
+		req.Header.Set("Delay", n.delay)
+	}
+	if n.markdown {
+		req.Header.Set("Markdown", "yes")
+	}
+	if len(n.actions) > 0 {
+		headers := make([]string, len(n.actions))
+		for i, action := range n.actions {
+			headers[i] = action.header()
+		}
+		req.Header.Set("Actions", strings.Join(headers, "; "))
+	}
 
 	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Timeout: n.httpTimeout}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
@@ -3,12 +3,14 @@ package brun
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNtfyUnit_Basic(t *testing.T) {
@@ -146,6 +148,10 @@ func TestNtfyUnit_BuildBody(t *testing.T) {
 		t.Error("Body missing timestamp")
 	}
 
+	if !strings.Contains(body, "BRun version: "+Version()) {
+		t.Error("Body missing brun version")
+	}
+
 	if !strings.Contains(body, "Output:") {
 		t.Error("Body missing output section")
 	}
@@ -214,6 +220,85 @@ func TestNtfyUnit_BuildBody_LimitLines(t *testing.T) {
 	}
 }
 
+func TestNtfyUnit_BuildBody_OutputLink(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "logs")
+
+	unit := NewNtfyUnit(
+		"test-ntfy",
+		"my-topic",
+		"https://ntfy.sh",
+		"",
+		"",
+		"",
+		true,
+		2,
+		nil,
+		nil,
+		nil,
+	)
+	unit.SetOutputLink(outputDir, "https://logs.example.com/{{.Filename}}")
+
+	unit.SetTriggeringUnit("build-unit")
+	output := "Line 1\nLine 2\nLine 3\nLine 4\nLine 5"
+	unit.SetOutput(output)
+
+	body := unit.buildBody()
+
+	if strings.Contains(body, "Line 5") {
+		t.Error("Body should not include output when linking")
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("Failed to read output dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 stored output file, got %d", len(entries))
+	}
+
+	filename := entries[0].Name()
+	if !strings.HasPrefix(filename, "build-unit-") {
+		t.Errorf("Unexpected output filename '%s'", filename)
+	}
+	if !strings.Contains(body, "https://logs.example.com/"+filename) {
+		t.Errorf("Body missing output URL: %s", body)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, filename))
+	if err != nil {
+		t.Fatalf("Failed to read stored output: %v", err)
+	}
+	if string(data) != output {
+		t.Errorf("Stored output mismatch: %q", data)
+	}
+
+	// Output under the limit is still included inline
+	unit.SetOutput("Line 1")
+	body = unit.buildBody()
+	if !strings.Contains(body, "Line 1") {
+		t.Error("Body should include short output inline")
+	}
+}
+
+func TestNtfyUnit_BuildBody_OutputLinkFallback(t *testing.T) {
+	// A file in place of the output directory makes storing fail
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unit := NewNtfyUnit("test-ntfy", "my-topic", "https://ntfy.sh", "", "", "", true, 2, nil, nil, nil)
+	unit.SetOutputLink(blocker, "https://logs.example.com/{{.Filename}}")
+	unit.SetTriggeringUnit("build-unit")
+	unit.SetOutput("Line 1\nLine 2\nLine 3")
+
+	body := unit.buildBody()
+
+	if !strings.Contains(body, "Line 2\nLine 3") {
+		t.Errorf("Body should fall back to output tail: %s", body)
+	}
+}
+
 func TestNtfyUnit_BuildBody_NoOutput(t *testing.T) {
 	unit := NewNtfyUnit(
 		"test-ntfy",
@@ -389,6 +474,149 @@ func TestNtfyUnit_Run_ServerError(t *testing.T) {
 	}
 }
 
+func TestNtfyUnit_Run_MarkdownActions(t *testing.T) {
+	var markdown, actions string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		markdown = r.Header.Get("Markdown")
+		actions = r.Header.Get("Actions")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	unit := NewNtfyUnit("test-ntfy", "my-topic", server.URL, "", "", "", true, 0, nil, nil, nil)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if markdown != "" || actions != "" {
+		t.Errorf("Expected no Markdown/Actions headers by default, got %q, %q", markdown, actions)
+	}
+
+	unit.SetMarkdown(true)
+	unit.SetActions([]NtfyAction{
+		{Action: "view", Label: "Logs", URL: "https://ci.example.com/logs"},
+		{
+			Action:  "http",
+			Label:   "Rebuild",
+			URL:     "https://ci.example.com/trigger?unit=build,test",
+			Method:  "POST",
+			Headers: map[string]string{"Authorization": "Bearer abc"},
+			Body:    `{"unit": "build"}`,
+			Clear:   true,
+		},
+	})
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if markdown != "yes" {
+		t.Errorf("Expected Markdown header 'yes', got %q", markdown)
+	}
+	want := `view, Logs, https://ci.example.com/logs; ` +
+		`http, Rebuild, "https://ci.example.com/trigger?unit=build,test", method=POST, ` +
+		`headers.Authorization=Bearer abc, body='{"unit": "build"}', clear=true`
+	if actions != want {
+		t.Errorf("Actions header =\n%s\nwant\n%s", actions, want)
+	}
+}
+
+func TestNtfyAction_Validate(t *testing.T) {
+	tests := []struct {
+		action  NtfyAction
+		wantErr bool
+	}{
+		{NtfyAction{Action: "view", Label: "Open", URL: "https://example.com"}, false},
+		{NtfyAction{Action: "broadcast", Label: "Notify"}, false},
+		{NtfyAction{Action: "http", Label: "Rebuild"}, true},
+		{NtfyAction{Action: "view", URL: "https://example.com"}, true},
+		{NtfyAction{Action: "email", Label: "Mail"}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.action.validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) error = %v, wantErr %v", tt.action, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNtfyUnit_Run_Delay(t *testing.T) {
+	var receivedDelay string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedDelay = r.Header.Get("Delay")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	unit := NewNtfyUnit("test-ntfy", "my-topic", server.URL, "", "", "", true, 0, nil, nil, nil)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if receivedDelay != "" {
+		t.Errorf("Expected no Delay header, got '%s'", receivedDelay)
+	}
+
+	unit.SetDelay("1h")
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if receivedDelay != "1h" {
+		t.Errorf("Expected Delay header '1h', got '%s'", receivedDelay)
+	}
+}
+
+func TestNtfyUnit_Run_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	unit := NewNtfyUnit("test-ntfy", "my-topic", server.URL, "", "", "", true, 0, nil, nil, nil)
+	unit.SetTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	err := unit.Run(context.Background())
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %v, timeout not applied", elapsed)
+	}
+}
+
+func TestNtfyUnit_Run_HTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	unit := NewNtfyUnit("test-ntfy", "my-topic", server.URL, "", "", "", true, 0, nil, nil, nil)
+	if unit.httpTimeout != defaultNtfyHTTPTimeout {
+		t.Errorf("Expected default HTTP timeout %v, got %v", defaultNtfyHTTPTimeout, unit.httpTimeout)
+	}
+	unit.SetHTTPTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	if err := unit.Run(context.Background()); err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %v, HTTP timeout not applied", elapsed)
+	}
+}
+
 func TestLoadConfig_WithNtfyUnit(t *testing.T) {
 	tempDir := t.TempDir()
 	configFile := filepath.Join(tempDir, "config.yaml")
@@ -569,3 +797,247 @@ func TestCreateUnits_NtfyMissingName(t *testing.T) {
 		t.Error("Expected error for missing name")
 	}
 }
+
+func TestNtfyUnit_StderrOnFailure(t *testing.T) {
+	unit := NewNtfyUnit("test-ntfy", "topic", "https://ntfy.sh", "", "", "", true, 0, nil, nil, nil)
+	unit.SetOutput("compiling\nerror: boom")
+	unit.SetStderr("error: boom")
+	unit.SetStderrOnFailure(true)
+
+	// Full output is included when the triggering unit succeeded
+	body := unit.buildBody()
+	if !strings.Contains(body, "compiling") {
+		t.Errorf("Expected full output on success, got: %s", body)
+	}
+
+	unit.SetTriggerError(errors.New("exit 1"))
+	body = unit.buildBody()
+	if strings.Contains(body, "compiling") || !strings.Contains(body, "error: boom") {
+		t.Errorf("Expected only stderr on failure, got: %s", body)
+	}
+}
+
+// TestNtfyUnit_CountFromChain verifies that a count unit earlier in the chain
+// is available to the title prefix and body
+func TestNtfyUnit_CountFromChain(t *testing.T) {
+	var receivedTitle, receivedBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedTitle = r.Header.Get("Title")
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	units := []Unit{
+		NewRunUnit("build", "exit 1", "", 0, "", false, nil, []string{"failures"}, nil),
+		NewCountUnit("failures", state, []string{"notify"}, nil, nil),
+		NewNtfyUnit("notify", "builds", server.URL, "Failure #{{.Count}}", "", "", true, 0, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+
+	for i := 0; i < 3; i++ {
+		_ = orchestrator.RunSingleUnit(context.Background(), "build", true)
+	}
+
+	if receivedTitle != "Failure #3: failures:success" {
+		t.Errorf("Expected count in title, got %q", receivedTitle)
+	}
+	if !strings.Contains(receivedBody, "Count: 3") {
+		t.Errorf("Expected count in body, got %q", receivedBody)
+	}
+}
+
+func TestRenderNotificationTemplate(t *testing.T) {
+	data := notificationData{Unit: "build", Count: 2}
+	if got := renderNotificationTemplate("[BRun] {{.Unit}} #{{.Count}}", data); got != "[BRun] build #2" {
+		t.Errorf("Unexpected render: %q", got)
+	}
+	if got := renderNotificationTemplate("[BRun]", data); got != "[BRun]" {
+		t.Errorf("Expected plain text unchanged, got %q", got)
+	}
+	if err := validateNotificationTemplate("[BRun {{.BrunVersion}}]"); err != nil {
+		t.Errorf("Expected BrunVersion to be valid, got %v", err)
+	}
+	if err := validateNotificationTemplate("{{.Missing}}"); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestQuietHours_Active(t *testing.T) {
+	q, err := NewQuietHours("22:00", "07:00", "UTC", false)
+	if err != nil {
+		t.Fatalf("NewQuietHours failed: %v", err)
+	}
+	tests := map[string]bool{
+		"21:59": false,
+		"22:00": true,
+		"03:00": true,
+		"06:59": true,
+		"07:00": false,
+		"12:00": false,
+	}
+	for clock, want := range tests {
+		at, _ := time.Parse("2006-01-02 15:04", "2026-03-01 "+clock)
+		if got := q.Active(at); got != want {
+			t.Errorf("Active(%s) = %v, want %v", clock, got, want)
+		}
+	}
+
+	for _, window := range [][3]string{{"25:00", "07:00", ""}, {"22:00", "22:00", ""}, {"22:00", "07:00", "Nowhere/City"}} {
+		if _, err := NewQuietHours(window[0], window[1], window[2], false); err == nil {
+			t.Errorf("Expected error for quiet hours %v", window)
+		}
+	}
+}
+
+func TestNtfyUnit_Run_QuietHours(t *testing.T) {
+	var titles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		titles = append(titles, r.Header.Get("Title"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	quiet, err := NewQuietHours(now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"), "", true)
+	if err != nil {
+		t.Fatalf("NewQuietHours failed: %v", err)
+	}
+	notQuiet, err := NewQuietHours(now.Add(time.Hour).Format("15:04"), now.Add(2*time.Hour).Format("15:04"), "", true)
+	if err != nil {
+		t.Fatalf("NewQuietHours failed: %v", err)
+	}
+
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	unit := NewNtfyUnit("test-ntfy", "my-topic", server.URL, "", "", "", false, 0, nil, nil, nil)
+	unit.SetQuietHours(quiet, state)
+	unit.SetTriggeringUnit("build")
+
+	// During quiet hours the notification is queued
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(titles) != 0 {
+		t.Fatalf("Expected no notification during quiet hours, got %v", titles)
+	}
+
+	// Critical notifications are sent anyway
+	unit.SetCritical(true)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(titles) != 1 {
+		t.Fatalf("Expected critical notification to be sent, got %v", titles)
+	}
+
+	// Once quiet hours end, the queued notification is sent
+	unit.SetQuietHours(notQuiet, state)
+	if err := unit.FlushQueued(context.Background()); err != nil {
+		t.Fatalf("FlushQueued failed: %v", err)
+	}
+	if len(titles) != 2 || titles[1] != "build:success" {
+		t.Errorf("Expected queued notification to be sent, got %v", titles)
+	}
+	if _, ok := state.Get("test-ntfy", "quiet_queue"); ok {
+		t.Error("Expected queue to be cleared")
+	}
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	outbox := NewOutbox(time.Hour, state)
+	now := time.Now()
+
+	outbox.now = func() time.Time { return now.Add(-2 * time.Hour) }
+	if err := outbox.add("alerts", "old", "body"); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	outbox.now = func() time.Time { return now }
+	for _, title := range []string{"first", "second", "third"} {
+		if err := outbox.add("alerts", title, "body"); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+
+	// Expired notifications are dropped and retrying stops at the first
+	// failure
+	var sent []string
+	err := outbox.retry("alerts", func(title, body string) error {
+		if title == "second" {
+			return errors.New("server down")
+		}
+		sent = append(sent, title)
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected retry error")
+	}
+	if len(sent) != 1 || sent[0] != "first" {
+		t.Errorf("Expected only 'first' to be sent, got %v", sent)
+	}
+
+	sent = nil
+	if err := outbox.retry("alerts", func(title, body string) error {
+		sent = append(sent, title)
+		return nil
+	}); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if len(sent) != 2 || sent[0] != "second" || sent[1] != "third" {
+		t.Errorf("Expected remaining notifications in order, got %v", sent)
+	}
+	if _, ok := state.Get("alerts", "outbox"); ok {
+		t.Error("Expected outbox to be cleared")
+	}
+}
+
+func TestNtfyUnit_BuildBody_CollapseOutput(t *testing.T) {
+	unit := NewNtfyUnit("test-ntfy", "my-topic", "https://ntfy.sh", "", "", "", true, 2, nil, nil, nil)
+	unit.SetTriggeringUnit("build-unit")
+	unit.SetOutput("Line 1\nLine 2\nLine 3")
+	unit.SetCollapseOutput(true)
+
+	// Plain text has no collapsible construct, so the body is unchanged
+	if body := unit.buildBody(); strings.Contains(body, "<details>") || !strings.Contains(body, "Output:") {
+		t.Errorf("Expected plain output without markdown, got:\n%s", body)
+	}
+
+	unit.SetMarkdown(true)
+	body := unit.buildBody()
+	want := "\n<details>\n<summary>Output</summary>\n\n(last 2 of 3 lines)\n```\nLine 2\nLine 3\n```\n\n</details>"
+	if !strings.HasSuffix(body, want) {
+		t.Errorf("Expected collapsed output %q, got:\n%s", want, body)
+	}
+	if !strings.HasPrefix(body, "Triggered by: build-unit\n") {
+		t.Errorf("Expected the summary before the output, got:\n%s", body)
+	}
+}
+
+func TestNtfyUnit_TriggersChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	units := []Unit{
+		NewStartTrigger("start", []string{"build"}, nil, nil),
+		NewRunUnit("build", "true", "", 0, "", false, []string{"notify"}, nil, nil),
+		NewNtfyUnit("notify", "my-topic", server.URL, "", "", "", false, 0, []string{"notified"}, nil, []string{"cleanup"}),
+		NewRunUnit("notified", "true", "", 0, "", false, nil, nil, nil),
+		NewRunUnit("cleanup", "true", "", 0, "", false, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	// An ntfy unit's own on_success and always units run after it sends
+	results := orchestrator.GetResults()
+	for _, name := range []string{"notify", "notified", "cleanup"} {
+		if _, ok := results[name]; !ok {
+			t.Errorf("Expected '%s' to run", name)
+		}
+	}
+}
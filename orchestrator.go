@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
 	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,27 +20,192 @@ import (
 type UnitResult struct {
 	Unit   Unit
 	Error  error
-	Output string // Captured stdout/stderr
+	Output string // Captured stdout and stderr, interleaved
+	Stdout string // Captured stdout only
+	Stderr string // Captured stderr only
 }
 
 // ansiEscapeRegex matches ANSI escape sequences including cursor movement and color codes
 var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]|\x1b\][0-9];[^\x07]*\x07`)
 
-// stripANSI removes ANSI escape sequences from a string
+// stripANSI removes ANSI escape sequences from a string and normalizes CRLF
+// line endings
 func stripANSI(s string) string {
-	return ansiEscapeRegex.ReplaceAllString(s, "")
+	s = ansiEscapeRegex.ReplaceAllString(s, "")
+	// Terminals (e.g., use_pty) emit CRLF line endings
+	return strings.ReplaceAll(s, "\r\n", "\n")
 }
 
+// lockedWriter serializes writes from the stdout and stderr copiers into the
+// combined output buffer
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// captureOutput runs fn with stdout and stderr redirected to separate pipes.
+// Each stream is copied to its original destination for display, to its own
+// buffer, and to a combined buffer in the order it arrives. ANSI escape
+// sequences are stripped from the captured output for cleaner logs/emails
+// while preserving them in the terminal display.
+func captureOutput(fn func()) (combined, stdout, stderr string) {
+	oldStdout := os.Stdout
+	oldStderr := os.Stderr
+
+	// Create pipes to capture output
+	rOut, wOut, _ := os.Pipe()
+	rErr, wErr, _ := os.Pipe()
+
+	// Redirect stdout and stderr
+	os.Stdout = wOut
+	os.Stderr = wErr
+
+	var combinedBuf, stdoutBuf, stderrBuf bytes.Buffer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	tee := func(r *os.File, buf *bytes.Buffer, display *os.File) {
+		defer wg.Done()
+		mw := io.MultiWriter(buf, lockedWriter{&mu, &combinedBuf}, display)
+		if _, err := io.Copy(mw, r); err != nil {
+			log.Println("Error copying output buffer: ", err)
+		}
+	}
+	wg.Add(2)
+	go tee(rOut, &stdoutBuf, oldStdout)
+	go tee(rErr, &stderrBuf, oldStderr)
+
+	fn()
+
+	// Close writers and wait for copies to complete
+	wOut.Close()
+	wErr.Close()
+	wg.Wait()
+	rOut.Close()
+	rErr.Close()
+
+	// Restore stdout/stderr
+	os.Stdout = oldStdout
+	os.Stderr = oldStderr
+
+	return stripANSI(combinedBuf.String()), stripANSI(stdoutBuf.String()), stripANSI(stderrBuf.String())
+}
+
+// lifecycleHookTimeout bounds how long on_shutdown units may run after the
+// daemon has been asked to stop
+const lifecycleHookTimeout = 30 * time.Second
+
+// defaultPollInterval is how often the daemon polls triggers by default
+const defaultPollInterval = 10 * time.Second
+
 // Orchestrator manages unit execution and triggering
+//
+// The orchestrator lifecycle is the same for one-shot and daemon runs:
+//
+//  1. Startup cycle: every trigger is checked, including startup-only
+//     triggers (boot, start, and daemon_start in daemon mode). Startup-only
+//     triggers are checked at most once per orchestrator.
+//  2. Poll cycles (daemon mode only): every pollInterval all triggers except
+//     the startup-only and scheduled (cron) triggers are checked.
+//  3. Scheduled cycles (daemon mode only): scheduled triggers are checked at
+//     their next scheduled time, so they fire on time rather than up to a
+//     poll interval late.
+//
+// Poll and scheduled cycles are driven by separate loops. Cycles never run
+// concurrently: a cycle that comes due while another is running waits for it
+// to finish, since units share state and the captured stdout/stderr.
+//
+// In daemon mode config.on_start units run before the startup cycle and
+// config.on_shutdown units run after the last poll cycle.
+// RunSingleUnit bypasses this lifecycle and runs one unit on demand.
 type Orchestrator struct {
 	units       []Unit
 	unitsByName map[string]Unit
-	results     map[string]*UnitResult
-	activeUnit  string
-	mu          sync.RWMutex
-	ctx         context.Context
-	cancel      context.CancelFunc
-	daemonMode  bool
+	// results holds the results of the current or latest run, guarded by mu
+	results map[string]*UnitResult
+	// cycleResults holds the results of the last completed trigger cycle,
+	// guarded by mu
+	cycleResults map[string]*UnitResult
+	activeUnit   string
+	mu           sync.RWMutex
+	cycleMu      sync.Mutex // serializes trigger cycles
+	// cycle counts trigger cycles, numbering the checks in triggerLog
+	cycle int
+	// triggerLog holds the most recent trigger checks, guarded by mu
+	triggerLog   []TriggerCheck
+	ctx          context.Context
+	cancel       context.CancelFunc
+	daemonMode   bool
+	pollInterval time.Duration
+	cycleTimeout time.Duration
+	maxRuntime   time.Duration
+	onStart      []string
+	onShutdown   []string
+	onAnySuccess []string
+	onAnyFailure []string
+	// artifactDecls holds set_artifact declarations keyed by unit name
+	artifactDecls map[string]map[string]string
+	// priorities holds unit priorities keyed by unit name; activated
+	// triggers with a higher priority run first
+	priorities map[string]int
+	// artifacts holds artifact values set during the current activation
+	artifacts map[string]string
+	// count holds the latest count set by a count unit during the current
+	// activation, or 0 if none ran
+	count int
+	// env holds environment variables published by units, such as the git
+	// trigger's commit, during the current activation
+	env map[string]string
+	// cooldowns holds trigger cooldowns keyed by unit name; the time each
+	// trigger last fired is kept in cooldownState
+	cooldowns     map[string]time.Duration
+	cooldownState *State
+	// backoffs holds the failure backoff of units keyed by unit name; the
+	// consecutive failures and next retry of each are kept in backoffState
+	backoffs     map[string]FailureBackoff
+	backoffState *State
+	// pollIntervals holds per-trigger poll intervals keyed by unit name;
+	// lastPolled holds the time each of these triggers was last checked
+	pollIntervals map[string]time.Duration
+	lastPolled    map[string]time.Time
+	// skipTriggers holds triggers that are never checked, e.g. because a
+	// systemd timer runs them
+	skipTriggers []string
+	// edgeTriggers holds the names of edge triggers; the last result of each
+	// check is kept in edgeState
+	edgeTriggers map[string]bool
+	edgeState    *State
+	// errorTriggers holds the on_error units of triggers keyed by unit name,
+	// run when the trigger's Check returns an error
+	errorTriggers map[string][]string
+	// propagateFailure holds the names of units whose on_failure units also
+	// run when a unit in their downstream chain fails
+	propagateFailure map[string]bool
+	// forcedTriggers holds, keyed by unit name, the trigger units it runs
+	// without checking their condition
+	forcedTriggers map[string][]string
+	// descriptions holds unit descriptions keyed by unit name, shown in logs
+	descriptions map[string]string
+	// destructive holds the names of units marked destructive in the config
+	destructive map[string]bool
+	// allowDestructive lets RunSingleUnit run destructive units
+	allowDestructive bool
+	// skipSingleCheck runs a trigger in RunSingleUnit without checking it
+	skipSingleCheck bool
+	// singleRun is set while RunSingleUnit is executing
+	singleRun bool
+	// eventHandlers are called when units start and complete
+	eventHandlers []func(Event)
+	// inDaemon is set while RunDaemon runs, so daemon start triggers fire
+	inDaemon bool
+	// startupDone is set once startup-only triggers (boot, start) have been
+	// checked so they fire at most once per orchestrator lifetime
+	startupDone bool
 }
 
 // NewOrchestrator creates a new orchestrator with the given units
@@ -48,14 +217,63 @@ func NewOrchestrator(units []Unit) *Orchestrator {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Orchestrator{
-		units:       units,
-		unitsByName: unitsByName,
-		results:     make(map[string]*UnitResult),
-		ctx:         ctx,
-		cancel:      cancel,
-		daemonMode:  false,
+	o := &Orchestrator{
+		units:        units,
+		unitsByName:  unitsByName,
+		results:      make(map[string]*UnitResult),
+		lastPolled:   make(map[string]time.Time),
+		ctx:          ctx,
+		cancel:       cancel,
+		daemonMode:   false,
+		pollInterval: defaultPollInterval,
+	}
+
+	// Units that run other units, such as escalation units, run them through
+	// the orchestrator
+	for _, unit := range units {
+		if u, ok := unit.(unitRunnerUser); ok {
+			u.SetUnitRunner(func(ctx context.Context, name string) error {
+				return o.runByName(ctx, unit.Name(), name)
+			})
+		}
+	}
+
+	// Units that summarize other units, such as aggregate units, look up
+	// their results in the current cycle
+	for _, unit := range units {
+		if u, ok := unit.(resultLookupUser); ok {
+			u.SetResultLookup(o.result)
+		}
+	}
+
+	return o
+}
+
+// unitRunnerUser is implemented by units that run other units
+type unitRunnerUser interface {
+	SetUnitRunner(run func(ctx context.Context, name string) error)
+}
+
+// resultLookupUser is implemented by units that read the results of other
+// units
+type resultLookupUser interface {
+	SetResultLookup(result func(name string) (*UnitResult, bool))
+}
+
+// runByName runs the named unit on behalf of source, capturing its output.
+// The unit's own triggers don't fire; source decides what happens next.
+func (o *Orchestrator) runByName(ctx context.Context, source, name string) error {
+	unit, ok := o.unitsByName[name]
+	if !ok {
+		return fmt.Errorf("unit '%s' not found", name)
+	}
+	if o.suppressDestructive(unit) {
+		return nil
 	}
+
+	o.prepareTarget(unit, source, &UnitResult{})
+	result := o.runAndCapture(ctx, unit)
+	return result.Error
 }
 
 // SetDaemonMode configures whether the orchestrator should run in daemon mode
@@ -63,6 +281,150 @@ func (o *Orchestrator) SetDaemonMode(daemon bool) {
 	o.daemonMode = daemon
 }
 
+// SetPollInterval sets how often the daemon polls triggers that aren't
+// scheduled. An interval of 0 keeps the default of 10 seconds.
+func (o *Orchestrator) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		o.pollInterval = interval
+	}
+}
+
+// SetCycleTimeout bounds how long a single startup or poll cycle may run
+// A timeout of 0 means cycles are not bounded
+func (o *Orchestrator) SetCycleTimeout(timeout time.Duration) {
+	o.cycleTimeout = timeout
+}
+
+// SetLifecycleHooks configures units to run once when the daemon starts and
+// once when it shuts down gracefully
+func (o *Orchestrator) SetLifecycleHooks(onStart, onShutdown []string) {
+	o.onStart = onStart
+	o.onShutdown = onShutdown
+}
+
+// SetGlobalTriggers configures units to trigger after any unit succeeds or
+// fails, in addition to the unit's own on_success/on_failure lists
+func (o *Orchestrator) SetGlobalTriggers(onAnySuccess, onAnyFailure []string) {
+	o.onAnySuccess = onAnySuccess
+	o.onAnyFailure = onAnyFailure
+}
+
+// SetMaxRuntime configures how long RunDaemon runs before returning so a
+// service manager can restart it. The current cycle finishes first. 0 means
+// no limit.
+func (o *Orchestrator) SetMaxRuntime(maxRuntime time.Duration) {
+	o.maxRuntime = maxRuntime
+}
+
+// SetUnitPriorities configures the priority of each unit, keyed by unit name
+// When several triggers fire in the same cycle, higher priorities run first
+func (o *Orchestrator) SetUnitPriorities(priorities map[string]int) {
+	o.priorities = priorities
+}
+
+// SetCooldowns configures trigger cooldowns, keyed by unit name. After a
+// trigger fires, it isn't checked again until its cooldown has passed. The
+// time each trigger last fired is stored in state so cooldowns survive
+// restarts.
+func (o *Orchestrator) SetCooldowns(cooldowns map[string]time.Duration, state *State) {
+	o.cooldowns = cooldowns
+	o.cooldownState = state
+}
+
+// SetPollIntervals sets per-trigger poll intervals, keyed by unit name. In
+// poll cycles, these triggers are skipped until their interval has passed
+// since they were last checked.
+func (o *Orchestrator) SetPollIntervals(intervals map[string]time.Duration) {
+	o.pollIntervals = intervals
+}
+
+// SetSkipTriggers sets triggers, by unit name, that are never checked or
+// scheduled, e.g. cron triggers run by systemd timers. They can still be run
+// with RunSingleUnit.
+func (o *Orchestrator) SetSkipTriggers(names []string) {
+	o.skipTriggers = names
+}
+
+// checkedUnits returns the units, without the skipped triggers
+func (o *Orchestrator) checkedUnits() []Unit {
+	var units []Unit
+	for _, unit := range o.units {
+		if !slices.Contains(o.skipTriggers, unit.Name()) {
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// SetEdgeTriggers configures the triggers, keyed by unit name, that only fire
+// when their check result changes from false to true. The last result is
+// stored in state so a condition that persists across restarts doesn't fire
+// again.
+func (o *Orchestrator) SetEdgeTriggers(edgeTriggers map[string]bool, state *State) {
+	o.edgeTriggers = edgeTriggers
+	o.edgeState = state
+}
+
+// SetErrorTriggers configures the units to run when a trigger's Check
+// returns an error, keyed by trigger name
+func (o *Orchestrator) SetErrorTriggers(errorTriggers map[string][]string) {
+	o.errorTriggers = errorTriggers
+}
+
+// SetPropagateFailure configures the units, keyed by name, whose on_failure
+// units also run when the chain they trigger fails, e.g. a git trigger whose
+// build fails
+func (o *Orchestrator) SetPropagateFailure(propagateFailure map[string]bool) {
+	o.propagateFailure = propagateFailure
+}
+
+// SetForcedTriggers configures, keyed by unit name, the trigger units a unit
+// runs whatever their Check returns when it triggers them. Check is still
+// called for its side effects, e.g. a git trigger updating its workspace.
+func (o *Orchestrator) SetForcedTriggers(forcedTriggers map[string][]string) {
+	o.forcedTriggers = forcedTriggers
+}
+
+// SetUnitDescriptions configures the descriptions shown next to unit names in
+// logs, keyed by unit name
+func (o *Orchestrator) SetUnitDescriptions(descriptions map[string]string) {
+	o.descriptions = descriptions
+}
+
+// describe returns the quoted unit name followed by its description, if any,
+// e.g. "'u1' (Nightly YOE build)"
+func (o *Orchestrator) describe(name string) string {
+	if description := o.descriptions[name]; description != "" {
+		return fmt.Sprintf("'%s' (%s)", name, description)
+	}
+	return "'" + name + "'"
+}
+
+// SetDestructiveUnits configures the units, keyed by unit name, that are
+// suppressed by RunSingleUnit unless allowed. Reboot units are always
+// destructive.
+func (o *Orchestrator) SetDestructiveUnits(destructive map[string]bool) {
+	o.destructive = destructive
+}
+
+// SetAllowDestructive allows RunSingleUnit to run destructive units
+func (o *Orchestrator) SetAllowDestructive(allow bool) {
+	o.allowDestructive = allow
+}
+
+// SetSkipSingleCheck makes RunSingleUnit with runTriggers run a trigger
+// without checking its condition, e.g. when a systemd timer has already
+// decided it's time to run
+func (o *Orchestrator) SetSkipSingleCheck(skip bool) {
+	o.skipSingleCheck = skip
+}
+
+// SetUnitArtifacts configures the artifacts each unit sets when it completes
+// successfully, keyed by unit name
+func (o *Orchestrator) SetUnitArtifacts(decls map[string]map[string]string) {
+	o.artifactDecls = decls
+}
+
 // Run executes the orchestrator (for use with oklog/run)
 func (o *Orchestrator) Run() error {
 	var err error
@@ -80,34 +442,242 @@ func (o *Orchestrator) Stop(error) {
 	o.cancel()
 }
 
-// RunOnce executes all units once with the given context
+// RunOnce executes the startup cycle with the given context and returns
 // This method is useful for testing and one-time execution
 func (o *Orchestrator) RunOnce(ctx context.Context) error {
 	log.Println("Starting orchestrator...")
-	o.checkAndExecuteTriggers(ctx, true)
+	o.runStartupCycle(ctx)
 	log.Println("Orchestrator finished")
 	return nil
 }
 
-// RunDaemon executes in daemon mode, continuously checking triggers
+// RunDaemon executes the startup cycle and then runs the poll and scheduler
+// loops until ctx is cancelled or the max runtime is reached
 func (o *Orchestrator) RunDaemon(ctx context.Context) error {
 	log.Println("Starting orchestrator in daemon mode...")
 
-	// Check interval - check triggers every 10 seconds as per README
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	o.inDaemon = true
+	defer func() { o.inDaemon = false }()
+
+	// The loops stop when stopCtx is done, but a cycle in progress runs
+	// under ctx so it finishes when the max runtime is reached
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	if o.maxRuntime > 0 {
+		timer := time.AfterFunc(o.maxRuntime, func() {
+			log.Println("Max runtime reached, restarting")
+			stop()
+		})
+		defer timer.Stop()
+	}
+
+	// Schedule from before the startup cycle so a scheduled time that passes
+	// while it runs is still checked
+	queue := newScheduleQueue(o.checkedUnits(), time.Now())
+
+	// Fire lifecycle start hooks before any triggers are checked
+	o.runLifecycleHooks(ctx, "on_start", o.onStart)
 
-	// Run once immediately on startup (check all triggers including boot triggers)
-	o.checkAndExecuteTriggers(ctx, true)
+	o.runStartupCycle(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		o.pollLoop(ctx, stopCtx)
+	}()
+	go func() {
+		defer wg.Done()
+		o.scheduleLoop(ctx, stopCtx, queue)
+	}()
+	wg.Wait()
+
+	log.Println("Orchestrator daemon shutting down...")
+	// The daemon context may already be cancelled, so shutdown hooks get
+	// their own context to be able to send notifications
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), lifecycleHookTimeout)
+	o.runLifecycleHooks(shutdownCtx, "on_shutdown", o.onShutdown)
+	cancel()
+	return ctx.Err()
+}
+
+// pollLoop runs a poll cycle with ctx every pollInterval until stopCtx is
+// done
+func (o *Orchestrator) pollLoop(ctx, stopCtx context.Context) {
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ctx.Done():
-			log.Println("Orchestrator daemon shutting down...")
-			return ctx.Err()
+		case <-stopCtx.Done():
+			return
 		case <-ticker.C:
-			// During polling, skip startup triggers like boot triggers
-			o.checkAndExecuteTriggers(ctx, false)
+			if stopCtx.Err() != nil {
+				return
+			}
+			o.runPollCycle(ctx)
+		}
+	}
+}
+
+// scheduleLoop sleeps until the next scheduled trigger is due and checks it
+// with ctx, until stopCtx is done. Every poll interval it re-reads schedules
+// that can change at runtime and requeues their triggers.
+func (o *Orchestrator) scheduleLoop(ctx, stopCtx context.Context, queue *scheduleQueue) {
+	if queue.Len() == 0 {
+		// Nothing scheduled
+		<-stopCtx.Done()
+		return
+	}
+
+	refresh := time.NewTicker(o.pollInterval)
+	defer refresh.Stop()
+
+	for {
+		// Without a next run, e.g. while a dynamic schedule never matches,
+		// only a refresh can schedule one
+		var due <-chan time.Time
+		timer := time.NewTimer(0)
+		timer.Stop()
+		if next, ok := queue.next(); ok {
+			timer.Reset(time.Until(next))
+			due = timer.C
+		}
+		select {
+		case <-stopCtx.Done():
+			timer.Stop()
+			return
+		case <-refresh.C:
+			timer.Stop()
+			queue.refresh(time.Now())
+		case <-due:
+			if stopCtx.Err() != nil {
+				return
+			}
+			o.runScheduledCycle(ctx, queue.popDue(time.Now()))
+		}
+	}
+}
+
+// runStartupCycle checks all triggers, including startup-only triggers the
+// first time it is called
+func (o *Orchestrator) runStartupCycle(ctx context.Context) {
+	o.runCycle(ctx, func(ctx context.Context) {
+		o.checkAndExecuteTriggers(ctx, true)
+	})
+}
+
+// runPollCycle checks all triggers except startup-only and scheduled triggers
+func (o *Orchestrator) runPollCycle(ctx context.Context) {
+	o.runCycle(ctx, func(ctx context.Context) {
+		o.checkAndExecuteTriggers(ctx, false)
+		o.retryFailedUnits(ctx)
+		o.flushQueuedNotifications(ctx)
+	})
+}
+
+// queueFlusher is implemented by notification units that queue notifications
+// during quiet hours or retry notifications that failed to send
+type queueFlusher interface {
+	FlushQueued(ctx context.Context) error
+}
+
+// flushQueuedNotifications retries failed notifications and sends
+// notifications queued during quiet hours once they have ended, so they don't
+// wait for the next notification
+func (o *Orchestrator) flushQueuedNotifications(ctx context.Context) {
+	for _, unit := range o.units {
+		if f, ok := unit.(queueFlusher); ok {
+			if err := f.FlushQueued(ctx); err != nil {
+				log.Printf("Error flushing queued notifications of unit '%s': %v", unit.Name(), err)
+			}
+		}
+	}
+}
+
+// runScheduledCycle checks the scheduled triggers that are due
+func (o *Orchestrator) runScheduledCycle(ctx context.Context, due []TriggerUnit) {
+	o.runCycle(ctx, func(ctx context.Context) {
+		// Clear results so units can run again in this cycle
+		o.clearResults()
+		o.checkAndExecute(ctx, due)
+	})
+}
+
+// runCycle runs one trigger check cycle, bounded by the cycle timeout if set
+func (o *Orchestrator) runCycle(ctx context.Context, check func(ctx context.Context)) {
+	o.cycleMu.Lock()
+	defer o.cycleMu.Unlock()
+
+	// Don't start a cycle that was waiting when the daemon was asked to stop
+	if ctx.Err() != nil {
+		return
+	}
+
+	o.mu.Lock()
+	o.cycle++
+	o.mu.Unlock()
+
+	if o.cycleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.cycleTimeout)
+		defer cancel()
+	}
+
+	check(ctx)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Trigger cycle timed out after %s, remaining units were cancelled", o.cycleTimeout)
+	}
+
+	o.mu.Lock()
+	o.cycleResults = maps.Clone(o.results)
+	o.mu.Unlock()
+}
+
+// clearResults starts a new set of results so units can run again
+func (o *Orchestrator) clearResults() {
+	o.mu.Lock()
+	o.results = make(map[string]*UnitResult)
+	o.mu.Unlock()
+}
+
+// chainFinisher is implemented by triggers that need to know when the chain
+// started by their firing has completed, e.g. to skip scheduled times that
+// came due meanwhile
+type chainFinisher interface {
+	ChainFinished()
+}
+
+// isStartupTrigger returns true for triggers that are only checked during the
+// startup cycle
+func isStartupTrigger(unit Unit) bool {
+	return unit.Type() == "trigger.boot" || unit.Type() == "trigger.start" || unit.Type() == "trigger.daemon_start"
+}
+
+// runLifecycleHooks executes the named units for a daemon lifecycle event
+// Triggered units see "brun:<event>" as their triggering unit
+func (o *Orchestrator) runLifecycleHooks(ctx context.Context, event string, unitNames []string) {
+	if len(unitNames) == 0 {
+		return
+	}
+
+	o.clearResults()
+
+	source := "brun:" + event
+	for _, unitName := range unitNames {
+		unit, ok := o.unitsByName[unitName]
+		if !ok {
+			log.Printf("Warning: %s unit '%s' not found", event, unitName)
+			continue
+		}
+
+		o.prepareTarget(unit, source, &UnitResult{})
+		o.resetActivation()
+
+		log.Printf("Running %s unit '%s'", event, unitName)
+		if err := o.executeUnit(ctx, unit, []string{unitName}); err != nil {
+			log.Printf("%s unit '%s' failed: %v", event, unitName, err)
 		}
 	}
 }
@@ -117,147 +687,295 @@ func (o *Orchestrator) RunDaemon(ctx context.Context) error {
 func (o *Orchestrator) checkAndExecuteTriggers(ctx context.Context, isStartup bool) {
 	// Clear results at the start of each check cycle to allow units to be re-executed
 	// in subsequent trigger cycles (e.g., cron triggers firing every minute)
-	o.results = make(map[string]*UnitResult)
+	o.clearResults()
 
-	for _, unit := range o.units {
+	// Startup-only triggers are checked on the first startup cycle only, even
+	// if RunOnce/RunDaemon are invoked more than once on this orchestrator
+	checkStartup := isStartup && !o.startupDone
+	o.startupDone = true
+
+	var triggers []TriggerUnit
+	for _, unit := range o.checkedUnits() {
 		if trigger, ok := unit.(TriggerUnit); ok {
 			// Skip startup-only triggers during polling (only check them on app startup)
-			if !isStartup && (unit.Type() == "trigger.boot" || unit.Type() == "trigger.start") {
+			if !checkStartup && isStartupTrigger(unit) {
 				continue
 			}
 
-			// Pass CheckModePolling during orchestrator polling
-			shouldTrigger, err := trigger.Check(ctx, CheckModePolling)
-			if err != nil {
-				log.Printf("Error checking trigger '%s': %v", unit.Name(), err)
+			// Daemon start triggers only fire when a daemon starts
+			if _, ok := unit.(*DaemonStartTrigger); ok && !o.inDaemon {
 				continue
 			}
 
-			if shouldTrigger {
-				log.Printf("Trigger '%s' activated", unit.Name())
-				// Start with the unit itself in the call stack
-				if err := o.executeUnit(ctx, unit, []string{unit.Name()}); err != nil {
-					log.Printf("Trigger '%s' failed: %v", unit.Name(), err)
-				}
+			// Scheduled triggers are checked at their scheduled time instead
+			// of during polling
+			if _, ok := unit.(scheduledTrigger); ok && !isStartup {
+				continue
+			}
+
+			if !o.pollDue(unit.Name(), isStartup) {
+				continue
 			}
+
+			triggers = append(triggers, trigger)
+		}
+	}
+
+	o.checkAndExecute(ctx, triggers)
+}
+
+// pollDue returns true if the named trigger should be checked in this cycle
+// and records the check time for triggers with their own poll interval. All
+// triggers are checked on startup.
+func (o *Orchestrator) pollDue(name string, isStartup bool) bool {
+	interval := o.pollIntervals[name]
+	if interval <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if last, ok := o.lastPolled[name]; ok && !isStartup && now.Sub(last) < interval {
+		return false
+	}
+	o.lastPolled[name] = now
+	return true
+}
+
+// checkAndExecute checks the given triggers and then executes the activated
+// ones in priority order. Triggers with equal priority keep their order.
+func (o *Orchestrator) checkAndExecute(ctx context.Context, triggers []TriggerUnit) {
+	var activated []TriggerUnit
+	for _, trigger := range triggers {
+		if o.checkTrigger(ctx, trigger) {
+			activated = append(activated, trigger)
 		}
 	}
+
+	slices.SortStableFunc(activated, func(a, b TriggerUnit) int {
+		return o.priorities[b.Name()] - o.priorities[a.Name()]
+	})
+
+	for _, trigger := range activated {
+		log.Printf("Trigger %s activated", o.describe(trigger.Name()))
+		o.resetActivation()
+		// Start with the unit itself in the call stack
+		if err := o.executeUnit(ctx, trigger, []string{trigger.Name()}); err != nil {
+			log.Printf("Trigger %s failed: %v", o.describe(trigger.Name()), err)
+		}
+		if f, ok := trigger.(chainFinisher); ok {
+			f.ChainFinished()
+		}
+	}
+}
+
+// checkTrigger returns true if a trigger unit should fire
+func (o *Orchestrator) checkTrigger(ctx context.Context, trigger TriggerUnit) bool {
+	// Don't check triggers in cooldown, so changes made meanwhile fire the
+	// trigger once the cooldown ends
+	if until, ok := o.cooldownUntil(trigger.Name()); ok && time.Now().Before(until) {
+		log.Printf("Trigger '%s' skipped, in cooldown until %s", trigger.Name(), until.Format(time.RFC3339))
+		return false
+	}
+
+	// Pass CheckModePolling during orchestrator polling
+	shouldTrigger, err := o.check(ctx, trigger, CheckModePolling)
+	if err != nil {
+		log.Printf("Error checking trigger '%s': %v", trigger.Name(), err)
+		o.resetActivation()
+		o.processErrorTriggers(ctx, trigger, err, []string{trigger.Name()})
+		return false
+	}
+
+	if o.edgeTriggers[trigger.Name()] && o.edgeState != nil {
+		shouldTrigger = o.edgeTransition(trigger.Name(), shouldTrigger)
+	}
+
+	if shouldTrigger && o.cooldowns[trigger.Name()] > 0 && o.cooldownState != nil {
+		if err := o.cooldownState.SetString(trigger.Name(), "cooldown_last_fired", time.Now().Format(time.RFC3339)); err != nil {
+			log.Printf("Error saving last fire time of trigger '%s': %v", trigger.Name(), err)
+		}
+	}
+	return shouldTrigger
+}
+
+// edgeTransition records result as the named trigger's last check result and
+// returns true only if the previous result was false
+func (o *Orchestrator) edgeTransition(name string, result bool) bool {
+	last := false
+	if val, ok := o.edgeState.Get(name, "edge_last_result"); ok {
+		if boolVal, ok := val.(bool); ok {
+			last = boolVal
+		}
+	}
+
+	if last != result {
+		if err := o.edgeState.Set(name, "edge_last_result", result); err != nil {
+			log.Printf("Error saving last result of trigger '%s': %v", name, err)
+		}
+	}
+
+	if result && last {
+		log.Printf("Trigger '%s' condition still met, not firing again", name)
+		return false
+	}
+	return result
+}
+
+// cooldownUntil returns the end of the named trigger's cooldown, or false if
+// it has no cooldown or hasn't fired
+func (o *Orchestrator) cooldownUntil(name string) (time.Time, bool) {
+	cooldown := o.cooldowns[name]
+	if cooldown <= 0 || o.cooldownState == nil {
+		return time.Time{}, false
+	}
+	lastFiredStr, ok := o.cooldownState.GetString(name, "cooldown_last_fired")
+	if !ok {
+		return time.Time{}, false
+	}
+	lastFired, err := time.Parse(time.RFC3339, lastFiredStr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return lastFired.Add(cooldown), true
 }
 
 // executeUnit runs a single unit and processes its triggers
 // callStack tracks units in the current execution path to detect circular dependencies
 func (o *Orchestrator) executeUnit(ctx context.Context, unit Unit, callStack []string) error {
-	// Track active unit
-	o.setActiveUnit(unit.Name())
-	defer o.setActiveUnit("")
+	result, _ := o.executeChain(ctx, unit, callStack)
+	if result == nil {
+		return nil
+	}
+	return result.Error
+}
 
-	result := &UnitResult{
-		Unit: unit,
+// executeChain runs a unit and processes its triggers like executeUnit. It
+// returns the unit's result, nil if it was suppressed, and the result of the
+// first unit that failed in the chain, starting with the unit itself, or nil
+// if none did.
+func (o *Orchestrator) executeChain(ctx context.Context, unit Unit, callStack []string) (result, failed *UnitResult) {
+	if o.suppressDestructive(unit) || o.backingOff(unit.Name()) {
+		return nil, nil
 	}
 
-	// Capture output while also displaying it
-	var outputBuf bytes.Buffer
-	oldStdout := os.Stdout
-	oldStderr := os.Stderr
+	result = o.runAndCapture(ctx, unit)
+	err := result.Error
+	o.recordBackoff(unit.Name(), err)
 
-	// Create a pipe to capture output
-	r, w, _ := os.Pipe()
+	if err == nil {
+		o.setArtifacts(unit.Name())
 
-	// Redirect stdout and stderr
-	os.Stdout = w
-	os.Stderr = w
+		// Make the count available to downstream notification units
+		if countUnit, ok := unit.(*CountUnit); ok {
+			o.count = countUnit.Count()
+		}
 
-	// Tee: copy to both buffer (for capturing) and original stdout (for display)
-	done := make(chan bool)
-	go func() {
-		// Use MultiWriter to write to both buffer and original stdout
-		mw := io.MultiWriter(&outputBuf, oldStdout)
-		_, err := io.Copy(mw, r)
-		if err != nil {
-			log.Println("Error copying output buffer: ", err)
+		// Make variables such as the git commit available to downstream units
+		if provider, ok := unit.(envProvider); ok {
+			if o.env == nil {
+				o.env = make(map[string]string)
+			}
+			maps.Copy(o.env, provider.Env())
 		}
-		done <- true
-	}()
+	}
 
-	// Run the unit
-	err := unit.Run(ctx)
-	result.Error = err
+	// Process triggers for all units (not just TriggerUnits)
+	failed = o.processTriggers(ctx, result, callStack)
+	if err != nil {
+		failed = result
+	}
 
-	// Close writer and wait for copy to complete
-	w.Close()
-	<-done
+	return result, failed
+}
 
-	// Restore stdout/stderr
-	os.Stdout = oldStdout
-	os.Stderr = oldStderr
+// runAndCapture runs a unit, capturing its output, and stores its result.
+// Event handlers are notified when the unit starts and completes.
+func (o *Orchestrator) runAndCapture(ctx context.Context, unit Unit) *UnitResult {
+	// Track active unit
+	o.setActiveUnit(unit.Name())
+	defer o.setActiveUnit("")
 
-	// Strip ANSI escape sequences from captured output for cleaner logs/emails
-	// while preserving them in the terminal display
-	result.Output = stripANSI(outputBuf.String())
+	o.emit(Event{Unit: unit.Name(), Phase: EventStarted})
+	start := time.Now()
+
+	result := &UnitResult{
+		Unit: unit,
+	}
+
+	// Capture output while also displaying it
+	result.Output, result.Stdout, result.Stderr = captureOutput(func() {
+		result.Error = runUnit(ctx, unit)
+	})
 
 	// Store result
+	o.mu.Lock()
 	o.results[unit.Name()] = result
+	o.mu.Unlock()
 
-	// Process triggers for all units (not just TriggerUnits)
-	o.processTriggers(ctx, unit, err, result.Output, callStack)
+	o.emit(Event{Unit: unit.Name(), Phase: EventCompleted, Result: result, Duration: time.Since(start)})
 
-	return err
+	return result
 }
 
 // processTriggers handles on_success, on_failure, and always triggers
 // This works for both TriggerUnit and regular Unit types
 // callStack tracks units in the current execution path to detect circular dependencies
-func (o *Orchestrator) processTriggers(ctx context.Context, unit Unit, execErr error, output string, callStack []string) {
+// It returns the result of the first triggered unit that failed, directly or
+// further down the chain, or nil if none did.
+func (o *Orchestrator) processTriggers(ctx context.Context, result *UnitResult, callStack []string) *UnitResult {
+	unit, execErr := result.Unit, result.Error
 	var toTrigger []string
 
-	// Check if this unit has trigger capabilities (on_success, on_failure, always)
-	// Both TriggerUnit and regular units can have these fields
-	switch u := unit.(type) {
-	case TriggerUnit:
-		if execErr == nil {
-			toTrigger = append(toTrigger, u.OnSuccess()...)
-		} else {
-			toTrigger = append(toTrigger, u.OnFailure()...)
-		}
-		toTrigger = append(toTrigger, u.Always()...)
-	case *RunUnit:
-		if execErr == nil {
-			toTrigger = append(toTrigger, u.OnSuccess()...)
-		} else {
-			toTrigger = append(toTrigger, u.OnFailure()...)
-		}
-		toTrigger = append(toTrigger, u.Always()...)
-	case *RebootUnit:
-		if execErr == nil {
-			toTrigger = append(toTrigger, u.OnSuccess()...)
-		} else {
-			toTrigger = append(toTrigger, u.OnFailure()...)
-		}
-		toTrigger = append(toTrigger, u.Always()...)
-	case *LogUnit:
+	if u, ok := unit.(chainTriggerer); ok {
 		if execErr == nil {
 			toTrigger = append(toTrigger, u.OnSuccess()...)
 		} else {
 			toTrigger = append(toTrigger, u.OnFailure()...)
 		}
 		toTrigger = append(toTrigger, u.Always()...)
-	case *CountUnit:
-		if execErr == nil {
-			toTrigger = append(toTrigger, u.OnSuccess()...)
-		} else {
-			toTrigger = append(toTrigger, u.OnFailure()...)
-		}
-		toTrigger = append(toTrigger, u.Always()...)
-	case *EmailUnit:
-		if execErr == nil {
-			toTrigger = append(toTrigger, u.OnSuccess()...)
-		} else {
-			toTrigger = append(toTrigger, u.OnFailure()...)
+	}
+
+	toTrigger = o.appendGlobalTriggers(toTrigger, unit.Name(), execErr)
+	failed := o.runTargets(ctx, unit, result, toTrigger, callStack)
+
+	// With propagate_failure, the unit's on_failure units also run when its
+	// chain failed. They see the failed unit's error and output.
+	if execErr == nil && failed != nil && o.propagateFailure[unit.Name()] {
+		if u, ok := unit.(failureTriggerer); ok && len(u.OnFailure()) > 0 {
+			log.Printf("Unit '%s' failed downstream of '%s', running its on_failure units", failed.Unit.Name(), unit.Name())
+			propagated := &UnitResult{
+				Unit:   unit,
+				Error:  fmt.Errorf("unit '%s' failed: %w", failed.Unit.Name(), failed.Error),
+				Output: failed.Output,
+				Stdout: failed.Stdout,
+				Stderr: failed.Stderr,
+			}
+			o.runTargets(ctx, unit, propagated, u.OnFailure(), callStack)
 		}
-		toTrigger = append(toTrigger, u.Always()...)
 	}
 
-	// Execute triggered units
+	return failed
+}
+
+// chainTriggerer is implemented by units with on_success, on_failure, and
+// always triggers, which is every unit type configurable with them
+type chainTriggerer interface {
+	OnSuccess() []string
+	OnFailure() []string
+	Always() []string
+}
+
+// failureTriggerer is implemented by units with on_failure triggers
+type failureTriggerer interface {
+	OnFailure() []string
+}
+
+// runTargets runs the units named in toTrigger on behalf of unit, which
+// produced result. Trigger units run only if their condition is met. It
+// returns the result of the first unit that failed in their chains, or nil if
+// none did.
+func (o *Orchestrator) runTargets(ctx context.Context, unit Unit, result *UnitResult, toTrigger, callStack []string) *UnitResult {
+	var failed *UnitResult
 	for _, unitName := range toTrigger {
 		targetUnit, ok := o.unitsByName[unitName]
 		if !ok {
@@ -265,30 +983,7 @@ func (o *Orchestrator) processTriggers(ctx context.Context, unit Unit, execErr e
 			continue
 		}
 
-		// If it's a log unit, pass the output and triggering unit name
-		if logUnit, ok := targetUnit.(*LogUnit); ok {
-			logUnit.SetOutput(output)
-			logUnit.SetTriggeringUnit(unit.Name())
-		}
-
-		// If it's a count unit, pass the triggering unit name
-		if countUnit, ok := targetUnit.(*CountUnit); ok {
-			countUnit.SetTriggeringUnit(unit.Name())
-		}
-
-		// If it's an email unit, pass the output, triggering unit name, and error
-		if emailUnit, ok := targetUnit.(*EmailUnit); ok {
-			emailUnit.SetOutput(output)
-			emailUnit.SetTriggeringUnit(unit.Name())
-			emailUnit.SetTriggerError(execErr)
-		}
-
-		// If it's an ntfy unit, pass the output, triggering unit name, and error
-		if ntfyUnit, ok := targetUnit.(*NtfyUnit); ok {
-			ntfyUnit.SetOutput(output)
-			ntfyUnit.SetTriggeringUnit(unit.Name())
-			ntfyUnit.SetTriggerError(execErr)
-		}
+		o.prepareTarget(targetUnit, unit.Name(), result)
 
 		// Check if this unit is already in the current call stack (circular dependency)
 		inCallStack := false
@@ -306,29 +1001,255 @@ func (o *Orchestrator) processTriggers(ctx context.Context, unit Unit, execErr e
 
 		// If the target is a trigger unit, check its condition before executing
 		if triggerUnit, ok := targetUnit.(TriggerUnit); ok {
-			// Pass CheckModeManual when another unit triggers this one
-			shouldTrigger, err := triggerUnit.Check(ctx, CheckModeManual)
+			// Pass CheckModeManual when another unit triggers this one. A
+			// forced trigger is still checked, since the check does work
+			// such as updating a git workspace, but runs either way.
+			shouldTrigger, err := o.check(ctx, triggerUnit, CheckModeManual)
 			if err != nil {
 				log.Printf("Error checking trigger '%s': %v", unitName, err)
+				o.processErrorTriggers(ctx, triggerUnit, err, append(callStack, unitName))
 				continue
 			}
-			if !shouldTrigger {
+			switch {
+			case slices.Contains(o.forcedTriggers[unit.Name()], unitName):
+				log.Printf("Trigger '%s' forced by '%s', executing regardless of its condition...", unitName, unit.Name())
+			case !shouldTrigger:
 				log.Printf("Trigger '%s' condition not met, skipping execution", unitName)
 				continue
+			default:
+				log.Printf("Trigger '%s' condition met, executing...", unitName)
 			}
-			log.Printf("Trigger '%s' condition met, executing...", unitName)
 		}
 
 		// Add current unit to call stack for downstream execution
 		newCallStack := append(callStack, unitName)
 
-		log.Printf("Triggering unit '%s'", unitName)
-		if err := o.executeUnit(ctx, targetUnit, newCallStack); err != nil {
-			log.Printf("Triggered unit '%s' failed: %v", unitName, err)
+		log.Printf("Triggering unit %s", o.describe(unitName))
+		targetResult, targetFailed := o.executeChain(ctx, targetUnit, newCallStack)
+		if targetResult != nil && targetResult.Error != nil {
+			log.Printf("Triggered unit %s failed: %v", o.describe(unitName), targetResult.Error)
+		}
+		if failed == nil {
+			failed = targetFailed
+		}
+	}
+	return failed
+}
+
+// processErrorTriggers runs the on_error units of a trigger whose Check
+// returned err. The units see the trigger as their triggering unit and err as
+// its error.
+func (o *Orchestrator) processErrorTriggers(ctx context.Context, trigger TriggerUnit, err error, callStack []string) {
+	result := &UnitResult{Unit: trigger, Error: err}
+	for _, unitName := range o.errorTriggers[trigger.Name()] {
+		targetUnit, ok := o.unitsByName[unitName]
+		if !ok {
+			log.Printf("Warning: referenced unit '%s' not found", unitName)
+			continue
+		}
+		if slices.Contains(callStack, unitName) {
+			log.Printf("Unit '%s' already in call stack, skipping to prevent circular dependency", unitName)
+			continue
+		}
+
+		o.prepareTarget(targetUnit, trigger.Name(), result)
+
+		log.Printf("Triggering unit %s on error of trigger '%s'", o.describe(unitName), trigger.Name())
+		if err := o.executeUnit(ctx, targetUnit, append(callStack, unitName)); err != nil {
+			log.Printf("Triggered unit %s failed: %v", o.describe(unitName), err)
 		}
 	}
 }
 
+// checkUnit calls trigger.Check, converting a panic into an error so a
+// misbehaving unit can't take down the daemon
+func checkUnit(ctx context.Context, trigger TriggerUnit, mode CheckMode) (shouldTrigger bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = unitPanic(trigger.Name(), "Check", r)
+		}
+	}()
+	return trigger.Check(ctx, mode)
+}
+
+// maxTriggerLog is the number of trigger checks kept for GetTriggerLog
+const maxTriggerLog = 1000
+
+// TriggerCheck records one call to a trigger's Check method
+type TriggerCheck struct {
+	Cycle  int // Trigger cycle the check ran in, 0 for checks outside a cycle
+	Unit   string
+	Mode   CheckMode
+	Result bool  // Value returned by Check, before cooldown and edge_trigger
+	Err    error // Error returned by Check
+	Time   time.Time
+}
+
+// check calls the trigger's Check method and records the call in the
+// trigger log
+func (o *Orchestrator) check(ctx context.Context, trigger TriggerUnit, mode CheckMode) (bool, error) {
+	start := time.Now()
+	shouldTrigger, err := checkUnit(ctx, trigger, mode)
+
+	o.mu.Lock()
+	o.triggerLog = append(o.triggerLog, TriggerCheck{
+		Cycle:  o.cycle,
+		Unit:   trigger.Name(),
+		Mode:   mode,
+		Result: shouldTrigger,
+		Err:    err,
+		Time:   start,
+	})
+	if len(o.triggerLog) > maxTriggerLog {
+		o.triggerLog = slices.Delete(o.triggerLog, 0, len(o.triggerLog)-maxTriggerLog)
+	}
+	o.mu.Unlock()
+
+	return shouldTrigger, err
+}
+
+// GetTriggerLog returns the most recent trigger checks, oldest first, so
+// tests can assert which triggers were checked and which fired in each cycle
+func (o *Orchestrator) GetTriggerLog() []TriggerCheck {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return slices.Clone(o.triggerLog)
+}
+
+// runUnit calls unit.Run, converting a panic into an error so the unit fails
+// and its on_failure triggers fire
+func runUnit(ctx context.Context, unit Unit) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = unitPanic(unit.Name(), "Run", r)
+		}
+	}()
+	return unit.Run(ctx)
+}
+
+// unitPanic logs a recovered panic with its stack trace and returns it as an error
+func unitPanic(unitName, method string, r any) error {
+	log.Printf("Unit '%s' panicked in %s: %v\n%s", unitName, method, r, debug.Stack())
+	return fmt.Errorf("panic in %s: %v", method, r)
+}
+
+// appendGlobalTriggers adds the on_any_success/on_any_failure units for a
+// completed unit, skipping units the unit already triggers. Units that are
+// themselves global targets don't fire global triggers to avoid cascades.
+func (o *Orchestrator) appendGlobalTriggers(toTrigger []string, unitName string, execErr error) []string {
+	if slices.Contains(o.onAnySuccess, unitName) || slices.Contains(o.onAnyFailure, unitName) {
+		return toTrigger
+	}
+
+	global := o.onAnySuccess
+	if execErr != nil {
+		global = o.onAnyFailure
+	}
+
+	for _, name := range global {
+		if !slices.Contains(toTrigger, name) {
+			toTrigger = append(toTrigger, name)
+		}
+	}
+
+	return toTrigger
+}
+
+// envProvider is implemented by units that publish environment variables to
+// the units they trigger
+type envProvider interface {
+	// Env returns the variables set by the last run
+	Env() map[string]string
+}
+
+// resetActivation clears the artifacts, count, and environment passed along
+// the chain before a new activation
+func (o *Orchestrator) resetActivation() {
+	o.artifacts = make(map[string]string)
+	o.count = 0
+	o.env = make(map[string]string)
+}
+
+// setArtifacts records the artifacts declared by a successfully completed unit
+// Values may reference artifacts set earlier in the activation
+func (o *Orchestrator) setArtifacts(unitName string) {
+	decls := o.artifactDecls[unitName]
+	if len(decls) == 0 {
+		return
+	}
+
+	if o.artifacts == nil {
+		o.artifacts = make(map[string]string)
+	}
+
+	expanded := make(map[string]string, len(decls))
+	for name, value := range decls {
+		expanded[name] = expandArtifacts(value, o.artifacts)
+	}
+	for name, value := range expanded {
+		log.Printf("Unit '%s' set artifact '%s' = '%s'", unitName, name, value)
+		o.artifacts[name] = value
+	}
+}
+
+// prepareTarget passes information about the triggering unit to units that use it
+func (o *Orchestrator) prepareTarget(targetUnit Unit, source string, result *UnitResult) {
+	// If it's a run unit, pass the artifacts set so far in this activation
+	if runUnit, ok := targetUnit.(*RunUnit); ok {
+		artifacts := make(map[string]string, len(o.artifacts))
+		for name, value := range o.artifacts {
+			artifacts[name] = value
+		}
+		runUnit.SetArtifacts(artifacts)
+		runUnit.SetEnv(maps.Clone(o.env))
+	}
+
+	// If it's a log unit, pass the output, triggering unit name, and error
+	if logUnit, ok := targetUnit.(*LogUnit); ok {
+		logUnit.SetOutput(result.Output)
+		logUnit.SetTriggeringUnit(source)
+		logUnit.SetTriggerError(result.Error)
+	}
+
+	// If it's a reboot unit, pass the triggering unit name and error to record
+	// as the reboot reason
+	if rebootUnit, ok := targetUnit.(*RebootUnit); ok {
+		rebootUnit.SetTriggeringUnit(source)
+		rebootUnit.SetTriggerError(result.Error)
+	}
+
+	// If it's a digest unit, pass the triggering unit name and error to record
+	if digestUnit, ok := targetUnit.(*DigestUnit); ok {
+		digestUnit.SetTriggeringUnit(source)
+		digestUnit.SetTriggerError(result.Error)
+	}
+
+	// If it's a count unit, pass the triggering unit name
+	if countUnit, ok := targetUnit.(*CountUnit); ok {
+		countUnit.SetTriggeringUnit(source)
+	}
+
+	// If it's an email unit, pass the output, triggering unit name, error, and count
+	if emailUnit, ok := targetUnit.(*EmailUnit); ok {
+		emailUnit.SetOutput(result.Output)
+		emailUnit.SetTriggeringUnit(source)
+		emailUnit.SetStderr(result.Stderr)
+		emailUnit.SetCount(o.count)
+		emailUnit.SetTriggerError(result.Error)
+		emailUnit.SetEnv(maps.Clone(o.env))
+	}
+
+	// If it's an ntfy unit, pass the output, triggering unit name, error, and count
+	if ntfyUnit, ok := targetUnit.(*NtfyUnit); ok {
+		ntfyUnit.SetOutput(result.Output)
+		ntfyUnit.SetTriggeringUnit(source)
+		ntfyUnit.SetStderr(result.Stderr)
+		ntfyUnit.SetCount(o.count)
+		ntfyUnit.SetTriggerError(result.Error)
+		ntfyUnit.SetEnv(maps.Clone(o.env))
+	}
+}
+
 // RunSingleUnit executes a single unit by name
 // If runTriggers is true, the unit runs and all its triggers are executed
 // If runTriggers is false, the unit runs in isolation without executing its triggers
@@ -340,14 +1261,19 @@ func (o *Orchestrator) RunSingleUnit(ctx context.Context, unitName string, runTr
 
 	log.Printf("Executing single unit '%s'...", unitName)
 
-	// Clear results
-	o.results = make(map[string]*UnitResult)
+	// Clear results and the data passed along the chain
+	o.clearResults()
+	o.resetActivation()
+
+	// Destructive units are suppressed while debugging unless allowed
+	o.singleRun = true
+	defer func() { o.singleRun = false }()
 
 	if runTriggers {
 		// For trigger units, check if the trigger condition is met first
-		if triggerUnit, ok := unit.(TriggerUnit); ok {
+		if triggerUnit, ok := unit.(TriggerUnit); ok && !o.skipSingleCheck {
 			// Pass CheckModeManual for manual execution
-			shouldTrigger, err := triggerUnit.Check(ctx, CheckModeManual)
+			shouldTrigger, err := o.check(ctx, triggerUnit, CheckModeManual)
 			if err != nil {
 				log.Printf("Error checking trigger '%s': %v", unitName, err)
 				return err
@@ -377,67 +1303,82 @@ func (o *Orchestrator) RunSingleUnit(ctx context.Context, unitName string, runTr
 	return nil
 }
 
-// executeUnitNoTriggers runs a single unit without processing its triggers
-func (o *Orchestrator) executeUnitNoTriggers(ctx context.Context, unit Unit) error {
-	// Track active unit
-	o.setActiveUnit(unit.Name())
-	defer o.setActiveUnit("")
-
-	result := &UnitResult{
-		Unit: unit,
+// RunTimerTrigger runs the named trigger and the units it triggers without
+// checking its condition, for a systemd timer that has decided it's time to
+// run. Unlike RunSingleUnit it isn't a debug run, so reboot and destructive
+// units run and failure backoffs apply as in the daemon.
+func (o *Orchestrator) RunTimerTrigger(ctx context.Context, name string) error {
+	unit, ok := o.unitsByName[name]
+	if !ok {
+		return fmt.Errorf("unit '%s' not found", name)
+	}
+	if _, ok := unit.(TriggerUnit); !ok {
+		return fmt.Errorf("unit '%s' is not a trigger", name)
 	}
 
-	// Capture output while also displaying it
-	var outputBuf bytes.Buffer
-	oldStdout := os.Stdout
-	oldStderr := os.Stderr
-
-	// Create a pipe to capture output
-	r, w, _ := os.Pipe()
-
-	// Redirect stdout and stderr
-	os.Stdout = w
-	os.Stderr = w
-
-	// Tee: copy to both buffer (for capturing) and original stdout (for display)
-	done := make(chan bool)
-	go func() {
-		// Use MultiWriter to write to both buffer and original stdout
-		mw := io.MultiWriter(&outputBuf, oldStdout)
-		_, err := io.Copy(mw, r)
-		if err != nil {
-			log.Println("Error copying output buffer: ", err)
-		}
-		done <- true
-	}()
-
-	// Run the unit
-	err := unit.Run(ctx)
-	result.Error = err
+	log.Printf("Trigger %s started by its timer", o.describe(name))
 
-	// Close writer and wait for copy to complete
-	w.Close()
-	<-done
+	o.clearResults()
+	o.resetActivation()
+	// Start with the unit itself in the call stack
+	if err := o.executeUnit(ctx, unit, []string{name}); err != nil {
+		log.Printf("Trigger %s failed: %v", o.describe(name), err)
+		return err
+	}
+	return nil
+}
 
-	// Restore stdout/stderr
-	os.Stdout = oldStdout
-	os.Stderr = oldStderr
+// suppressDestructive returns true, and logs it, if unit is destructive and
+// must not run because RunSingleUnit is executing without allowDestructive.
+// Suppressed units don't run and don't fire their triggers.
+func (o *Orchestrator) suppressDestructive(unit Unit) bool {
+	if !o.singleRun || o.allowDestructive {
+		return false
+	}
+	if unit.Type() != "reboot" && !o.destructive[unit.Name()] {
+		return false
+	}
+	log.Printf("Destructive unit '%s' suppressed (use -allow-destructive to run it)", unit.Name())
+	return true
+}
 
-	// Strip ANSI escape sequences from captured output for cleaner logs/emails
-	// while preserving them in the terminal display
-	result.Output = stripANSI(outputBuf.String())
+// executeUnitNoTriggers runs a single unit without processing its triggers
+func (o *Orchestrator) executeUnitNoTriggers(ctx context.Context, unit Unit) error {
+	if o.suppressDestructive(unit) {
+		return nil
+	}
 
-	// Store result
-	o.results[unit.Name()] = result
+	result := o.runAndCapture(ctx, unit)
 
 	// Do NOT process triggers in this method
 
-	return err
+	return result.Error
 }
 
-// GetResults returns all execution results
+// GetResults returns a copy of the execution results of the current or latest
+// run, keyed by unit name. While a cycle is running, it only includes the
+// units that have completed so far.
 func (o *Orchestrator) GetResults() map[string]*UnitResult {
-	return o.results
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return maps.Clone(o.results)
+}
+
+// result returns the named unit's result in the current or latest run
+func (o *Orchestrator) result(name string) (*UnitResult, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	result, ok := o.results[name]
+	return result, ok
+}
+
+// GetLastCycleResults returns a copy of the execution results of the last
+// completed trigger cycle, keyed by unit name, so readers get a consistent
+// view while the next cycle runs. It is nil until a cycle has completed.
+func (o *Orchestrator) GetLastCycleResults() map[string]*UnitResult {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return maps.Clone(o.cycleResults)
 }
 
 // GetActiveUnit returns the name of the currently executing unit, or empty string if none
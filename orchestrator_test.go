@@ -2,8 +2,11 @@ package brun
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -436,3 +439,956 @@ func TestOrchestrator_GitTriggerWithNoPollInterval(t *testing.T) {
 		t.Error("build unit SHOULD have executed because git-trigger ran successfully")
 	}
 }
+
+// TestOrchestrator_LifecycleHooks verifies that on_start units run when the
+// daemon starts and on_shutdown units run when it stops
+func TestOrchestrator_LifecycleHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.yaml")
+
+	state := NewState(stateFile)
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	counter := NewCountUnit("counter", state, nil, nil, nil)
+
+	orchestrator := NewOrchestrator([]Unit{counter})
+	orchestrator.SetLifecycleHooks([]string{"counter"}, []string{"counter"})
+
+	// Cancel up front so the daemon runs its startup hooks and then
+	// immediately shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := orchestrator.RunDaemon(ctx); err != context.Canceled {
+		t.Fatalf("RunDaemon() = %v, want context.Canceled", err)
+	}
+
+	count, _ := state.Get("counter", "brun:on_start")
+	if count != 1 {
+		t.Errorf("on_start count = %v, want 1", count)
+	}
+
+	count, _ = state.Get("counter", "brun:on_shutdown")
+	if count != 1 {
+		t.Errorf("on_shutdown count = %v, want 1", count)
+	}
+}
+
+// TestOrchestrator_StartTriggerFiresOnce verifies that a start trigger fires
+// only once per orchestrator even if startup checks run more than once
+func TestOrchestrator_StartTriggerFiresOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.yaml")
+
+	state := NewState(stateFile)
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	startTrigger := NewStartTrigger("start", []string{"counter"}, nil, nil)
+	counter := NewCountUnit("counter", state, nil, nil, nil)
+
+	orchestrator := NewOrchestrator([]Unit{startTrigger, counter})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := orchestrator.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce() failed: %v", err)
+		}
+	}
+
+	count, _ := state.Get("counter", "start")
+	if count != 1 {
+		t.Errorf("Start trigger fired %v time(s), want 1", count)
+	}
+}
+
+// newLifecycleTestUnits creates boot, start, and cron triggers that all feed a
+// shared counter. The cron schedule is due in the current minute only.
+func newLifecycleTestUnits(t *testing.T, state *State) []Unit {
+	t.Helper()
+
+	// Avoid straddling a minute boundary while the test runs
+	if time.Now().Second() >= 57 {
+		time.Sleep(4 * time.Second)
+	}
+
+	now := time.Now()
+	schedule := fmt.Sprintf("%d %d * * *", now.Minute(), now.Hour())
+	if err := state.SetString("cron", "last_execution", now.Add(-24*time.Hour).Format(time.RFC3339)); err != nil {
+		t.Fatalf("Failed to set last execution: %v", err)
+	}
+
+	cronTrigger, err := NewCronTrigger("cron", schedule, state, 0, []string{"counter"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+
+	return []Unit{
+		NewBootTrigger("boot", state, []string{"counter"}, nil, nil),
+		NewStartTrigger("start", []string{"counter"}, nil, nil),
+		cronTrigger,
+		NewCountUnit("counter", state, nil, nil, nil),
+	}
+}
+
+// checkLifecycleCounts verifies each trigger fired exactly once
+func checkLifecycleCounts(t *testing.T, state *State) {
+	t.Helper()
+	for _, name := range []string{"boot", "start", "cron"} {
+		count, _ := state.Get("counter", name)
+		if count != 1 {
+			t.Errorf("%s trigger fired %v time(s), want 1", name, count)
+		}
+	}
+}
+
+// TestOrchestrator_LifecycleRunOnce verifies trigger semantics for repeated
+// one-shot runs on the same orchestrator
+func TestOrchestrator_LifecycleRunOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	orchestrator := NewOrchestrator(newLifecycleTestUnits(t, state))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := orchestrator.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce() failed: %v", err)
+		}
+	}
+
+	checkLifecycleCounts(t, state)
+}
+
+// TestOrchestrator_LifecycleDaemon verifies trigger semantics across the
+// daemon startup cycle and several poll cycles
+func TestOrchestrator_LifecycleDaemon(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	orchestrator := NewOrchestrator(newLifecycleTestUnits(t, state))
+	orchestrator.pollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := orchestrator.RunDaemon(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("RunDaemon() = %v, want context.DeadlineExceeded", err)
+	}
+
+	checkLifecycleCounts(t, state)
+}
+
+// TestOrchestrator_DaemonStart verifies that a daemon start trigger fires once
+// per daemon start and never in one-shot runs
+func TestOrchestrator_DaemonStart(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	newUnits := func() []Unit {
+		return []Unit{
+			NewDaemonStartTrigger("resync", []string{"counter"}, nil, nil),
+			NewCountUnit("counter", state, nil, nil, nil),
+		}
+	}
+
+	if err := NewOrchestrator(newUnits()).RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+	if _, ok := state.Get("counter", "resync"); ok {
+		t.Error("Expected daemon start trigger not to fire in a one-shot run")
+	}
+
+	// Each daemon start, e.g. a service restart, fires it once
+	for i := 0; i < 2; i++ {
+		orchestrator := NewOrchestrator(newUnits())
+		orchestrator.pollInterval = 10 * time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		if err := orchestrator.RunDaemon(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("RunDaemon() = %v, want context.DeadlineExceeded", err)
+		}
+		cancel()
+	}
+	if count, _ := state.Get("counter", "resync"); count != 2 {
+		t.Errorf("Daemon start trigger fired %v time(s), want 2", count)
+	}
+}
+
+// TestOrchestrator_SkipIfRunning verifies that a trigger with skip_if_running
+// doesn't fire for a scheduled time that came due while the chain from its
+// previous firing was still running
+func TestOrchestrator_SkipIfRunning(t *testing.T) {
+	for _, skip := range []bool{true, false} {
+		tmpDir := t.TempDir()
+		state := NewState(filepath.Join(tmpDir, "state.yaml"))
+		if err := state.Load(); err != nil {
+			t.Fatalf("Failed to load state: %v", err)
+		}
+
+		cronTrigger, err := NewCronTrigger("cron", "* * * * *", state, 0, []string{"slow", "counter"}, nil, nil)
+		if err != nil {
+			t.Fatalf("NewCronTrigger failed: %v", err)
+		}
+		cronTrigger.SetSkipIfRunning(skip)
+
+		// Start the clock just before a minute boundary, so the slow chain
+		// is still running when the next scheduled time comes due
+		boundary := time.Date(2025, 1, 1, 12, 1, 0, 0, time.Local)
+		start := time.Now()
+		cronTrigger.now = func() time.Time {
+			return boundary.Add(-300 * time.Millisecond).Add(time.Since(start))
+		}
+
+		slow := NewRunUnit("slow", "sleep 0.6", tmpDir, 0, "", false, nil, nil, nil)
+		counter := NewCountUnit("counter", state, nil, nil, nil)
+		orchestrator := NewOrchestrator([]Unit{cronTrigger, slow, counter})
+
+		ctx := context.Background()
+		for range 2 {
+			if err := orchestrator.RunOnce(ctx); err != nil {
+				t.Fatalf("RunOnce() failed: %v", err)
+			}
+		}
+
+		want := 1
+		if !skip {
+			want = 2
+		}
+		if count, _ := state.Get("counter", "cron"); count != want {
+			t.Errorf("skip_if_running %v: cron fired %v time(s), want %d", skip, count, want)
+		}
+	}
+}
+
+// TestOrchestrator_RunTimerTrigger verifies that a reboot unit downstream of
+// a trigger run from a systemd timer executes, unlike in a -trigger debug run
+func TestOrchestrator_RunTimerTrigger(t *testing.T) {
+	defer func(cmd string) { rebootCommand = cmd }(rebootCommand)
+	rebootCommand = "true"
+
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	cronTrigger, err := NewCronTrigger("nightly", "0 2 * * *", state, 0, []string{"reboot"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+	reboot := NewRebootUnit("reboot", 0, state, nil, nil, nil)
+	orchestrator := NewOrchestrator([]Unit{cronTrigger, reboot})
+
+	ctx := context.Background()
+	orchestrator.SetSkipSingleCheck(true)
+	if err := orchestrator.RunSingleUnit(ctx, "nightly", true); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+	if _, ok := state.GetString("reboot", "last_reboot_time"); ok {
+		t.Fatal("Expected reboot to be suppressed in a -trigger run")
+	}
+
+	if err := orchestrator.RunTimerTrigger(ctx, "nightly"); err != nil {
+		t.Fatalf("RunTimerTrigger() failed: %v", err)
+	}
+	if _, ok := state.GetString("reboot", "last_reboot_time"); !ok {
+		t.Error("Expected reboot to run from the timer")
+	}
+
+	if err := orchestrator.RunTimerTrigger(ctx, "reboot"); err == nil {
+		t.Error("Expected error for a unit that isn't a trigger")
+	}
+}
+
+// TestOrchestrator_Artifacts verifies that artifacts set by a unit are
+// available to downstream run units
+func TestOrchestrator_Artifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	outFile := filepath.Join(tmpDir, "out.txt")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - start:
+      name: start
+      on_success:
+        - build
+  - run:
+      name: build
+      script: echo building
+      set_artifact:
+        image_path: /out/image.bin
+      on_success:
+        - deploy
+  - run:
+      name: deploy
+      script: echo "${artifact.image_path} $BRUN_ARTIFACT_IMAGE_PATH" > ` + outFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetUnitArtifacts(config.UnitArtifacts())
+
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(data) != "/out/image.bin /out/image.bin\n" {
+		t.Errorf("Expected artifact values in output, got %q", string(data))
+	}
+}
+
+// TestOrchestrator_GlobalTriggers verifies on_any_success/on_any_failure fire
+// after every unit without double firing targets the unit already triggers
+func TestOrchestrator_GlobalTriggers(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	units := []Unit{
+		NewStartTrigger("start", []string{"ok-unit", "fail-unit"}, nil, nil),
+		NewRunUnit("ok-unit", "true", "", 0, "", false, nil, nil, nil),
+		NewRunUnit("fail-unit", "exit 1", "", 0, "", false, nil, []string{"failures"}, nil),
+		NewCountUnit("successes", state, nil, nil, nil),
+		NewCountUnit("failures", state, nil, nil, nil),
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetGlobalTriggers([]string{"successes"}, []string{"failures"})
+
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	for _, name := range []string{"start", "ok-unit"} {
+		if count, _ := state.Get("successes", name); count != 1 {
+			t.Errorf("successes count for %s = %v, want 1", name, count)
+		}
+	}
+	if _, ok := state.Get("successes", "fail-unit"); ok {
+		t.Error("successes should not be triggered by fail-unit")
+	}
+
+	// fail-unit routes to failures itself, so the global trigger must not double fire
+	if count, _ := state.Get("failures", "fail-unit"); count != 1 {
+		t.Errorf("failures count for fail-unit = %v, want 1", count)
+	}
+
+	// Global targets don't trigger global units themselves
+	if _, ok := state.Get("successes", "failures"); ok {
+		t.Error("global targets should not fire global triggers")
+	}
+}
+
+// TestOrchestrator_CycleTimeout verifies that a runaway cycle is cancelled
+// when the cycle timeout expires
+func TestOrchestrator_CycleTimeout(t *testing.T) {
+	units := []Unit{
+		NewStartTrigger("start", []string{"hang"}, nil, nil),
+		NewRunUnit("hang", "sleep 10", "", 0, "", false, nil, nil, nil),
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetCycleTimeout(200 * time.Millisecond)
+
+	start := time.Now()
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Cycle took %v, expected it to be cancelled by the cycle timeout", elapsed)
+	}
+
+	result, ok := orchestrator.GetResults()["hang"]
+	if !ok {
+		t.Fatal("hang unit should have executed")
+	}
+	if result.Error == nil {
+		t.Error("hang unit should have failed when the cycle timed out")
+	}
+}
+
+// panicUnit is a trigger unit that panics in Check or Run
+type panicUnit struct {
+	name       string
+	panicCheck bool
+	onFailure  []string
+}
+
+func (p *panicUnit) Name() string { return p.name }
+func (p *panicUnit) Type() string { return "trigger.panic" }
+
+func (p *panicUnit) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	if p.panicCheck {
+		panic("check exploded")
+	}
+	return true, nil
+}
+
+func (p *panicUnit) Run(ctx context.Context) error {
+	panic("run exploded")
+}
+
+func (p *panicUnit) OnSuccess() []string { return nil }
+func (p *panicUnit) OnFailure() []string { return p.onFailure }
+func (p *panicUnit) Always() []string    { return nil }
+
+func TestOrchestrator_RecoversFromPanics(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	units := []Unit{
+		&panicUnit{name: "bad-check", panicCheck: true},
+		&panicUnit{name: "bad-run", onFailure: []string{"failures"}},
+		NewStartTrigger("start", []string{"successes"}, nil, nil),
+		NewCountUnit("successes", state, nil, nil, nil),
+		NewCountUnit("failures", state, nil, nil, nil),
+	}
+
+	orchestrator := NewOrchestrator(units)
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	// Other triggers still run after a panicking Check
+	if count, _ := state.Get("successes", "start"); count != 1 {
+		t.Errorf("successes count for start = %v, want 1", count)
+	}
+
+	// A panicking Run is a failure that fires on_failure
+	if count, _ := state.Get("failures", "bad-run"); count != 1 {
+		t.Errorf("failures count for bad-run = %v, want 1", count)
+	}
+
+	result := orchestrator.GetResults()["bad-run"]
+	if result == nil || result.Error == nil || !strings.Contains(result.Error.Error(), "run exploded") {
+		t.Errorf("Expected panic error in bad-run result, got %+v", result)
+	}
+}
+
+// TestOrchestrator_CapturesStdoutAndStderr verifies that stdout and stderr are
+// captured separately as well as combined, and passed on to notification units
+func TestOrchestrator_CapturesStdoutAndStderr(t *testing.T) {
+	build := NewRunUnit("build", "echo out; echo err >&2; exit 1", "", 0, "", false, nil, nil, nil)
+	email := NewEmailUnit("email", []string{"user@example.com"}, "brun@example.com", "",
+		"smtp.example.com", 587, "", "", true, true, 0, nil, nil, nil)
+	email.SetStderrOnFailure(true)
+
+	orchestrator := NewOrchestrator([]Unit{build, email})
+	if err := orchestrator.RunSingleUnit(context.Background(), "build", false); err == nil {
+		t.Fatal("Expected build to fail")
+	}
+
+	result := orchestrator.results["build"]
+	if result.Stdout != "out\n" {
+		t.Errorf("Expected stdout %q, got %q", "out\n", result.Stdout)
+	}
+	if result.Stderr != "err\n" {
+		t.Errorf("Expected stderr %q, got %q", "err\n", result.Stderr)
+	}
+	if !strings.Contains(result.Output, "out\n") || !strings.Contains(result.Output, "err\n") {
+		t.Errorf("Expected combined output to contain both streams, got %q", result.Output)
+	}
+
+	orchestrator.prepareTarget(email, "build", result)
+	body := email.buildBody("build", "2025-01-01T00:00:00Z")
+	if !strings.Contains(body, "err") || strings.Contains(body, "out") {
+		t.Errorf("Expected only stderr in body, got: %s", body)
+	}
+}
+
+// TestOrchestrator_TriggerPriority verifies that triggers firing in the same
+// cycle run in priority order, falling back to config order for ties
+func TestOrchestrator_TriggerPriority(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	outFile := filepath.Join(tmpDir, "order.txt")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - start:
+      name: nightly-report
+      on_success:
+        - report
+  - start:
+      name: cleanup
+      on_success:
+        - clean
+  - start:
+      name: critical-backup
+      trigger_priority: 10
+      on_success:
+        - backup
+  - run:
+      name: report
+      script: echo report >> ` + outFile + `
+  - run:
+      name: clean
+      script: echo clean >> ` + outFile + `
+  - run:
+      name: backup
+      script: echo backup >> ` + outFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetUnitPriorities(config.UnitPriorities())
+
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(data) != "backup\nreport\nclean\n" {
+		t.Errorf("Expected backup to run first, got %q", string(data))
+	}
+}
+
+func TestOrchestrator_SuppressDestructiveInSingleRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	wipedFile := filepath.Join(tmpDir, "wiped")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tmpDir, "state.yaml") + `
+
+units:
+  - run:
+      name: build
+      script: "true"
+      on_success:
+        - wipe
+        - restart
+  - run:
+      name: wipe
+      destructive: true
+      script: touch ` + wipedFile + `
+  - reboot:
+      name: restart
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetDestructiveUnits(config.DestructiveUnits())
+
+	if err := orchestrator.RunSingleUnit(context.Background(), "build", true); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+	results := orchestrator.GetResults()
+	if _, ok := results["restart"]; ok {
+		t.Error("Expected reboot unit to be suppressed")
+	}
+	if _, err := os.Stat(wipedFile); !os.IsNotExist(err) {
+		t.Error("Expected destructive unit to be suppressed")
+	}
+
+	orchestrator.SetAllowDestructive(true)
+	if err := orchestrator.RunSingleUnit(context.Background(), "wipe", false); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+	if _, err := os.Stat(wipedFile); err != nil {
+		t.Errorf("Expected destructive unit to run when allowed: %v", err)
+	}
+}
+
+func TestOrchestrator_Cooldown(t *testing.T) {
+	tmpDir := t.TempDir()
+	watchFile := filepath.Join(tmpDir, "src.txt")
+	if err := os.WriteFile(watchFile, []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	trigger := NewFileTrigger("watch", filepath.Join(tmpDir, "*.txt"), state, nil, nil, nil)
+	orchestrator := NewOrchestrator([]Unit{trigger})
+	orchestrator.SetCooldowns(map[string]time.Duration{"watch": time.Hour}, state)
+
+	// First check records the baseline and fires
+	if !orchestrator.checkTrigger(context.Background(), trigger) {
+		t.Fatal("Expected trigger to fire on first check")
+	}
+
+	// Changes during the cooldown don't fire the trigger
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(watchFile, []byte("v2 changed"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if orchestrator.checkTrigger(context.Background(), trigger) {
+		t.Error("Expected trigger not to fire during cooldown")
+	}
+
+	// Once the cooldown has passed, the pending change fires the trigger
+	past := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	if err := state.SetString("watch", "cooldown_last_fired", past); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if !orchestrator.checkTrigger(context.Background(), trigger) {
+		t.Error("Expected trigger to fire after cooldown")
+	}
+}
+
+func TestOrchestrator_EdgeTrigger(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+
+	// A huge threshold keeps the disk low, and a tiny cooldown makes the
+	// trigger fire on every check
+	trigger, err := NewDiskTrigger("disk-low", tmpDir, "1000000TB", time.Nanosecond, state, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDiskTrigger failed: %v", err)
+	}
+	orchestrator := NewOrchestrator([]Unit{trigger})
+	orchestrator.SetEdgeTriggers(map[string]bool{"disk-low": true}, state)
+
+	if !orchestrator.checkTrigger(context.Background(), trigger) {
+		t.Fatal("Expected trigger to fire when the condition is first met")
+	}
+
+	time.Sleep(time.Millisecond)
+	if orchestrator.checkTrigger(context.Background(), trigger) {
+		t.Error("Expected trigger not to fire again while the condition persists")
+	}
+
+	// After the condition clears, the next time it's met fires again
+	if err := state.Set("disk-low", "edge_last_result", false); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if !orchestrator.checkTrigger(context.Background(), trigger) {
+		t.Error("Expected trigger to fire after the condition cleared")
+	}
+}
+
+// TestOrchestrator_MaxRuntime verifies that the daemon returns once the max
+// runtime is reached, after the cycle in progress finishes
+func TestOrchestrator_MaxRuntime(t *testing.T) {
+	doneFile := filepath.Join(t.TempDir(), "done")
+	units := []Unit{
+		NewStartTrigger("start", []string{"slow"}, nil, nil),
+		NewRunUnit("slow", "sleep 0.2 && touch "+doneFile, "", 0, "", false, nil, nil, nil),
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetMaxRuntime(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := orchestrator.RunDaemon(ctx); err != nil {
+		t.Fatalf("RunDaemon() = %v, want nil", err)
+	}
+
+	if _, err := os.Stat(doneFile); err != nil {
+		t.Errorf("Expected cycle in progress to finish: %v", err)
+	}
+}
+
+func TestOrchestrator_Describe(t *testing.T) {
+	orchestrator := NewOrchestrator(nil)
+	orchestrator.SetUnitDescriptions(map[string]string{"u1": "Nightly YOE build"})
+
+	if got := orchestrator.describe("u1"); got != "'u1' (Nightly YOE build)" {
+		t.Errorf("describe(u1) = %q", got)
+	}
+	if got := orchestrator.describe("u2"); got != "'u2'" {
+		t.Errorf("describe(u2) = %q", got)
+	}
+}
+
+func TestOrchestrator_GetTriggerLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "src.txt"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	units := []Unit{
+		NewStartTrigger("start", []string{"watch"}, nil, nil),
+		NewFileTrigger("watch", filepath.Join(tmpDir, "*.txt"), state, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+
+	// The startup cycle checks both triggers, and start then triggers a
+	// manual check of watch, which finds no new changes. The poll cycle
+	// checks only watch.
+	orchestrator.runStartupCycle(context.Background())
+	orchestrator.runPollCycle(context.Background())
+
+	want := []TriggerCheck{
+		{Cycle: 1, Unit: "start", Mode: CheckModePolling, Result: true},
+		{Cycle: 1, Unit: "watch", Mode: CheckModePolling, Result: true},
+		{Cycle: 1, Unit: "watch", Mode: CheckModeManual, Result: false},
+		{Cycle: 2, Unit: "watch", Mode: CheckModePolling, Result: false},
+	}
+	got := orchestrator.GetTriggerLog()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d checks, got %+v", len(want), got)
+	}
+	for i := range want {
+		g := got[i]
+		if g.Cycle != want[i].Cycle || g.Unit != want[i].Unit || g.Mode != want[i].Mode || g.Result != want[i].Result || g.Err != nil {
+			t.Errorf("Check %d = %+v, want %+v", i, g, want[i])
+		}
+	}
+}
+
+func TestOrchestrator_PollInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	units := []Unit{
+		NewFileTrigger("fast", filepath.Join(tmpDir, "*.txt"), state, nil, nil, nil),
+		NewFileTrigger("slow", filepath.Join(tmpDir, "*.log"), state, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetPollIntervals(map[string]time.Duration{"slow": time.Hour})
+
+	// Both are checked on startup, then slow waits for its interval
+	orchestrator.runStartupCycle(context.Background())
+	orchestrator.runPollCycle(context.Background())
+
+	orchestrator.lastPolled["slow"] = time.Now().Add(-2 * time.Hour)
+	orchestrator.runPollCycle(context.Background())
+
+	var checks []string
+	for _, c := range orchestrator.GetTriggerLog() {
+		checks = append(checks, fmt.Sprintf("%d:%s", c.Cycle, c.Unit))
+	}
+	want := []string{"1:fast", "1:slow", "2:fast", "3:fast", "3:slow"}
+	if !slices.Equal(checks, want) {
+		t.Errorf("Checks = %v, want %v", checks, want)
+	}
+}
+
+func TestOrchestrator_SkipTriggers(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	cron, err := NewCronTrigger("nightly", "0 2 * * *", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+	units := []Unit{
+		NewFileTrigger("files", filepath.Join(tmpDir, "*.txt"), state, nil, nil, nil),
+		cron,
+	}
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetSkipTriggers([]string{"nightly"})
+
+	orchestrator.runStartupCycle(context.Background())
+
+	var checks []string
+	for _, c := range orchestrator.GetTriggerLog() {
+		checks = append(checks, c.Unit)
+	}
+	if !slices.Equal(checks, []string{"files"}) {
+		t.Errorf("Checks = %v, want [files]", checks)
+	}
+
+	// Skipped triggers aren't scheduled either
+	if queue := newScheduleQueue(orchestrator.checkedUnits(), time.Now()); queue.Len() != 0 {
+		t.Errorf("Expected no scheduled triggers, got %d", queue.Len())
+	}
+
+	// A timer runs the trigger without checking its schedule
+	orchestrator.SetSkipSingleCheck(true)
+	if err := orchestrator.RunSingleUnit(context.Background(), "nightly", true); err != nil {
+		t.Fatalf("RunSingleUnit failed: %v", err)
+	}
+	if _, ok := orchestrator.GetResults()["nightly"]; !ok {
+		t.Error("Expected nightly to run without its schedule being due")
+	}
+}
+
+func TestOrchestrator_OnError(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	units := []Unit{
+		&panicUnit{name: "bad-check", panicCheck: true, onFailure: []string{"failures"}},
+		NewCountUnit("errors", state, nil, nil, nil),
+		NewCountUnit("failures", state, nil, nil, nil),
+	}
+
+	orchestrator := NewOrchestrator(units)
+	orchestrator.SetErrorTriggers(map[string][]string{"bad-check": {"errors"}})
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	// A failing Check runs on_error with the trigger as the triggering unit
+	if count, _ := state.Get("errors", "bad-check"); count != 1 {
+		t.Errorf("errors count for bad-check = %v, want 1", count)
+	}
+
+	// on_failure is for failed runs, not failed checks
+	if _, ok := state.Get("failures", "bad-check"); ok {
+		t.Error("Expected on_failure not to run when Check fails")
+	}
+}
+
+func TestOrchestrator_PropagateFailure(t *testing.T) {
+	for _, propagate := range []bool{false, true} {
+		tmpDir := t.TempDir()
+		logFile := filepath.Join(tmpDir, "alerts.log")
+		units := []Unit{
+			NewStartTrigger("start", []string{"build"}, []string{"alert"}, nil),
+			NewRunUnit("build", "echo building", "", 0, "", false, []string{"deploy"}, nil, nil),
+			NewRunUnit("deploy", "echo deploy output; exit 2", "", 0, "", false, nil, nil, nil),
+			NewLogUnit("alert", logFile, nil, nil, nil),
+		}
+
+		orchestrator := NewOrchestrator(units)
+		if propagate {
+			orchestrator.SetPropagateFailure(map[string]bool{"start": true})
+		}
+		if err := orchestrator.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(logFile)
+		if !propagate {
+			if len(data) != 0 {
+				t.Errorf("Expected on_failure not to run without propagate_failure, got:\n%s", data)
+			}
+			continue
+		}
+
+		// The trigger's on_failure sees the failed unit's error and output
+		for _, want := range []string{"start", "unit 'deploy' failed", "deploy output"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("Alert log missing %q:\n%s", want, data)
+			}
+		}
+		if result := orchestrator.GetResults()["start"]; result.Error != nil {
+			t.Errorf("Expected the trigger itself to succeed, got %v", result.Error)
+		}
+	}
+}
+
+func TestOrchestrator_ForcedTrigger(t *testing.T) {
+	for _, force := range []bool{false, true} {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, "src.txt"), []byte("v1"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		state := NewState(filepath.Join(tmpDir, "state.yaml"))
+		watch := NewFileTrigger("watch", filepath.Join(tmpDir, "*.txt"), state, []string{"builds"}, nil, nil)
+		watch.SetInitialTrigger(false)
+		units := []Unit{
+			NewStartTrigger("start", []string{"watch"}, nil, nil),
+			watch,
+			NewCountUnit("builds", state, nil, nil, nil),
+		}
+
+		orchestrator := NewOrchestrator(units)
+		if force {
+			orchestrator.SetForcedTriggers(map[string][]string{"start": {"watch"}})
+		}
+		if err := orchestrator.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() failed: %v", err)
+		}
+
+		// Without changes, watch only runs when start forces it
+		count, _ := state.Get("builds", "watch")
+		if force && count != 1 {
+			t.Errorf("builds count = %v, want 1 when forced", count)
+		}
+		if !force && count != nil {
+			t.Errorf("builds count = %v, want none when not forced", count)
+		}
+	}
+}
+
+func TestOrchestrator_ResultsAreCopies(t *testing.T) {
+	units := []Unit{
+		NewStartTrigger("start", []string{"build"}, nil, nil),
+		NewRunUnit("build", "true", "", 0, "", false, nil, nil, nil),
+	}
+	orchestrator := NewOrchestrator(units)
+
+	if orchestrator.GetLastCycleResults() != nil {
+		t.Error("Expected no cycle results before the first cycle")
+	}
+	if err := orchestrator.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+
+	results := orchestrator.GetResults()
+	delete(results, "build")
+	if orchestrator.GetResults()["build"] == nil {
+		t.Error("Expected changes to the returned results not to affect the orchestrator")
+	}
+
+	// A single unit run doesn't replace the last cycle's results
+	if err := orchestrator.RunSingleUnit(context.Background(), "start", false); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+	cycle := orchestrator.GetLastCycleResults()
+	if len(cycle) != 2 || cycle["build"] == nil || cycle["start"] == nil {
+		t.Errorf("Expected start and build in last cycle results, got %v", cycle)
+	}
+	if _, ok := orchestrator.GetResults()["build"]; ok {
+		t.Error("Expected current results to only hold the single unit run")
+	}
+}
@@ -0,0 +1,197 @@
+package brun
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// overlayUnit is a unit from the units section of a config, in either form
+type overlayUnit struct {
+	name string
+	typ  string
+	body *yaml.Node
+}
+
+// applyOverlay merges the config overlay onto base, both YAML documents, and
+// returns the merged document. The config block is merged key by key, and
+// units are matched by name: fields set in the overlay replace those of the
+// base unit, nested maps are merged, and units not in base are added.
+func applyOverlay(base, overlay []byte) ([]byte, error) {
+	var baseDoc, overlayDoc yaml.Node
+	if err := yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(overlay, &overlayDoc); err != nil {
+		return nil, err
+	}
+	if len(overlayDoc.Content) == 0 {
+		return base, nil
+	}
+	if len(baseDoc.Content) == 0 {
+		return overlay, nil
+	}
+
+	baseRoot, overlayRoot := baseDoc.Content[0], overlayDoc.Content[0]
+	if baseRoot.Kind != yaml.MappingNode || overlayRoot.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a map")
+	}
+
+	for i := 0; i+1 < len(overlayRoot.Content); i += 2 {
+		key, value := overlayRoot.Content[i].Value, overlayRoot.Content[i+1]
+		baseValue := mappingValue(baseRoot, key)
+		if baseValue == nil {
+			baseRoot.Content = append(baseRoot.Content, overlayRoot.Content[i], value)
+			continue
+		}
+
+		if key == "units" {
+			merged, err := mergeUnits(baseValue, value)
+			if err != nil {
+				return nil, err
+			}
+			setMappingValue(baseRoot, key, merged)
+		} else {
+			setMappingValue(baseRoot, key, mergeNodes(baseValue, value))
+		}
+	}
+
+	return yaml.Marshal(&baseDoc)
+}
+
+// mergeUnits merges the overlay units onto the base units by name and returns
+// them in the list form
+func mergeUnits(base, overlay *yaml.Node) (*yaml.Node, error) {
+	baseUnits, err := overlayUnits(base, false)
+	if err != nil {
+		return nil, err
+	}
+	overlayList, err := overlayUnits(overlay, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range overlayList {
+		index := -1
+		for i, b := range baseUnits {
+			if b.name == o.name {
+				index = i
+				break
+			}
+		}
+
+		if index < 0 {
+			if o.typ == "" {
+				return nil, fmt.Errorf("unit '%s': type is required for a unit not in the base config", o.name)
+			}
+			baseUnits = append(baseUnits, o)
+			continue
+		}
+
+		b := &baseUnits[index]
+		if o.typ != "" && o.typ != b.typ {
+			return nil, fmt.Errorf("unit '%s': type '%s' doesn't match the base config's '%s'", o.name, o.typ, b.typ)
+		}
+		b.body = mergeNodes(b.body, o.body)
+	}
+
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, u := range baseUnits {
+		list.Content = append(list.Content, u.listItem())
+	}
+	return list, nil
+}
+
+// listItem returns the unit as an item of a units list
+func (u overlayUnit) listItem() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: u.typ},
+		u.body,
+	}}
+}
+
+// overlayUnits returns the units of a units section in the list or map form.
+// Units in the map form get their name set in the body. If typeOptional is
+// set, map form units may leave out the type to override a base unit.
+func overlayUnits(node *yaml.Node, typeOptional bool) ([]overlayUnit, error) {
+	var units []overlayUnit
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if item.Kind != yaml.MappingNode || len(item.Content) != 2 || item.Content[1].Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("unit %d: expected a single unit type", i)
+			}
+			body := item.Content[1]
+			name := mappingValue(body, "name")
+			if name == nil || name.Value == "" {
+				return nil, fmt.Errorf("unit %d: name is required", i)
+			}
+			units = append(units, overlayUnit{name: name.Value, typ: item.Content[0].Value, body: body})
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name, body := node.Content[i].Value, node.Content[i+1]
+			if body.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("unit '%s': expected a map", name)
+			}
+			typ := mappingValue(body, "type")
+			if typ == nil && !typeOptional {
+				return nil, fmt.Errorf("unit '%s': type is required", name)
+			}
+			if n := mappingValue(body, "name"); n != nil && n.Value != name {
+				return nil, fmt.Errorf("unit '%s': name '%s' doesn't match its key", name, n.Value)
+			} else if n == nil {
+				setMappingValue(body, "name", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name})
+			}
+			u := overlayUnit{name: name, body: body}
+			if typ != nil {
+				u.typ = typ.Value
+			}
+			units = append(units, u)
+		}
+	default:
+		return nil, fmt.Errorf("units must be a list or a map")
+	}
+
+	return units, nil
+}
+
+// mergeNodes returns overlay merged onto base. Maps are merged key by key;
+// any other overlay value, including a list, replaces the base value.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i].Value, overlay.Content[i+1]
+		if baseValue := mappingValue(base, key); baseValue != nil {
+			setMappingValue(base, key, mergeNodes(baseValue, value))
+		} else {
+			base.Content = append(base.Content, overlay.Content[i], value)
+		}
+	}
+	return base
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of key in a mapping node, adding the key if
+// it isn't there
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
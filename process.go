@@ -0,0 +1,177 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processDialTimeout bounds how long a tcp_port check waits for a connection
+const processDialTimeout = 2 * time.Second
+
+// ProcessTrigger is a trigger unit that fires when a process dies or a port
+// stops accepting connections
+type ProcessTrigger struct {
+	name        string
+	pidFile     string
+	processName string
+	tcpPort     int
+	state       *State
+	onSuccess   []string
+	onFailure   []string
+	always      []string
+}
+
+// ProcessConfig represents the configuration for a process trigger. Exactly
+// one of PIDFile, ProcessName, and TCPPort must be set.
+type ProcessConfig struct {
+	UnitConfig  `yaml:",inline"`
+	PIDFile     string `yaml:"pid_file,omitempty"`
+	ProcessName string `yaml:"process_name,omitempty"`
+	TCPPort     int    `yaml:"tcp_port,omitempty"`
+}
+
+// NewProcessTrigger creates a new process trigger unit
+func NewProcessTrigger(name, pidFile, processName string, tcpPort int, state *State, onSuccess, onFailure, always []string) *ProcessTrigger {
+	return &ProcessTrigger{
+		name:        name,
+		pidFile:     pidFile,
+		processName: processName,
+		tcpPort:     tcpPort,
+		state:       state,
+		onSuccess:   onSuccess,
+		onFailure:   onFailure,
+		always:      always,
+	}
+}
+
+// Name returns the name of the unit
+func (p *ProcessTrigger) Name() string {
+	return p.name
+}
+
+// Type returns the unit type
+func (p *ProcessTrigger) Type() string {
+	return "trigger.process"
+}
+
+// target describes what the trigger watches, for logging
+func (p *ProcessTrigger) target() string {
+	switch {
+	case p.pidFile != "":
+		return "pid file " + p.pidFile
+	case p.processName != "":
+		return "process " + p.processName
+	default:
+		return fmt.Sprintf("tcp port %d", p.tcpPort)
+	}
+}
+
+// isUp returns true if the watched process is running or port is listening
+func (p *ProcessTrigger) isUp(ctx context.Context) (bool, error) {
+	switch {
+	case p.pidFile != "":
+		data, err := os.ReadFile(p.pidFile)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to read pid file: %w", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || pid <= 0 {
+			return false, fmt.Errorf("invalid pid in %s: %q", p.pidFile, strings.TrimSpace(string(data)))
+		}
+		return processAlive(pid), nil
+
+	case p.processName != "":
+		return processRunning(p.processName)
+
+	default:
+		dialer := net.Dialer{Timeout: processDialTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("localhost", strconv.Itoa(p.tcpPort)))
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	}
+}
+
+// processRunning returns true if a process with the given name (as shown in
+// /proc/<pid>/comm) is running. Only supported on Linux.
+func processRunning(name string) (bool, error) {
+	comms, err := filepath.Glob("/proc/[0-9]*/comm")
+	if err != nil {
+		return false, fmt.Errorf("failed to list processes: %w", err)
+	}
+	if len(comms) == 0 {
+		return false, fmt.Errorf("process_name requires /proc")
+	}
+
+	for _, comm := range comms {
+		data, err := os.ReadFile(comm)
+		if err != nil {
+			continue // Process exited while scanning
+		}
+		if strings.TrimSpace(string(data)) == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Check returns true when the process or port goes from up to down. If no
+// previous state is stored, the process is assumed to have been up, so a
+// process that isn't running at the first check also triggers.
+func (p *ProcessTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	up, err := p.isUp(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Get last state from state file (state is already loaded at startup)
+	wasUp := true
+	if val, ok := p.state.Get(p.name, "up"); ok {
+		if boolVal, ok := val.(bool); ok {
+			wasUp = boolVal
+		}
+	}
+
+	if val, ok := p.state.Get(p.name, "up"); !ok || val != up {
+		if err := p.state.Set(p.name, "up", up); err != nil {
+			return false, fmt.Errorf("failed to save process state: %w", err)
+		}
+	}
+
+	return wasUp && !up, nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (p *ProcessTrigger) OnSuccess() []string {
+	return p.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (p *ProcessTrigger) OnFailure() []string {
+	return p.onFailure
+}
+
+// Always returns the list of units to trigger regardless of success/failure
+func (p *ProcessTrigger) Always() []string {
+	return p.always
+}
+
+// Run executes the trigger unit
+// Note: Check() has already been called by the orchestrator before Run() is invoked
+func (p *ProcessTrigger) Run(ctx context.Context) error {
+	log.Printf("Process trigger '%s' activated (%s is down)", p.name, p.target())
+	return nil
+}
@@ -0,0 +1,117 @@
+package brun
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProcessTrigger_TCPPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger := NewProcessTrigger("web-down", "", "", port, state, nil, nil, nil)
+	ctx := context.Background()
+
+	check := func(want bool, msg string) {
+		t.Helper()
+		got, err := trigger.Check(ctx, CheckModePolling)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: Check() = %v, want %v", msg, got, want)
+		}
+	}
+
+	check(false, "port listening")
+
+	listener.Close()
+	check(true, "port stopped listening")
+	check(false, "port still down")
+
+	listener, err = net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		t.Skipf("Failed to listen again on port %d: %v", port, err)
+	}
+	defer listener.Close()
+	check(false, "port back up")
+}
+
+func TestProcessTrigger_PIDFile(t *testing.T) {
+	tempDir := t.TempDir()
+	pidFile := filepath.Join(tempDir, "service.pid")
+	state := NewState(filepath.Join(tempDir, "state.yaml"))
+	trigger := NewProcessTrigger("service-down", pidFile, "", 0, state, nil, nil, nil)
+	ctx := context.Background()
+
+	// Our own process is alive
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write pid file: %v", err)
+	}
+	if fired, err := trigger.Check(ctx, CheckModePolling); err != nil || fired {
+		t.Errorf("Check() = %v, %v, want false while running", fired, err)
+	}
+
+	// A removed pid file means the process is down
+	if err := os.Remove(pidFile); err != nil {
+		t.Fatalf("Failed to remove pid file: %v", err)
+	}
+	if fired, err := trigger.Check(ctx, CheckModePolling); err != nil || !fired {
+		t.Errorf("Check() = %v, %v, want true after process exit", fired, err)
+	}
+
+	if err := os.WriteFile(pidFile, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("Failed to write pid file: %v", err)
+	}
+	if _, err := trigger.Check(ctx, CheckModePolling); err == nil {
+		t.Error("Expected error for invalid pid file")
+	}
+}
+
+func TestProcessTrigger_NotRunningAtStart(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger := NewProcessTrigger("service-down", filepath.Join(t.TempDir(), "missing.pid"), "", 0, state, nil, nil, nil)
+
+	// Without previous state the process is assumed to have been up
+	if fired, err := trigger.Check(context.Background(), CheckModePolling); err != nil || !fired {
+		t.Errorf("Check() = %v, %v, want true on first check while down", fired, err)
+	}
+}
+
+func TestCreateUnits_ProcessTargets(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: ` + filepath.Join(tempDir, "state.yaml") + `
+
+units:
+  - process:
+      name: web-down
+      process_name: nginx
+      tcp_port: 80
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	_, err = config.CreateUnits()
+	if err == nil || !strings.Contains(err.Error(), "exactly one of") {
+		t.Errorf("Expected target error, got %v", err)
+	}
+}
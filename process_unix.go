@@ -0,0 +1,15 @@
+//go:build !windows
+
+package brun
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive returns true if a process with the given pid exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM means the process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
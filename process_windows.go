@@ -0,0 +1,17 @@
+//go:build windows
+
+package brun
+
+import "os"
+
+// processAlive returns true if a process with the given pid exists
+func processAlive(pid int) bool {
+	// FindProcess opens a handle to the process on Windows, so it fails if
+	// the process doesn't exist
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
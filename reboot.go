@@ -9,21 +9,44 @@ import (
 
 // RebootUnit is a unit that logs and reboots the system
 type RebootUnit struct {
-	name      string
-	delay     int // delay in seconds before reboot
-	onSuccess []string
-	onFailure []string
-	always    []string
+	name           string
+	delay          int // delay in seconds before reboot
+	state          *State
+	triggeringUnit string // Name of the unit that triggered the reboot
+	triggerError   error  // Error from the triggering unit (if any)
+	// preReboot is a script run before rebooting, bounded by preTimeout
+	preReboot  string
+	preTimeout time.Duration
+	// abortOnPreFailure cancels the reboot if the pre_reboot script fails
+	abortOnPreFailure bool
+	onSuccess         []string
+	onFailure         []string
+	always            []string
 }
 
 // RebootConfig represents the configuration for a reboot unit
 type RebootConfig struct {
 	UnitConfig `yaml:",inline"`
 	Delay      int `yaml:"delay,omitempty"` // delay in seconds before reboot
+	// PreReboot is a script run before the reboot command, e.g. to stop a
+	// database cleanly
+	PreReboot         string `yaml:"pre_reboot,omitempty"`
+	PreRebootTimeout  string `yaml:"pre_reboot_timeout,omitempty"`
+	AbortOnPreFailure bool   `yaml:"abort_on_pre_failure,omitempty"`
 }
 
-// NewRebootUnit creates a new reboot unit
-func NewRebootUnit(name string, delay int, onSuccess, onFailure, always []string) *RebootUnit {
+// maxRebootHistory is the number of reboots kept in reboot_history
+const maxRebootHistory = 10
+
+// defaultPreRebootTimeout bounds the pre_reboot script if no timeout is set
+const defaultPreRebootTimeout = 5 * time.Minute
+
+// rebootCommand is the command that reboots the system, overridable for tests
+var rebootCommand = "reboot"
+
+// NewRebootUnit creates a new reboot unit. The reason for each reboot is
+// recorded in state before rebooting.
+func NewRebootUnit(name string, delay int, state *State, onSuccess, onFailure, always []string) *RebootUnit {
 	if delay <= 0 {
 		delay = 0 // immediate reboot
 	}
@@ -31,6 +54,7 @@ func NewRebootUnit(name string, delay int, onSuccess, onFailure, always []string
 	return &RebootUnit{
 		name:      name,
 		delay:     delay,
+		state:     state,
 		onSuccess: onSuccess,
 		onFailure: onFailure,
 		always:    always,
@@ -47,19 +71,115 @@ func (r *RebootUnit) Type() string {
 	return "reboot"
 }
 
+// SetPreReboot sets a script to run with the default shell before rebooting.
+// A timeout of 0 uses the default of 5 minutes. If abortOnFailure is set, a
+// failing script cancels the reboot and the unit fails.
+func (r *RebootUnit) SetPreReboot(script string, timeout time.Duration, abortOnFailure bool) {
+	if timeout <= 0 {
+		timeout = defaultPreRebootTimeout
+	}
+	r.preReboot = script
+	r.preTimeout = timeout
+	r.abortOnPreFailure = abortOnFailure
+}
+
+// runPreReboot runs the pre_reboot script, printing its output so it is
+// captured with the unit's output
+func (r *RebootUnit) runPreReboot(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.preTimeout)
+	defer cancel()
+
+	fmt.Println("Running pre_reboot script...")
+	cmd := exec.CommandContext(ctx, defaultShell, shellArgs(defaultShell, r.preReboot)...)
+	setProcessGroup(cmd)
+	output, err := cmd.CombinedOutput()
+	fmt.Print(string(output))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("pre_reboot timed out after %s", r.preTimeout)
+		}
+		return fmt.Errorf("pre_reboot failed: %w", err)
+	}
+	return nil
+}
+
+// SetTriggeringUnit sets the name of the unit that triggered the reboot
+func (r *RebootUnit) SetTriggeringUnit(unitName string) {
+	r.triggeringUnit = unitName
+}
+
+// SetTriggerError sets the error from the triggering unit
+func (r *RebootUnit) SetTriggerError(err error) {
+	r.triggerError = err
+}
+
+// recordReason stores the time, triggering unit, and error of this reboot in
+// state as the last reboot and prepends it to reboot_history, so units run
+// after boot can report why the system rebooted
+func (r *RebootUnit) recordReason() error {
+	errStr := ""
+	if r.triggerError != nil {
+		errStr = r.triggerError.Error()
+	}
+	now := time.Now().Format(time.RFC3339)
+
+	if err := r.state.SetString(r.name, "last_reboot_time", now); err != nil {
+		return err
+	}
+	if err := r.state.SetString(r.name, "last_reboot_trigger", r.triggeringUnit); err != nil {
+		return err
+	}
+	if err := r.state.SetString(r.name, "last_reboot_error", errStr); err != nil {
+		return err
+	}
+
+	history := []any{map[string]any{"time": now, "trigger": r.triggeringUnit, "error": errStr}}
+	if val, ok := r.state.Get(r.name, "reboot_history"); ok {
+		if previous, ok := val.([]any); ok {
+			history = append(history, previous...)
+		}
+	}
+	if len(history) > maxRebootHistory {
+		history = history[:maxRebootHistory]
+	}
+	return r.state.Set(r.name, "reboot_history", history)
+}
+
 // Run executes the reboot unit
 func (r *RebootUnit) Run(ctx context.Context) error {
 	fmt.Printf("Reboot unit '%s' executing\n", r.name)
 
 	if r.delay > 0 {
 		fmt.Printf("Rebooting in %d seconds...\n", r.delay)
-		time.Sleep(time.Duration(r.delay) * time.Second)
+		select {
+		case <-time.After(time.Duration(r.delay) * time.Second):
+		case <-ctx.Done():
+			fmt.Println("Reboot cancelled")
+			return ctx.Err()
+		}
 	} else {
 		fmt.Println("Rebooting now...")
 	}
 
+	if r.preReboot != "" {
+		if err := r.runPreReboot(ctx); err != nil {
+			if r.abortOnPreFailure {
+				return fmt.Errorf("reboot aborted: %w", err)
+			}
+			fmt.Printf("Rebooting anyway: %v\n", err)
+		}
+	}
+
+	if r.state != nil {
+		// A full or read-only state disk must not keep the system from
+		// rebooting, which may be what recovers it
+		if err := r.recordReason(); err != nil {
+			fmt.Printf("Failed to record reboot reason, rebooting anyway: %v\n", err)
+		}
+	}
+
 	// Execute reboot command
-	cmd := exec.Command("reboot")
+	cmd := exec.Command(rebootCommand)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to execute reboot: %w", err)
 	}
@@ -0,0 +1,149 @@
+package brun
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebootUnit_RecordsReason(t *testing.T) {
+	defer func(cmd string) { rebootCommand = cmd }(rebootCommand)
+	rebootCommand = "true"
+
+	stateFile := filepath.Join(t.TempDir(), "state.yaml")
+	state := NewState(stateFile)
+	unit := NewRebootUnit("reboot", 0, state, nil, nil, nil)
+
+	unit.SetTriggeringUnit("watchdog")
+	unit.SetTriggerError(errors.New("service unresponsive"))
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	unit.SetTriggeringUnit("nightly")
+	unit.SetTriggerError(nil)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// The reason survives the reboot in the state file
+	reloaded := NewState(stateFile)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	if trigger, _ := reloaded.GetString("reboot", "last_reboot_trigger"); trigger != "nightly" {
+		t.Errorf("last_reboot_trigger = %q, want nightly", trigger)
+	}
+	if errStr, _ := reloaded.GetString("reboot", "last_reboot_error"); errStr != "" {
+		t.Errorf("last_reboot_error = %q, want empty", errStr)
+	}
+	if _, ok := reloaded.GetString("reboot", "last_reboot_time"); !ok {
+		t.Error("Expected last_reboot_time to be set")
+	}
+
+	val, _ := reloaded.Get("reboot", "reboot_history")
+	history, ok := val.([]any)
+	if !ok || len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %v", val)
+	}
+	oldest, ok := history[1].(map[string]any)
+	if !ok || oldest["trigger"] != "watchdog" || oldest["error"] != "service unresponsive" {
+		t.Errorf("Unexpected oldest history entry: %v", history[1])
+	}
+}
+
+func TestRebootUnit_RecordReasonFails(t *testing.T) {
+	defer func(cmd string) { rebootCommand = cmd }(rebootCommand)
+
+	tmpDir := t.TempDir()
+	rebooted := filepath.Join(tmpDir, "rebooted")
+	script := filepath.Join(tmpDir, "reboot.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntouch "+rebooted+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write reboot script: %v", err)
+	}
+	rebootCommand = script
+
+	// The state directory can't be created because a file is in the way
+	blocker := filepath.Join(tmpDir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	state := NewState(filepath.Join(blocker, "state.yaml"))
+
+	unit := NewRebootUnit("reboot", 0, state, nil, nil, nil)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Expected reboot despite state failure, got %v", err)
+	}
+	if _, err := os.Stat(rebooted); err != nil {
+		t.Errorf("Expected reboot command to run: %v", err)
+	}
+}
+
+func TestRebootUnit_PreReboot(t *testing.T) {
+	defer func(cmd string) { rebootCommand = cmd }(rebootCommand)
+	rebootCommand = "true"
+
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	stopped := filepath.Join(tmpDir, "stopped")
+
+	unit := NewRebootUnit("reboot", 0, state, nil, nil, nil)
+	unit.SetPreReboot("touch "+stopped, 0, true)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if _, err := os.Stat(stopped); err != nil {
+		t.Errorf("Expected pre_reboot to run: %v", err)
+	}
+
+	// A failing pre_reboot aborts the reboot when abort_on_pre_failure is set
+	unit = NewRebootUnit("abort", 0, state, nil, nil, nil)
+	unit.SetPreReboot("exit 1", 0, true)
+	if err := unit.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "reboot aborted") {
+		t.Errorf("Expected reboot to be aborted, got %v", err)
+	}
+	if _, ok := state.GetString("abort", "last_reboot_time"); ok {
+		t.Error("Expected aborted reboot not to be recorded")
+	}
+
+	// Otherwise the reboot goes ahead
+	unit = NewRebootUnit("continue", 0, state, nil, nil, nil)
+	unit.SetPreReboot("exit 1", 0, false)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Errorf("Expected reboot despite pre_reboot failure, got %v", err)
+	}
+
+	// The timeout bounds the script
+	unit = NewRebootUnit("slow", 0, state, nil, nil, nil)
+	unit.SetPreReboot("sleep 10", 50*time.Millisecond, true)
+	if err := unit.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected pre_reboot timeout, got %v", err)
+	}
+}
+
+func TestRebootUnit_DelayCancelled(t *testing.T) {
+	defer func(cmd string) { rebootCommand = cmd }(rebootCommand)
+	rebootCommand = "true"
+
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	unit := NewRebootUnit("reboot", 60, state, nil, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := unit.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s after cancel", elapsed)
+	}
+	if _, ok := state.GetString("reboot", "last_reboot_time"); ok {
+		t.Error("Expected cancelled reboot not to be recorded")
+	}
+}
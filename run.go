@@ -1,42 +1,83 @@
 package brun
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // RunConfig represents the configuration for a Run unit
 type RunConfig struct {
-	UnitConfig `yaml:",inline"`
-	Script     string `yaml:"script"`
-	Directory  string `yaml:"directory,omitempty"`
-	Timeout    string `yaml:"timeout,omitempty"`
-	Shell      string `yaml:"shell,omitempty"`
-	UsePTY     bool   `yaml:"use_pty,omitempty"`
+	UnitConfig   `yaml:",inline"`
+	Script       string `yaml:"script"`
+	Pre          string `yaml:"pre,omitempty"`
+	Post         string `yaml:"post,omitempty"`
+	Directory    string `yaml:"directory,omitempty"`
+	Timeout      string `yaml:"timeout,omitempty"`
+	Shell        string `yaml:"shell,omitempty"`
+	UsePTY       bool   `yaml:"use_pty,omitempty"`
+	PTYRows      int    `yaml:"pty_rows,omitempty"`
+	PTYCols      int    `yaml:"pty_cols,omitempty"`
+	FailOnStderr bool   `yaml:"fail_on_stderr,omitempty"`
+	RunAsUser    string `yaml:"run_as_user,omitempty"`
+	RunAsGroup   string `yaml:"run_as_group,omitempty"`
+	Umask        string `yaml:"umask,omitempty"`
+	// Env sets environment variables for the scripts, overriding config.env
+	Env map[string]string `yaml:"env,omitempty"`
+	// EnvFile is a dotenv file with variables for the scripts, overridden by
+	// env and config.env
+	EnvFile string `yaml:"env_file,omitempty"`
 }
 
+// stderrErrorLines limits how much stderr output is included in the error
+// for fail_on_stderr
+const stderrErrorLines = 5
+
+// Default terminal size for use_pty
+const (
+	defaultPTYRows = 40
+	defaultPTYCols = 120
+)
+
 // RunUnit executes shell scripts/commands
 type RunUnit struct {
-	name      string
-	script    string
-	directory string
-	timeout   time.Duration
-	shell     string
-	usePTY    bool
-	onSuccess []string
-	onFailure []string
-	always    []string
+	name         string
+	script       string
+	pre          string
+	post         string
+	directory    string
+	timeout      time.Duration
+	shell        string
+	usePTY       bool
+	ptyRows      int
+	ptyCols      int
+	failOnStderr bool
+	runAs        *runAsCredential  // nil runs scripts as the brun user
+	umask        string            // octal umask set before each script, if not empty
+	variables    map[string]string // config.env merged with the unit's env
+	envFile      string            // dotenv file read before each script, if not empty
+	state        *State            // resolves ${state:...} references in the directory and scripts
+	artifacts    map[string]string // artifacts set by upstream units
+	env          map[string]string // variables published by upstream units
+	onSuccess    []string
+	onFailure    []string
+	always       []string
 }
 
 // NewRunUnit creates a new Run unit
 func NewRunUnit(name, script, directory string, timeout time.Duration, shell string, usePTY bool, onSuccess, onFailure, always []string) *RunUnit {
-	// Default to 'sh' if no shell is specified
+	// Default to the platform shell if no shell is specified
 	if shell == "" {
-		shell = "sh"
+		shell = defaultShell
 	}
 	return &RunUnit{
 		name:      name,
@@ -45,12 +86,128 @@ func NewRunUnit(name, script, directory string, timeout time.Duration, shell str
 		timeout:   timeout,
 		shell:     shell,
 		usePTY:    usePTY,
+		ptyRows:   defaultPTYRows,
+		ptyCols:   defaultPTYCols,
 		onSuccess: onSuccess,
 		onFailure: onFailure,
 		always:    always,
 	}
 }
 
+// SetPrePost sets scripts to run before and after the main script.
+// post always runs, even if pre or the main script fail.
+func (r *RunUnit) SetPrePost(pre, post string) {
+	r.pre = pre
+	r.post = post
+}
+
+// SetPTYSize sets the terminal size used with use_pty. Zero values keep the
+// default of 40 rows by 120 columns.
+func (r *RunUnit) SetPTYSize(rows, cols int) {
+	if rows > 0 {
+		r.ptyRows = rows
+	}
+	if cols > 0 {
+		r.ptyCols = cols
+	}
+}
+
+// SetFailOnStderr makes the unit fail when a script writes to stderr, even if
+// it exits successfully
+func (r *RunUnit) SetFailOnStderr(failOnStderr bool) {
+	r.failOnStderr = failOnStderr
+}
+
+// runAsCredential is the user and group scripts run as
+type runAsCredential struct {
+	uid      uint32
+	gid      uint32
+	username string // empty if only the group is changed
+	home     string
+}
+
+// SetRunAs makes scripts run as the named user and/or group, which requires
+// brun to run as root. If only userName is set, the user's primary group is
+// used; if only groupName is set, the user is unchanged. Empty names leave
+// the credentials unchanged.
+func (r *RunUnit) SetRunAs(userName, groupName string) error {
+	if userName == "" && groupName == "" {
+		r.runAs = nil
+		return nil
+	}
+
+	cred := &runAsCredential{uid: uint32(os.Getuid()), gid: uint32(os.Getgid())}
+
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return fmt.Errorf("failed to look up user '%s': %w", userName, err)
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid uid '%s' for user '%s': %w", u.Uid, userName, err)
+		}
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid gid '%s' for user '%s': %w", u.Gid, userName, err)
+		}
+		cred.uid, cred.gid = uint32(uid), uint32(gid)
+		cred.username, cred.home = u.Username, u.HomeDir
+	}
+
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			return fmt.Errorf("failed to look up group '%s': %w", groupName, err)
+		}
+		gid, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid gid '%s' for group '%s': %w", g.Gid, groupName, err)
+		}
+		cred.gid = uint32(gid)
+	}
+
+	r.runAs = cred
+	return nil
+}
+
+// SetUmask sets the umask scripts run with, so files they create get
+// predictable permissions regardless of the daemon's umask
+func (r *RunUnit) SetUmask(umask os.FileMode) {
+	r.umask = fmt.Sprintf("%04o", uint32(umask))
+}
+
+// SetVariables sets the environment variables configured for the scripts.
+// Values may reference the inherited environment, e.g. $PATH.
+func (r *RunUnit) SetVariables(variables map[string]string) {
+	r.variables = variables
+}
+
+// SetEnvFile sets a dotenv file with environment variables for the scripts.
+// It is read before each script, so a pre script can write it, and the
+// variables set with SetVariables take precedence.
+func (r *RunUnit) SetEnvFile(path string) {
+	r.envFile = path
+}
+
+// SetState sets the state used to resolve ${state:<unit>.<key>} references in
+// the directory and scripts
+func (r *RunUnit) SetState(state *State) {
+	r.state = state
+}
+
+// SetArtifacts sets the artifacts available to the scripts as
+// ${artifact.<name>} references and BRUN_ARTIFACT_<NAME> environment variables
+func (r *RunUnit) SetArtifacts(artifacts map[string]string) {
+	r.artifacts = artifacts
+}
+
+// SetEnv sets environment variables published by upstream units, such as
+// BRUN_GIT_COMMIT
+func (r *RunUnit) SetEnv(env map[string]string) {
+	r.env = env
+}
+
 // Name returns the unit name
 func (r *RunUnit) Name() string {
 	return r.name
@@ -62,9 +219,28 @@ func (r *RunUnit) Type() string {
 }
 
 // Run executes the shell script
+// If configured, the pre script runs first and the post script runs last
+// regardless of the outcome. The result reflects the pre and main scripts only.
 func (r *RunUnit) Run(ctx context.Context) error {
 	log.Printf("Running unit '%s'", r.name)
 
+	// Expand the directory once so all scripts run in the same one
+	dir, err := r.workDir()
+	if err != nil {
+		return err
+	}
+
+	if r.post != "" {
+		// Run post with the parent context so cleanup still happens when
+		// the main script times out
+		defer func(ctx context.Context) {
+			log.Printf("Running post script for unit '%s'", r.name)
+			if err := r.runScript(ctx, dir, r.post); err != nil {
+				log.Printf("Post script for unit '%s' failed: %v", r.name, err)
+			}
+		}(ctx)
+	}
+
 	// Apply timeout if configured
 	if r.timeout > 0 {
 		var cancel context.CancelFunc
@@ -73,39 +249,145 @@ func (r *RunUnit) Run(ctx context.Context) error {
 		log.Printf("Timeout set to %s", r.timeout)
 	}
 
-	// Create command to execute script using configured shell
-	var cmd *exec.Cmd
-	if r.usePTY {
-		// Wrap command with 'script' to provide a pseudo-TTY
-		// Build the command as: script -q -e -c "bash -c 'script contents'" /dev/null
-		// We need to pass each argument separately to avoid quote interpretation issues
-		scriptPath, _ := exec.LookPath("script")
-		cmd = &exec.Cmd{
-			Path: scriptPath,
-			Args: []string{"script", "-q", "-e", "-c", r.shell, "-c", r.script, "/dev/null"},
+	if r.pre != "" {
+		log.Printf("Running pre script for unit '%s'", r.name)
+		if err := r.runScript(ctx, dir, r.pre); err != nil {
+			return fmt.Errorf("pre script failed: %w", err)
 		}
-		if ctx != nil {
-			cmd = exec.CommandContext(ctx, scriptPath, "-q", "-e", "-c", r.shell, "-c", r.script, "/dev/null")
+	}
+
+	if err := r.runScript(ctx, dir, r.script); err != nil {
+		return err
+	}
+
+	log.Printf("Unit '%s' completed successfully", r.name)
+	return nil
+}
+
+// shellName returns the lowercase base name of shell without .exe
+func shellName(shell string) string {
+	name := strings.ToLower(filepath.Base(shell))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// isPOSIXShell returns false for the Windows shells cmd and PowerShell
+func isPOSIXShell(shell string) bool {
+	switch shellName(shell) {
+	case "cmd", "powershell", "pwsh":
+		return false
+	}
+	return true
+}
+
+// shellArgs returns the arguments that run script with shell. cmd and
+// PowerShell use their own flags; all other shells take -c.
+func shellArgs(shell, script string) []string {
+	switch shellName(shell) {
+	case "cmd":
+		return []string{"/C", script}
+	case "powershell", "pwsh":
+		return []string{"-NoProfile", "-NonInteractive", "-Command", script}
+	default:
+		return []string{"-c", script}
+	}
+}
+
+// workDir returns the working directory with references expanded. A
+// directory with references is created if it doesn't exist, since it usually
+// names a new directory, e.g. one per commit.
+func (r *RunUnit) workDir() (string, error) {
+	dir, err := expandRefs(r.directory, r.state, r.lookupVar)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand directory: %w", err)
+	}
+	if dir != r.directory {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory: %w", err)
 		}
-	} else {
-		cmd = exec.CommandContext(ctx, r.shell, "-c", r.script)
 	}
+	return dir, nil
+}
 
-	// Set working directory if specified
-	if r.directory != "" {
-		cmd.Dir = r.directory
-		log.Printf("Working directory: %s", r.directory)
+// lookupVar returns the value of an environment variable as the scripts see
+// it, apart from variables from the env file
+func (r *RunUnit) lookupVar(name string) (string, bool) {
+	if value, ok := r.variables[name]; ok {
+		return os.ExpandEnv(value), true
+	}
+	if value, ok := r.env[name]; ok {
+		return value, true
 	}
+	return os.LookupEnv(name)
+}
 
-	// Set up output to go to stdout/stderr
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// runScript executes a single script in dir using the configured shell
+func (r *RunUnit) runScript(ctx context.Context, dir, script string) error {
+	script = expandArtifacts(script, r.artifacts)
+	script, err := expandRefs(script, r.state, nil)
+	if err != nil {
+		return fmt.Errorf("failed to expand script: %w", err)
+	}
+	if r.umask != "" {
+		script = "umask " + r.umask + "\n" + script
+	}
+
+	// Create command to execute script using configured shell
+	cmd := exec.CommandContext(ctx, r.shell, shellArgs(r.shell, script)...)
+
+	setProcessGroup(cmd)
+
+	// Set working directory if specified
+	if dir != "" {
+		cmd.Dir = dir
+		log.Printf("Working directory: %s", dir)
+	}
 
 	// Inherit environment and set TERM to ensure tools expecting shell environment work
 	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	if r.envFile != "" {
+		fileVars, err := loadEnvFile(r.envFile)
+		if err != nil {
+			return err
+		}
+		for name, value := range fileVars {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+	for name, value := range r.variables {
+		cmd.Env = append(cmd.Env, name+"="+os.ExpandEnv(value))
+	}
+	cmd.Env = append(cmd.Env, artifactEnv(r.artifacts)...)
+	for name, value := range r.env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	// Drop privileges for the script
+	if r.runAs != nil {
+		if err := setCredential(cmd, r.runAs.uid, r.runAs.gid); err != nil {
+			return err
+		}
+		if r.runAs.username != "" {
+			cmd.Env = append(cmd.Env, "USER="+r.runAs.username, "LOGNAME="+r.runAs.username, "HOME="+r.runAs.home)
+		}
+	}
 
 	// Run the command
-	if err := cmd.Run(); err != nil {
+	var stderr bytes.Buffer
+	if r.usePTY {
+		// Output goes to a pseudo-terminal and is copied to stdout, which the
+		// orchestrator captures
+		err = runWithPTY(cmd, r.ptyRows, r.ptyCols)
+	} else {
+		// Set up output to go to stdout/stderr
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if r.failOnStderr {
+			cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		}
+		err = cmd.Run()
+	}
+
+	if err != nil {
 		// Check if error is due to context timeout
 		if ctx.Err() == context.DeadlineExceeded {
 			return fmt.Errorf("task timed out after %s", r.timeout)
@@ -116,7 +398,14 @@ func (r *RunUnit) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to execute script: %w", err)
 	}
 
-	log.Printf("Unit '%s' completed successfully", r.name)
+	if stderr.Len() > 0 {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if len(lines) > stderrErrorLines {
+			lines = append(lines[:stderrErrorLines], "...")
+		}
+		return fmt.Errorf("script wrote to stderr:\n%s", strings.Join(lines, "\n"))
+	}
+
 	return nil
 }
 
@@ -3,7 +3,9 @@ package brun
 import (
 	"context"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -70,6 +72,45 @@ func TestRunUnit_Failure(t *testing.T) {
 	}
 }
 
+func TestRunUnit_PrePost(t *testing.T) {
+	tempDir := t.TempDir()
+	logFile := filepath.Join(tempDir, "steps.log")
+
+	tests := []struct {
+		name      string
+		pre       string
+		script    string
+		wantErr   bool
+		wantSteps string
+	}{
+		{"success", "echo pre >> steps.log", "echo main >> steps.log", false, "pre\nmain\npost\n"},
+		{"main fails", "echo pre >> steps.log", "echo main >> steps.log; exit 1", true, "pre\nmain\npost\n"},
+		{"pre fails", "echo pre >> steps.log; exit 1", "echo main >> steps.log", true, "pre\npost\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(logFile)
+
+			unit := NewRunUnit("test-pre-post", tt.script, tempDir, 0, "", false, nil, nil, nil)
+			unit.SetPrePost(tt.pre, "echo post >> steps.log; exit 1")
+
+			err := unit.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			data, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatalf("Failed to read steps log: %v", err)
+			}
+			if string(data) != tt.wantSteps {
+				t.Errorf("Expected steps %q, got %q", tt.wantSteps, string(data))
+			}
+		})
+	}
+}
+
 func TestRunUnit_WithDirectory(t *testing.T) {
 	tempDir := t.TempDir()
 	testFile := filepath.Join(tempDir, "test.txt")
@@ -463,3 +504,312 @@ units:
 		t.Error("Expected usePTY to be true")
 	}
 }
+
+func TestRunUnit_PTYOutputCaptured(t *testing.T) {
+	unit := NewRunUnit("pty-build", "echo 'captured via pty'; [ -t 1 ] && echo 'is a tty'", "", 0, "bash", true, nil, nil, nil)
+
+	orchestrator := NewOrchestrator([]Unit{unit})
+	if err := orchestrator.RunSingleUnit(context.Background(), "pty-build", false); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+
+	result, ok := orchestrator.GetResults()["pty-build"]
+	if !ok {
+		t.Fatal("No result for pty-build")
+	}
+	if !strings.Contains(result.Output, "captured via pty") {
+		t.Errorf("PTY output not captured: %q", result.Output)
+	}
+	if !strings.Contains(result.Output, "is a tty") {
+		t.Errorf("Script did not run on a terminal: %q", result.Output)
+	}
+	if strings.Contains(result.Output, "\r\n") {
+		t.Errorf("Captured output should use LF line endings: %q", result.Output)
+	}
+}
+
+func TestRunUnit_PTYSize(t *testing.T) {
+	unit := NewRunUnit("pty-size", "stty size", "", 0, "bash", true, nil, nil, nil)
+
+	orchestrator := NewOrchestrator([]Unit{unit})
+	if err := orchestrator.RunSingleUnit(context.Background(), "pty-size", false); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+	if output := orchestrator.GetResults()["pty-size"].Output; !strings.Contains(output, "40 120") {
+		t.Errorf("Expected default size '40 120', got %q", output)
+	}
+
+	unit.SetPTYSize(24, 0)
+	if err := orchestrator.RunSingleUnit(context.Background(), "pty-size", false); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+	if output := orchestrator.GetResults()["pty-size"].Output; !strings.Contains(output, "24 120") {
+		t.Errorf("Expected size '24 120', got %q", output)
+	}
+}
+
+func TestRunUnit_PTYTimeout(t *testing.T) {
+	unit := NewRunUnit("pty-hang", "sleep 10", "", 200*time.Millisecond, "bash", true, nil, nil, nil)
+
+	start := time.Now()
+	err := unit.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %v, timeout did not stop the pty command", elapsed)
+	}
+}
+
+func TestRunUnit_FailOnStderr(t *testing.T) {
+	unit := NewRunUnit("warn", "echo ok; echo 'error: disk quota' >&2", "", 0, "sh", false, nil, nil, nil)
+
+	// Stderr output is allowed by default
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Expected success without fail_on_stderr, got %v", err)
+	}
+
+	unit.SetFailOnStderr(true)
+	err := unit.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "error: disk quota") {
+		t.Errorf("Expected stderr error, got %v", err)
+	}
+
+	// Only stdout output is fine
+	unit = NewRunUnit("quiet", "echo ok", "", 0, "sh", false, nil, nil, nil)
+	unit.SetFailOnStderr(true)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Errorf("Expected success with only stdout, got %v", err)
+	}
+}
+
+func TestShellArgs(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"sh", []string{"-c", "echo hi"}},
+		{"/bin/bash", []string{"-c", "echo hi"}},
+		{"cmd", []string{"/C", "echo hi"}},
+		{"CMD.EXE", []string{"/C", "echo hi"}},
+		{"powershell", []string{"-NoProfile", "-NonInteractive", "-Command", "echo hi"}},
+		{"pwsh.exe", []string{"-NoProfile", "-NonInteractive", "-Command", "echo hi"}},
+	}
+
+	for _, tt := range tests {
+		got := shellArgs(tt.shell, "echo hi")
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("shellArgs(%q) = %v, want %v", tt.shell, got, tt.want)
+		}
+	}
+}
+
+func TestRunUnit_RunAs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("running as another user requires root")
+	}
+	u, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody not found")
+	}
+
+	unit := NewRunUnit("as-nobody", "id -u; echo $USER", "", 0, "", false, nil, nil, nil)
+	if err := unit.SetRunAs("nobody", ""); err != nil {
+		t.Fatalf("SetRunAs failed: %v", err)
+	}
+
+	orchestrator := NewOrchestrator([]Unit{unit})
+	if err := orchestrator.RunSingleUnit(context.Background(), "as-nobody", false); err != nil {
+		t.Fatalf("RunSingleUnit() failed: %v", err)
+	}
+
+	output := orchestrator.GetResults()["as-nobody"].Output
+	if !strings.Contains(output, u.Uid+"\nnobody\n") {
+		t.Errorf("Expected script to run as nobody (uid %s), got %q", u.Uid, output)
+	}
+}
+
+func TestRunUnit_SetRunAsUnknownUser(t *testing.T) {
+	unit := NewRunUnit("build", "true", "", 0, "", false, nil, nil, nil)
+	if err := unit.SetRunAs("no-such-user-brun", ""); err == nil {
+		t.Error("Expected error for unknown user")
+	}
+	if err := unit.SetRunAs("", "no-such-group-brun"); err == nil {
+		t.Error("Expected error for unknown group")
+	}
+}
+
+func TestRunUnit_Umask(t *testing.T) {
+	tmpDir := t.TempDir()
+	unit := NewRunUnit("umask", "touch created", tmpDir, 0, "", false, nil, nil, nil)
+	unit.SetUmask(0o002)
+
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(tmpDir, "created"))
+	if err != nil {
+		t.Fatalf("Failed to stat created file: %v", err)
+	}
+	if info.Mode().Perm() != 0o664 {
+		t.Errorf("Expected mode 0664, got %04o", info.Mode().Perm())
+	}
+}
+
+func TestLoadConfig_WithEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	outFile := filepath.Join(tmpDir, "env.txt")
+
+	configContent := `config:
+  state_location: state.yaml
+  env:
+    CARGO_HOME: /opt/cargo
+    TOOLCHAIN: global
+    PATH: /opt/cargo/bin:$PATH
+units:
+  - run:
+      name: build
+      script: echo "$CARGO_HOME $TOOLCHAIN $PATH" > ` + outFile + `
+      env:
+        TOOLCHAIN: nightly
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+	if err := units[0].Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	want := "/opt/cargo nightly /opt/cargo/bin:" + os.Getenv("PATH") + "\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, string(data))
+	}
+}
+
+func TestLoadConfig_WithEnvFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	outFile := filepath.Join(tmpDir, "env.txt")
+
+	envContent := `# build settings
+export TOOLCHAIN=stable
+TARGET='x86_64 linux'
+HOME=/tmp/build
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "build.env"), []byte(envContent), 0644); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	// The env file is relative to the config, below env and above the
+	// inherited environment
+	configContent := `config:
+  state_location: state.yaml
+units:
+  - run:
+      name: build
+      script: echo "$TOOLCHAIN|$TARGET|$HOME" > ` + outFile + `
+      env_file: build.env
+      env:
+        TOOLCHAIN: nightly
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+	if err := units[0].Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if want := "nightly|x86_64 linux|/tmp/build\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, string(data))
+	}
+
+	// A missing env file fails the unit
+	if err := os.Remove(filepath.Join(tmpDir, "build.env")); err != nil {
+		t.Fatalf("Failed to remove env file: %v", err)
+	}
+	if err := units[0].Run(context.Background()); err == nil || !strings.Contains(err.Error(), "env file") {
+		t.Errorf("Expected env file error, got %v", err)
+	}
+}
+
+func TestRunUnit_TemplatedDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Set("git-watch", "last_commit_hash", "abc123"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	unit := NewRunUnit("build", "echo ${state:git-watch.last_commit_hash} > out.txt", filepath.Join(tmpDir, "builds", "${state:git-watch.last_commit_hash}"), 0, "", false, nil, nil, nil)
+	unit.SetState(state)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// The directory is created and the script runs in it
+	data, err := os.ReadFile(filepath.Join(tmpDir, "builds", "abc123", "out.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(data) != "abc123\n" {
+		t.Errorf("Expected %q, got %q", "abc123\n", string(data))
+	}
+
+	// A reference that can't be resolved fails the unit
+	unit = NewRunUnit("build", "true", filepath.Join(tmpDir, "${state:git-watch.missing}"), 0, "", false, nil, nil, nil)
+	unit.SetState(state)
+	if err := unit.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to expand directory") {
+		t.Errorf("Expected directory expansion error, got %v", err)
+	}
+}
+
+func TestRunUnit_EscapedRefsInScript(t *testing.T) {
+	tmpDir := t.TempDir()
+	state := NewState(filepath.Join(tmpDir, "state.yaml"))
+	if err := state.Set("git-watch", "last_commit_hash", "abc123"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	// The shell sees ${NAME} and ${state:...}, not $$ followed by a brace
+	script := `NAME=shell; echo "$${NAME}" '$${state:git-watch.last_commit_hash}' > out.txt`
+	unit := NewRunUnit("build", script, tmpDir, 0, "", false, nil, nil, nil)
+	unit.SetState(state)
+	if err := unit.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "out.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if want := "shell ${state:git-watch.last_commit_hash}\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, string(data))
+	}
+}
@@ -0,0 +1,63 @@
+//go:build !windows
+
+package brun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// defaultShell is used by run units that don't specify a shell
+const defaultShell = "sh"
+
+// setProcessGroup runs the command in its own process group and kills the
+// whole group on cancellation, so child processes that hold the output pipe
+// open don't keep running after a timeout
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// setCredential runs the command as uid and gid. It must be called after
+// setProcessGroup.
+func setCredential(cmd *exec.Cmd, uid, gid uint32) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid}
+	return nil
+}
+
+// runWithPTY runs the command with a new pseudo-terminal as its controlling
+// terminal of the given size and copies the terminal output to stdout. The
+// command is started in a new session, which is also its own process group.
+func runWithPTY(cmd *exec.Cmd, rows, cols int) error {
+	// setsid fails for a process group leader, so drop Setpgid but keep any
+	// credential
+	attr := &syscall.SysProcAttr{}
+	if cmd.SysProcAttr != nil {
+		attr.Credential = cmd.SysProcAttr.Credential
+	}
+	cmd.SysProcAttr = attr
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+	if err != nil {
+		return fmt.Errorf("failed to start command with pty: %w", err)
+	}
+	defer ptmx.Close()
+
+	// Reading fails with EIO once all processes have closed the terminal
+	if _, err := io.Copy(os.Stdout, ptmx); err != nil && !errors.Is(err, syscall.EIO) {
+		log.Printf("Error copying pty output: %v", err)
+	}
+
+	return cmd.Wait()
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package brun
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// defaultShell is used by run units that don't specify a shell
+const defaultShell = "powershell"
+
+// setProcessGroup is a no-op on Windows; the default cancellation kills only
+// the direct child process
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// setCredential is not supported on Windows
+func setCredential(cmd *exec.Cmd, uid, gid uint32) error {
+	return errors.New("run_as_user and run_as_group are not supported on Windows")
+}
+
+// runWithPTY is not supported on Windows
+func runWithPTY(cmd *exec.Cmd, rows, cols int) error {
+	return errors.New("use_pty is not supported on Windows")
+}
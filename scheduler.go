@@ -0,0 +1,135 @@
+package brun
+
+import (
+	"container/heap"
+	"log"
+	"time"
+)
+
+// scheduledTrigger is implemented by triggers that fire at known times (e.g.,
+// cron). In daemon mode they are checked at their scheduled time instead of
+// on every poll cycle.
+type scheduledTrigger interface {
+	TriggerUnit
+	// NextRun returns the first time after after that the trigger is due, or
+	// the zero time if it never is
+	NextRun(after time.Time) time.Time
+}
+
+// dynamicScheduler is implemented by scheduled triggers whose schedule is
+// read at runtime, e.g. from state, and can change while queued
+type dynamicScheduler interface {
+	DynamicSchedule() bool
+}
+
+// isDynamic returns true if trigger's schedule can change while queued
+func isDynamic(trigger scheduledTrigger) bool {
+	d, ok := trigger.(dynamicScheduler)
+	return ok && d.DynamicSchedule()
+}
+
+// scheduleEntry is a scheduled trigger and its next run time. A zero next run
+// time means never, e.g. for a schedule like "0 0 30 2 *".
+type scheduleEntry struct {
+	trigger scheduledTrigger
+	next    time.Time
+	order   int // position in the config, breaks ties between equal times
+}
+
+// scheduleQueue is a priority queue of scheduled triggers ordered by next run
+// time. Triggers that never run are kept at the back only if their schedule is
+// dynamic, so a changed schedule can bring them back. It implements
+// heap.Interface.
+type scheduleQueue []*scheduleEntry
+
+// newScheduleQueue returns a queue with the next run after now of each
+// scheduled trigger in units
+func newScheduleQueue(units []Unit, now time.Time) *scheduleQueue {
+	q := &scheduleQueue{}
+	for i, unit := range units {
+		trigger, ok := unit.(scheduledTrigger)
+		if !ok {
+			continue
+		}
+		next := trigger.NextRun(now)
+		if next.IsZero() && !isDynamic(trigger) {
+			log.Printf("Trigger '%s' never fires, its schedule has no next run", trigger.Name())
+			continue
+		}
+		*q = append(*q, &scheduleEntry{trigger: trigger, next: next, order: i})
+	}
+	heap.Init(q)
+	return q
+}
+
+func (q scheduleQueue) Len() int { return len(q) }
+
+func (q scheduleQueue) Less(i, j int) bool {
+	if q[i].next.IsZero() != q[j].next.IsZero() {
+		return q[j].next.IsZero()
+	}
+	if q[i].next.Equal(q[j].next) {
+		return q[i].order < q[j].order
+	}
+	return q[i].next.Before(q[j].next)
+}
+
+func (q scheduleQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *scheduleQueue) Push(x any) { *q = append(*q, x.(*scheduleEntry)) }
+
+func (q *scheduleQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// next returns the earliest next run time, or false if no trigger is due to
+// run
+func (q *scheduleQueue) next() (time.Time, bool) {
+	if q.Len() == 0 || (*q)[0].next.IsZero() {
+		return time.Time{}, false
+	}
+	return (*q)[0].next, true
+}
+
+// refresh recomputes the next run of triggers with a dynamic schedule, so a
+// changed schedule applies without waiting for the queued time. Entries
+// already due are left alone to still fire; entries that never run are
+// refreshed too.
+func (q *scheduleQueue) refresh(now time.Time) {
+	changed := false
+	for _, entry := range *q {
+		if !isDynamic(entry.trigger) || (!entry.next.IsZero() && !entry.next.After(now)) {
+			continue
+		}
+		if next := entry.trigger.NextRun(now); !next.Equal(entry.next) {
+			entry.next = next
+			changed = true
+		}
+	}
+	if changed {
+		heap.Init(q)
+	}
+}
+
+// popDue returns the triggers due at or before now, in scheduled order, and
+// reschedules them for their next run after now
+func (q *scheduleQueue) popDue(now time.Time) []TriggerUnit {
+	var due []*scheduleEntry
+	for next, ok := q.next(); ok && !next.After(now); next, ok = q.next() {
+		due = append(due, heap.Pop(q).(*scheduleEntry))
+	}
+
+	triggers := make([]TriggerUnit, len(due))
+	for i, entry := range due {
+		triggers[i] = entry.trigger
+		entry.next = entry.trigger.NextRun(now)
+		if entry.next.IsZero() && !isDynamic(entry.trigger) {
+			continue
+		}
+		heap.Push(q, entry)
+	}
+	return triggers
+}
@@ -0,0 +1,263 @@
+package brun
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// intervalTrigger is a scheduled trigger that is due at every multiple of
+// interval and records when it was checked
+type intervalTrigger struct {
+	name     string
+	interval time.Duration
+	mu       sync.Mutex
+	checks   []time.Time
+}
+
+func (i *intervalTrigger) Name() string { return i.name }
+func (i *intervalTrigger) Type() string { return "trigger.interval" }
+
+func (i *intervalTrigger) NextRun(after time.Time) time.Time {
+	return after.Truncate(i.interval).Add(i.interval)
+}
+
+func (i *intervalTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.checks = append(i.checks, time.Now())
+	return true, nil
+}
+
+func (i *intervalTrigger) Run(ctx context.Context) error { return nil }
+func (i *intervalTrigger) OnSuccess() []string           { return nil }
+func (i *intervalTrigger) OnFailure() []string           { return nil }
+func (i *intervalTrigger) Always() []string              { return nil }
+
+func TestScheduleQueue_PopDue(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fast := &intervalTrigger{name: "fast", interval: time.Minute}
+	slow := &intervalTrigger{name: "slow", interval: time.Hour}
+	also := &intervalTrigger{name: "also-fast", interval: time.Minute}
+
+	queue := newScheduleQueue([]Unit{slow, fast, NewStartTrigger("start", nil, nil, nil), also}, base)
+	if next, ok := queue.next(); !ok || !next.Equal(base.Add(time.Minute)) {
+		t.Fatalf("Expected next run at 00:01, got %v", next)
+	}
+
+	if due := queue.popDue(base.Add(30 * time.Second)); len(due) != 0 {
+		t.Errorf("Expected no triggers due, got %d", len(due))
+	}
+
+	// Ties are returned in config order
+	due := queue.popDue(base.Add(time.Minute))
+	if len(due) != 2 || due[0].Name() != "fast" || due[1].Name() != "also-fast" {
+		t.Fatalf("Expected [fast also-fast] due, got %v", due)
+	}
+
+	if next, _ := queue.next(); !next.Equal(base.Add(2 * time.Minute)) {
+		t.Errorf("Expected due triggers rescheduled for 00:02, got %v", next)
+	}
+
+	// Overdue triggers come first
+	due = queue.popDue(base.Add(time.Hour))
+	if len(due) != 3 || due[0].Name() != "fast" || due[2].Name() != "slow" {
+		t.Errorf("Expected [fast also-fast slow] due at 01:00, got %d triggers", len(due))
+	}
+}
+
+func TestCronTrigger_NextRun(t *testing.T) {
+	state := NewState(t.TempDir() + "/state.yaml")
+	trigger, err := NewCronTrigger("nightly", "0 2 * * *", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+
+	after := time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC)
+	if next := trigger.NextRun(after); !next.Equal(after.Add(24 * time.Hour)) {
+		t.Errorf("Expected next run the following day, got %v", next)
+	}
+
+	// The jitter offset delays the scheduled time
+	trigger.offset = 5 * time.Minute
+	if next := trigger.NextRun(after); !next.Equal(after.Add(5 * time.Minute)) {
+		t.Errorf("Expected next run at 02:05, got %v", next)
+	}
+}
+
+func TestScheduleQueue_RefreshDynamicSchedule(t *testing.T) {
+	state := NewState(t.TempDir() + "/state.yaml")
+	if err := state.Set("tuning", "backup_schedule", "0 3 * * 0"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	trigger, err := NewCronTrigger("backup", "${state:tuning.backup_schedule}", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+
+	// Wednesday, so the weekly run is days away
+	now := time.Date(2025, 1, 1, 10, 1, 0, 0, time.Local)
+	queue := newScheduleQueue([]Unit{trigger}, now)
+	if next, _ := queue.next(); !next.Equal(time.Date(2025, 1, 5, 3, 0, 0, 0, time.Local)) {
+		t.Fatalf("Expected weekly run on Sunday, got %v", next)
+	}
+
+	// Tuning the schedule requeues the trigger on the next refresh
+	if err := state.Set("tuning", "backup_schedule", "*/5 * * * *"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	queue.refresh(now)
+	if next, _ := queue.next(); !next.Equal(time.Date(2025, 1, 1, 10, 5, 0, 0, time.Local)) {
+		t.Errorf("Expected run at 10:05 after the schedule changed, got %v", next)
+	}
+}
+
+func TestScheduleQueue_NeverFires(t *testing.T) {
+	state := NewState(t.TempDir() + "/state.yaml")
+	now := time.Date(2025, 1, 1, 10, 1, 0, 0, time.Local)
+
+	// February 30th never comes, so the trigger isn't queued at all
+	never, err := NewCronTrigger("never", "0 0 30 2 *", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+	if next := never.NextRun(now); !next.IsZero() {
+		t.Fatalf("Expected no next run, got %v", next)
+	}
+	queue := newScheduleQueue([]Unit{never}, now)
+	if next, ok := queue.next(); ok {
+		t.Errorf("Expected nothing scheduled, got %v", next)
+	}
+	if due := queue.popDue(now.AddDate(10, 0, 0)); len(due) != 0 {
+		t.Errorf("Expected no triggers due, got %d", len(due))
+	}
+
+	// A dynamic schedule that changes to one that never fires stops being due
+	// without being dropped, so changing it back schedules it again
+	if err := state.Set("tuning", "backup_schedule", "*/5 * * * *"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	dynamic, err := NewCronTrigger("backup", "${state:tuning.backup_schedule}", state, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCronTrigger failed: %v", err)
+	}
+	queue = newScheduleQueue([]Unit{dynamic}, now)
+	if err := state.Set("tuning", "backup_schedule", "0 0 30 2 *"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if due := queue.popDue(time.Date(2025, 1, 1, 10, 5, 0, 0, time.Local)); len(due) != 1 {
+		t.Fatalf("Expected the queued run to still fire, got %d triggers", len(due))
+	}
+	if next, ok := queue.next(); ok {
+		t.Errorf("Expected nothing scheduled after popDue, got %v", next)
+	}
+	queue.refresh(now)
+	if next, ok := queue.next(); ok {
+		t.Errorf("Expected nothing scheduled after refresh, got %v", next)
+	}
+
+	if err := state.Set("tuning", "backup_schedule", "*/5 * * * *"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	queue.refresh(now)
+	if next, ok := queue.next(); !ok || !next.Equal(time.Date(2025, 1, 1, 10, 5, 0, 0, time.Local)) {
+		t.Errorf("Expected run at 10:05 after the schedule changed back, got %v", next)
+	}
+}
+
+// TestOrchestrator_ScheduledTriggersFireOnTime verifies that scheduled
+// triggers are checked at their scheduled time rather than on the poll ticker
+func TestOrchestrator_ScheduledTriggersFireOnTime(t *testing.T) {
+	trigger := &intervalTrigger{name: "interval", interval: 50 * time.Millisecond}
+
+	orchestrator := NewOrchestrator([]Unit{trigger})
+	orchestrator.pollInterval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 230*time.Millisecond)
+	defer cancel()
+	if err := orchestrator.RunDaemon(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("RunDaemon() = %v, want context.DeadlineExceeded", err)
+	}
+
+	trigger.mu.Lock()
+	defer trigger.mu.Unlock()
+
+	// One check in the startup cycle, then one per scheduled time
+	if len(trigger.checks) < 4 {
+		t.Fatalf("Expected at least 4 checks, got %d", len(trigger.checks))
+	}
+	for _, check := range trigger.checks[1:] {
+		late := check.Sub(check.Truncate(trigger.interval))
+		if late > 20*time.Millisecond {
+			t.Errorf("Check at %v was %v late", check, late)
+		}
+	}
+}
+
+// pollTrigger is a polled trigger that counts checks and detects checks that
+// overlap with another cycle
+type pollTrigger struct {
+	name    string
+	active  *int32
+	mu      sync.Mutex
+	checks  int
+	overlap bool
+}
+
+func (p *pollTrigger) Name() string { return p.name }
+func (p *pollTrigger) Type() string { return "trigger.poll" }
+
+func (p *pollTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	if atomic.AddInt32(p.active, 1) > 1 {
+		p.mu.Lock()
+		p.overlap = true
+		p.mu.Unlock()
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt32(p.active, -1)
+
+	p.mu.Lock()
+	p.checks++
+	p.mu.Unlock()
+	return false, nil
+}
+
+func (p *pollTrigger) Run(ctx context.Context) error { return nil }
+func (p *pollTrigger) OnSuccess() []string           { return nil }
+func (p *pollTrigger) OnFailure() []string           { return nil }
+func (p *pollTrigger) Always() []string              { return nil }
+
+// TestOrchestrator_PollAndScheduleLoops verifies that polled triggers follow
+// the poll interval independently of scheduled triggers and that cycles
+// from the two loops don't overlap
+func TestOrchestrator_PollAndScheduleLoops(t *testing.T) {
+	var active int32
+	polled := &pollTrigger{name: "polled", active: &active}
+	scheduled := &intervalTrigger{name: "interval", interval: 40 * time.Millisecond}
+
+	orchestrator := NewOrchestrator([]Unit{polled, scheduled})
+	orchestrator.SetPollInterval(15 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if err := orchestrator.RunDaemon(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("RunDaemon() = %v, want context.DeadlineExceeded", err)
+	}
+
+	polled.mu.Lock()
+	defer polled.mu.Unlock()
+	scheduled.mu.Lock()
+	defer scheduled.mu.Unlock()
+
+	if polled.checks < 6 {
+		t.Errorf("Expected polled trigger to be checked at least 6 times, got %d", polled.checks)
+	}
+	if len(scheduled.checks) < 4 {
+		t.Errorf("Expected scheduled trigger to be checked at least 4 times, got %d", len(scheduled.checks))
+	}
+	if polled.overlap {
+		t.Error("Expected cycles not to overlap")
+	}
+}
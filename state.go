@@ -1,16 +1,21 @@
 package brun
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
 // State represents the common state file for all units
+// It is safe for concurrent use.
 type State struct {
 	filePath string
+	mu       sync.RWMutex
 	data     map[string]any
 }
 
@@ -25,6 +30,9 @@ func NewState(filePath string) *State {
 
 // Load reads the state file from disk
 func (s *State) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -44,6 +52,13 @@ func (s *State) Load() error {
 
 // Save writes the state file to disk
 func (s *State) Save() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.save()
+}
+
+// save writes the state file to disk, with s.mu held
+func (s *State) save() error {
 	// Ensure directory exists
 	lastSlash := strings.LastIndex(s.filePath, "/")
 	if lastSlash > 0 {
@@ -67,6 +82,9 @@ func (s *State) Save() error {
 
 // Get retrieves a value from state for the given unit name and key
 func (s *State) Get(unitName, key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	unitData, ok := s.data[unitName]
 	if !ok {
 		return nil, false
@@ -83,6 +101,9 @@ func (s *State) Get(unitName, key string) (any, bool) {
 
 // Set stores a value in state for the given unit name and key and automatically saves
 func (s *State) Set(unitName, key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	unitData, ok := s.data[unitName]
 	if !ok {
 		unitData = make(map[string]any)
@@ -98,7 +119,40 @@ func (s *State) Set(unitName, key string, value any) error {
 	unitMap[key] = value
 
 	// Automatically save after setting
-	return s.Save()
+	return s.save()
+}
+
+// Refresh reads a value for the given unit name and key from the state file
+// into memory and returns it, so a value written by another process is seen
+// without a restart and kept by later saves. If the file doesn't have the
+// key, the value in memory is returned.
+func (s *State) Refresh(unitName, key string) (any, bool, error) {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var onDisk map[string]any
+	if err := yaml.Unmarshal(data, &onDisk); err != nil {
+		return nil, false, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	if unitMap, ok := onDisk[unitName].(map[string]any); ok {
+		if value, ok := unitMap[key]; ok {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			current, ok := s.data[unitName].(map[string]any)
+			if !ok {
+				current = make(map[string]any)
+				s.data[unitName] = current
+			}
+			current[key] = value
+			return value, true, nil
+		}
+	}
+
+	value, ok := s.Get(unitName, key)
+	return value, ok, nil
 }
 
 // GetString retrieves a string value from state
@@ -116,3 +170,133 @@ func (s *State) GetString(unitName, key string) (string, bool) {
 func (s *State) SetString(unitName, key, value string) error {
 	return s.Set(unitName, key, value)
 }
+
+// Delete removes all state for the given unit and automatically saves
+func (s *State) Delete(unitName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, unitName)
+	return s.save()
+}
+
+// DeleteKey removes a single key from the given unit's state and
+// automatically saves
+func (s *State) DeleteKey(unitName, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unitMap, ok := s.data[unitName].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	delete(unitMap, key)
+	if len(unitMap) == 0 {
+		delete(s.data, unitName)
+	}
+
+	return s.save()
+}
+
+// Prune removes the state of all units not in keep and saves if anything
+// changed. It returns the sorted names of the pruned units.
+func (s *State) Prune(keep []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pruned []string
+	for _, name := range s.units() {
+		if !slices.Contains(keep, name) {
+			delete(s.data, name)
+			pruned = append(pruned, name)
+		}
+	}
+
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+
+	return pruned, s.save()
+}
+
+// Units returns the sorted names of all units with stored state
+func (s *State) Units() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.units()
+}
+
+// units returns the sorted names of all units with stored state, with s.mu held
+func (s *State) units() []string {
+	var names []string
+	for name := range s.data {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// maxSummaryValue is the length of the longest value shown by Summary
+const maxSummaryValue = 80
+
+// Summary returns the state as one "<unit>.<key>: <value>" line per value,
+// sorted by unit and key. Lists, maps, and long strings such as file hashes
+// are summarized by their size.
+func (s *State) Summary() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var lines []string
+	for _, name := range s.units() {
+		unitMap, ok := s.data[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(unitMap))
+		for key := range unitMap {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			var value string
+			switch v := unitMap[key].(type) {
+			case []any:
+				value = fmt.Sprintf("(%d entries)", len(v))
+			case map[string]any:
+				value = fmt.Sprintf("(%d entries)", len(v))
+			case string:
+				value = v
+				if len(v) > maxSummaryValue {
+					value = fmt.Sprintf("(%d bytes)", len(v))
+				}
+			default:
+				value = fmt.Sprint(v)
+			}
+			lines = append(lines, fmt.Sprintf("%s.%s: %s", name, key, value))
+		}
+	}
+	return lines
+}
+
+// Dump returns the state grouped by unit as YAML, or as indented JSON if
+// asJSON is set
+func (s *State) Dump(asJSON bool) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if asJSON {
+		data, err := json.MarshalIndent(s.data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal state: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+
+	data, err := yaml.Marshal(s.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return data, nil
+}
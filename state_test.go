@@ -0,0 +1,162 @@
+package brun
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestState_DeleteAndUnits(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.yaml")
+	state := NewState(statePath)
+
+	if err := state.SetString("git", "last_commit_hash", "abc123"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if err := state.Set("boot", "count", 3); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	if units := state.Units(); !slices.Equal(units, []string{"boot", "git"}) {
+		t.Errorf("Units() = %v, want [boot git]", units)
+	}
+
+	if err := state.Delete("git"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+
+	// Deletion is persisted
+	reloaded := NewState(statePath)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if _, ok := reloaded.Get("git", "last_commit_hash"); ok {
+		t.Error("git state should be deleted")
+	}
+	if count, _ := reloaded.Get("boot", "count"); count != 3 {
+		t.Errorf("boot count = %v, want 3", count)
+	}
+}
+
+func TestState_Dump(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	if err := state.SetString("git", "last_commit_hash", "abc123"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	yamlData, err := state.Dump(false)
+	if err != nil {
+		t.Fatalf("Dump(false) failed: %v", err)
+	}
+	if !strings.Contains(string(yamlData), "git:\n    last_commit_hash: abc123") {
+		t.Errorf("Unexpected YAML dump:\n%s", yamlData)
+	}
+
+	jsonData, err := state.Dump(true)
+	if err != nil {
+		t.Fatalf("Dump(true) failed: %v", err)
+	}
+	var decoded map[string]map[string]string
+	if err := json.Unmarshal(jsonData, &decoded); err != nil {
+		t.Fatalf("Invalid JSON dump: %v", err)
+	}
+	if decoded["git"]["last_commit_hash"] != "abc123" {
+		t.Errorf("Unexpected JSON dump: %s", jsonData)
+	}
+}
+
+func TestState_DeleteKey(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.yaml")
+	state := NewState(statePath)
+
+	if err := state.SetString("git", "last_commit_hash", "abc123"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if err := state.SetString("git", "last_poll_time", "2025-01-01T00:00:00Z"); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	if err := state.DeleteKey("git", "last_commit_hash"); err != nil {
+		t.Fatalf("DeleteKey() failed: %v", err)
+	}
+
+	reloaded := NewState(statePath)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if _, ok := reloaded.Get("git", "last_commit_hash"); ok {
+		t.Error("last_commit_hash should be deleted")
+	}
+	if _, ok := reloaded.Get("git", "last_poll_time"); !ok {
+		t.Error("last_poll_time should be kept")
+	}
+
+	// Removing the last key removes the unit
+	if err := state.DeleteKey("git", "last_poll_time"); err != nil {
+		t.Fatalf("DeleteKey() failed: %v", err)
+	}
+	if units := state.Units(); len(units) != 0 {
+		t.Errorf("Units() = %v, want none", units)
+	}
+
+	// Deleting missing keys is not an error
+	if err := state.DeleteKey("missing", "key"); err != nil {
+		t.Errorf("DeleteKey() on missing unit failed: %v", err)
+	}
+}
+
+func TestState_Prune(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	for _, name := range []string{"boot", "old-build", "git", "removed"} {
+		if err := state.Set(name, "count", 1); err != nil {
+			t.Fatalf("Failed to set state: %v", err)
+		}
+	}
+
+	pruned, err := state.Prune([]string{"boot", "git", "not-stored"})
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+
+	if !slices.Equal(pruned, []string{"old-build", "removed"}) {
+		t.Errorf("Prune() = %v, want [old-build removed]", pruned)
+	}
+	if units := state.Units(); !slices.Equal(units, []string{"boot", "git"}) {
+		t.Errorf("Units() = %v, want [boot git]", units)
+	}
+
+	pruned, err = state.Prune([]string{"boot", "git"})
+	if err != nil || len(pruned) != 0 {
+		t.Errorf("Second Prune() = %v, %v, want nothing pruned", pruned, err)
+	}
+}
+
+func TestState_Summary(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	values := []struct {
+		unit, key string
+		value     any
+	}{
+		{"nightly", "last_execution", "2025-01-02T03:00:00Z"},
+		{"counter", "build", 3},
+		{"watch", "files_state", strings.Repeat("x", 200)},
+		{"reboot", "reboot_history", []any{"a", "b"}},
+	}
+	for _, v := range values {
+		if err := state.Set(v.unit, v.key, v.value); err != nil {
+			t.Fatalf("Failed to set state: %v", err)
+		}
+	}
+
+	want := []string{
+		"counter.build: 3",
+		"nightly.last_execution: 2025-01-02T03:00:00Z",
+		"reboot.reboot_history: (2 entries)",
+		"watch.files_state: (200 bytes)",
+	}
+	if got := state.Summary(); !slices.Equal(got, want) {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package brun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+)
+
+// stateCompareOps are the comparisons supported by the state trigger
+var stateCompareOps = []string{"==", "!=", ">", ">=", "<", "<="}
+
+// StateTrigger is a trigger unit that fires when a value another unit stored
+// in state meets a condition, such as a count reaching a threshold
+type StateTrigger struct {
+	name      string
+	unit      string
+	key       string
+	compare   string
+	value     string
+	state     *State
+	onSuccess []string
+	onFailure []string
+	always    []string
+}
+
+// StateConfig represents the configuration for a state trigger
+type StateConfig struct {
+	UnitConfig `yaml:",inline"`
+	Unit       string `yaml:"unit"`
+	Key        string `yaml:"key"`
+	Compare    string `yaml:"compare,omitempty"`
+	Value      string `yaml:"value"`
+}
+
+// NewStateTrigger creates a new state trigger that watches the state value
+// key of unit. compare is one of ==, !=, >, >=, <, or <= (default >=);
+// ordered comparisons require value to be a number.
+func NewStateTrigger(name, unit, key, compare, value string, state *State, onSuccess, onFailure, always []string) (*StateTrigger, error) {
+	if compare == "" {
+		compare = ">="
+	}
+	if !slices.Contains(stateCompareOps, compare) {
+		return nil, fmt.Errorf("invalid compare '%s' (expected one of ==, !=, >, >=, <, <=)", compare)
+	}
+	if compare != "==" && compare != "!=" {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("compare '%s' requires a numeric value, got '%s'", compare, value)
+		}
+	}
+
+	return &StateTrigger{
+		name:      name,
+		unit:      unit,
+		key:       key,
+		compare:   compare,
+		value:     value,
+		state:     state,
+		onSuccess: onSuccess,
+		onFailure: onFailure,
+		always:    always,
+	}, nil
+}
+
+// Name returns the name of the unit
+func (s *StateTrigger) Name() string {
+	return s.name
+}
+
+// Type returns the unit type
+func (s *StateTrigger) Type() string {
+	return "trigger.state"
+}
+
+// matches returns true if the state value meets the condition. Values are
+// compared as numbers if both are numeric, otherwise as strings.
+func (s *StateTrigger) matches(actual any) bool {
+	actualStr := fmt.Sprint(actual)
+	a, errA := strconv.ParseFloat(actualStr, 64)
+	b, errB := strconv.ParseFloat(s.value, 64)
+	if errA != nil || errB != nil {
+		switch s.compare {
+		case "==":
+			return actualStr == s.value
+		case "!=":
+			return actualStr != s.value
+		}
+		return false
+	}
+
+	switch s.compare {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	default:
+		return a <= b
+	}
+}
+
+// Check returns true when the watched value starts meeting the condition. It
+// doesn't fire again until the condition has stopped being met. A missing
+// value doesn't meet any condition.
+func (s *StateTrigger) Check(ctx context.Context, mode CheckMode) (bool, error) {
+	actual, ok := s.state.Get(s.unit, s.key)
+	matched := ok && s.matches(actual)
+
+	wasMatched := false
+	if val, ok := s.state.Get(s.name, "matched"); ok {
+		if boolVal, ok := val.(bool); ok {
+			wasMatched = boolVal
+		}
+	}
+
+	if matched != wasMatched {
+		if err := s.state.Set(s.name, "matched", matched); err != nil {
+			return false, fmt.Errorf("failed to save state trigger state: %w", err)
+		}
+	}
+
+	return matched && !wasMatched, nil
+}
+
+// OnSuccess returns the list of units to trigger on success
+func (s *StateTrigger) OnSuccess() []string {
+	return s.onSuccess
+}
+
+// OnFailure returns the list of units to trigger on failure
+func (s *StateTrigger) OnFailure() []string {
+	return s.onFailure
+}
+
+// Always returns the list of units to trigger regardless of success/failure
+func (s *StateTrigger) Always() []string {
+	return s.always
+}
+
+// Run executes the trigger unit
+// Note: Check() has already been called by the orchestrator before Run() is invoked
+func (s *StateTrigger) Run(ctx context.Context) error {
+	actual, _ := s.state.Get(s.unit, s.key)
+	log.Printf("State trigger '%s' activated (%s.%s = %v, %s %s)", s.name, s.unit, s.key, actual, s.compare, s.value)
+	return nil
+}
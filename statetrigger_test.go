@@ -0,0 +1,121 @@
+package brun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateTrigger_Check(t *testing.T) {
+	state := NewState(filepath.Join(t.TempDir(), "state.yaml"))
+	trigger, err := NewStateTrigger("escalate", "failures", "build", ">=", "3", state, []string{"page"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewStateTrigger failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// A missing value doesn't fire
+	if fired, err := trigger.Check(ctx, CheckModePolling); err != nil || fired {
+		t.Fatalf("Check() = %v, %v; want false with no value", fired, err)
+	}
+
+	steps := []struct {
+		count int
+		want  bool
+	}{
+		{2, false},
+		{3, true},  // reaches the threshold
+		{4, false}, // still met, doesn't fire again
+		{0, false}, // reset
+		{5, true},  // met again
+	}
+	for _, step := range steps {
+		if err := state.Set("failures", "build", step.count); err != nil {
+			t.Fatalf("Failed to set state: %v", err)
+		}
+		fired, err := trigger.Check(ctx, CheckModePolling)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if fired != step.want {
+			t.Errorf("count %d: Check() = %v, want %v", step.count, fired, step.want)
+		}
+	}
+}
+
+func TestStateTrigger_Matches(t *testing.T) {
+	tests := []struct {
+		compare, value string
+		actual         any
+		want           bool
+	}{
+		{"==", "5", 5, true},
+		{"==", "5", 5.0, true},
+		{"!=", "5", 4, true},
+		{">", "5", 5, false},
+		{"<", "5", 4, true},
+		{"<=", "5", "5", true},
+		{"==", "failed", "failed", true},
+		{"!=", "failed", "ok", true},
+		{">=", "5", "many", false},
+	}
+	for _, tt := range tests {
+		trigger, err := NewStateTrigger("s", "u", "k", tt.compare, tt.value, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("NewStateTrigger(%s %s) failed: %v", tt.compare, tt.value, err)
+		}
+		if got := trigger.matches(tt.actual); got != tt.want {
+			t.Errorf("%v %s %s = %v, want %v", tt.actual, tt.compare, tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := NewStateTrigger("s", "u", "k", "=~", "5", nil, nil, nil, nil); err == nil {
+		t.Error("Expected error for invalid compare")
+	}
+	if _, err := NewStateTrigger("s", "u", "k", ">", "five", nil, nil, nil, nil); err == nil {
+		t.Error("Expected error for non-numeric value with >")
+	}
+}
+
+func TestLoadConfig_WithStateUnit(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `config:
+  state_location: state.yaml
+
+units:
+  - state:
+      name: escalate
+      unit: failures
+      key: build
+      value: 5
+      on_success: [page]
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	units, err := config.CreateUnits()
+	if err != nil {
+		t.Fatalf("CreateUnits failed: %v", err)
+	}
+
+	trigger, ok := units[0].(*StateTrigger)
+	if !ok {
+		t.Fatalf("Expected *StateTrigger, got %T", units[0])
+	}
+	if trigger.compare != ">=" || trigger.value != "5" {
+		t.Errorf("Expected default compare >= 5, got %s %s", trigger.compare, trigger.value)
+	}
+
+	config.Units[0].State.Key = ""
+	if _, err := config.CreateUnits(); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
@@ -0,0 +1,193 @@
+package brun
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronStarBit is set by the cron parser in a field given as "*" or "?"
+const cronStarBit = 1 << 63
+
+// cronTimer is a systemd timer running a cron trigger with brun run -trigger
+type cronTimer struct {
+	Name string
+	// Directives are the [Timer] directives that schedule it, e.g.
+	// "OnCalendar=*-*-* 02:00:00"
+	Directives []string
+}
+
+// cronTimers returns a systemd timer for each cron trigger in the config
+func (c *Config) cronTimers() ([]cronTimer, error) {
+	// config.jitter becomes a randomized delay that is stable per host and
+	// timer, like the jitter offset of the daemon
+	var jitterDirectives []string
+	if c.ConfigBlock.Jitter != "" {
+		jitter, err := time.ParseDuration(c.ConfigBlock.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf("config.jitter: invalid format '%s': %w", c.ConfigBlock.Jitter, err)
+		}
+		if seconds := int(jitter / time.Second); seconds > 0 {
+			jitterDirectives = []string{fmt.Sprintf("RandomizedDelaySec=%d", seconds), "FixedRandomDelay=true"}
+		}
+	}
+
+	var timers []cronTimer
+	for _, wrapper := range c.Units {
+		cfg := wrapper.Cron
+		if cfg == nil {
+			continue
+		}
+		directives, err := timerDirectives(cfg.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("cron unit '%s': %w", cfg.Name, err)
+		}
+		timers = append(timers, cronTimer{Name: cfg.Name, Directives: append(directives, jitterDirectives...)})
+	}
+	return timers, nil
+}
+
+// timerDirectives translates a cron schedule into systemd [Timer] directives.
+// A schedule restricting both the day of month and the day of week fires on
+// either, like cron, so it becomes two OnCalendar directives.
+func timerDirectives(schedule string) ([]string, error) {
+	if isDynamicRef(schedule) {
+		return nil, fmt.Errorf("schedule %s is read at runtime and can't be converted to a systemd timer", schedule)
+	}
+
+	sched, err := parseCronSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec *cron.SpecSchedule
+	switch s := sched.(type) {
+	case *cron.SpecSchedule:
+		spec = s
+	case cron.ConstantDelaySchedule:
+		seconds := int(s.Delay / time.Second)
+		return []string{fmt.Sprintf("OnActiveSec=%d", seconds), fmt.Sprintf("OnUnitActiveSec=%d", seconds)}, nil
+	default:
+		return nil, fmt.Errorf("schedule '%s' can't be converted to a systemd timer", schedule)
+	}
+
+	month := calendarField(spec.Month, 1, 12, nil)
+	dom := calendarField(spec.Dom, 1, 31, nil)
+	dow := calendarField(spec.Dow, 0, 6, []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"})
+	clock := fmt.Sprintf("%s:%s:00", calendarField(spec.Hour, 0, 23, nil), calendarField(spec.Minute, 0, 59, nil))
+
+	zone := ""
+	if spec.Location != nil && spec.Location != time.Local {
+		zone = " " + spec.Location.String()
+	}
+
+	switch {
+	case dow == "*":
+		return []string{fmt.Sprintf("OnCalendar=*-%s-%s %s%s", month, dom, clock, zone)}, nil
+	case dom == "*":
+		return []string{fmt.Sprintf("OnCalendar=%s *-%s-* %s%s", dow, month, clock, zone)}, nil
+	default:
+		return []string{
+			fmt.Sprintf("OnCalendar=*-%s-%s %s%s", month, dom, clock, zone),
+			fmt.Sprintf("OnCalendar=%s *-%s-* %s%s", dow, month, clock, zone),
+		}, nil
+	}
+}
+
+// calendarField formats a cron field's bits as a systemd calendar component,
+// e.g. "*", "05" or "09..17,20". names, if set, replace the numbers.
+func calendarField(bits uint64, min, max int, names []string) string {
+	if bits&cronStarBit != 0 {
+		return "*"
+	}
+
+	format := func(v int) string {
+		if names != nil {
+			return names[v]
+		}
+		return fmt.Sprintf("%02d", v)
+	}
+
+	var parts []string
+	for v := min; v <= max; v++ {
+		if bits&(1<<uint(v)) == 0 {
+			continue
+		}
+		end := v
+		for end+1 <= max && bits&(1<<uint(end+1)) != 0 {
+			end++
+		}
+		if end > v {
+			parts = append(parts, format(v)+".."+format(end))
+		} else {
+			parts = append(parts, format(v))
+		}
+		v = end
+	}
+	return strings.Join(parts, ",")
+}
+
+// generateTimerFile generates the systemd timer file content for a cron timer
+func generateTimerFile(timer cronTimer) string {
+	return fmt.Sprintf(`[Unit]
+Description=BRun - cron trigger %s
+
+[Timer]
+%s
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, timer.Name, strings.Join(timer.Directives, "\n"))
+}
+
+// generateTimerServiceFile generates the oneshot service a cron timer starts,
+// which runs the trigger and the units it triggers with brun run -timer. The
+// timer decides when to run, so the trigger's schedule isn't checked again.
+// userService adds the SSH agent socket like the user brun service.
+func generateTimerServiceFile(execPath, configPath, name string, userService bool) string {
+	env := ""
+	if userService {
+		env = "Environment=SSH_AUTH_SOCK=%t/ssh-agent.socket\n"
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=BRun - cron trigger %s
+After=network.target
+
+[Service]
+Type=oneshot
+ExecStart=%s run %s -timer %s
+%sStandardOutput=journal
+StandardError=journal
+`, name, execPath, configPath, systemdQuote(name), env)
+}
+
+// systemdQuote quotes an ExecStart argument, if needed, so it stays one
+// argument and isn't expanded by systemd
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\%$;") {
+		return arg
+	}
+	arg = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(arg)
+	return `"` + arg + `"`
+}
+
+// timerUnitName returns the systemd unit name, without suffix, of the timer
+// running the named cron trigger. Characters systemd doesn't allow in unit
+// names are escaped like systemd-escape does.
+func timerUnitName(name string) string {
+	var b strings.Builder
+	b.WriteString("brun-")
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(":_.-", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package brun
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTimerDirectives(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     []string
+	}{
+		{"0 2 * * *", []string{"OnCalendar=*-*-* 02:00:00"}},
+		{"*/15 9-17 * * 1-5", []string{"OnCalendar=Mon..Fri *-*-* 09..17:00,15,30,45:00"}},
+		{"30 4 1,15 * *", []string{"OnCalendar=*-*-01,15 04:30:00"}},
+		{"0 0 1 * 0", []string{"OnCalendar=*-*-01 00:00:00", "OnCalendar=Sun *-*-* 00:00:00"}},
+		{"0 6 * 1,7 *", []string{"OnCalendar=*-01,07-* 06:00:00"}},
+		{"@daily", []string{"OnCalendar=*-*-* 00:00:00"}},
+		{"CRON_TZ=UTC 0 8 * * *", []string{"OnCalendar=*-*-* 08:00:00 UTC"}},
+		{"@every 30m", []string{"OnActiveSec=1800", "OnUnitActiveSec=1800"}},
+	}
+
+	for _, tt := range tests {
+		got, err := timerDirectives(tt.schedule)
+		if err != nil {
+			t.Errorf("timerDirectives(%q) failed: %v", tt.schedule, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("timerDirectives(%q) = %q, want %q", tt.schedule, got, tt.want)
+		}
+	}
+
+	if _, err := timerDirectives("${state:config.schedule}"); err == nil {
+		t.Error("Expected error for a schedule read at runtime")
+	}
+}
+
+func TestGenerateTimerFiles(t *testing.T) {
+	timer := generateTimerFile(cronTimer{Name: "nightly", Directives: []string{"OnCalendar=*-*-* 02:00:00"}})
+	for _, want := range []string{"OnCalendar=*-*-* 02:00:00\n", "Persistent=true\n", "WantedBy=timers.target\n"} {
+		if !strings.Contains(timer, want) {
+			t.Errorf("Expected %q in timer file, got:\n%s", want, timer)
+		}
+	}
+
+	service := generateTimerServiceFile("/usr/local/bin/brun", "/etc/brun/config.yaml", "nightly", false)
+	if !strings.Contains(service, "ExecStart=/usr/local/bin/brun run /etc/brun/config.yaml -timer nightly\n") {
+		t.Errorf("Unexpected ExecStart in service file:\n%s", service)
+	}
+	// Names that systemd would split or expand are quoted
+	service = generateTimerServiceFile("/usr/local/bin/brun", "/etc/brun/config.yaml", `nightly "50%" backup`, false)
+	if !strings.Contains(service, `ExecStart=/usr/local/bin/brun run /etc/brun/config.yaml -timer "nightly \"50%%\" backup"`+"\n") {
+		t.Errorf("Expected quoted trigger name in service file:\n%s", service)
+	}
+	if name := timerUnitName("nightly backup"); name != `brun-nightly\x20backup` {
+		t.Errorf("timerUnitName() = %s", name)
+	}
+	if strings.Contains(service, "SSH_AUTH_SOCK") {
+		t.Errorf("Expected no SSH agent in system service file:\n%s", service)
+	}
+	if service := generateTimerServiceFile("/usr/local/bin/brun", "/home/u/config.yaml", "nightly", true); !strings.Contains(service, "Environment=SSH_AUTH_SOCK=%t/ssh-agent.socket\n") {
+		t.Errorf("Expected SSH agent in user service file:\n%s", service)
+	}
+}
+
+func TestCronTimers_Jitter(t *testing.T) {
+	config := &Config{ConfigBlock: ConfigBlock{Jitter: "10m"}}
+	config.Units = UnitList{{Cron: &CronConfig{UnitConfig: UnitConfig{Name: "nightly"}, Schedule: "0 2 * * *"}}}
+
+	timers, err := config.cronTimers()
+	if err != nil {
+		t.Fatalf("cronTimers failed: %v", err)
+	}
+	want := []string{"OnCalendar=*-*-* 02:00:00", "RandomizedDelaySec=600", "FixedRandomDelay=true"}
+	if len(timers) != 1 || !slices.Equal(timers[0].Directives, want) {
+		t.Errorf("Expected directives %v, got %+v", want, timers)
+	}
+}
@@ -57,9 +57,42 @@ type TriggerUnit interface {
 
 // UnitConfig represents the base configuration for all units
 type UnitConfig struct {
-	Name      string   `yaml:"name"`
-	Type      string   `yaml:"type"`
-	OnSuccess []string `yaml:"on_success,omitempty"`
-	OnFailure []string `yaml:"on_failure,omitempty"`
-	Always    []string `yaml:"always,omitempty"`
+	Name string `yaml:"name"`
+	// Type selects the unit type when units are written as a map. In the
+	// list form it is optional and must match the unit's block key.
+	Type string `yaml:"type"`
+	// Description is shown next to the name in logs
+	Description string   `yaml:"description,omitempty"`
+	OnSuccess   []string `yaml:"on_success,omitempty"`
+	OnFailure   []string `yaml:"on_failure,omitempty"`
+	Always      []string `yaml:"always,omitempty"`
+	// OnError lists units to run when a trigger's check itself fails, e.g. a
+	// git fetch that can't reach the remote
+	OnError []string `yaml:"on_error,omitempty"`
+	// Force lists trigger units this unit triggers that run without their
+	// condition being checked, e.g. a scheduled rebuild of a git trigger
+	Force []string `yaml:"force,omitempty"`
+	// Named values downstream units can reference as ${artifact.<name>}
+	SetArtifact map[string]string `yaml:"set_artifact,omitempty"`
+	// Triggers with a higher priority run first when several fire in a cycle
+	// (named trigger_priority since ntfy units use priority for notifications)
+	TriggerPriority int `yaml:"trigger_priority,omitempty"`
+	// Destructive units are suppressed in -unit and -trigger runs unless
+	// -allow-destructive is set. Reboot units are always destructive.
+	Destructive bool `yaml:"destructive,omitempty"`
+	// After a trigger fires, it isn't checked again for this duration
+	Cooldown string `yaml:"cooldown,omitempty"`
+	// Poll sets how often a trigger is checked in daemon poll cycles when it
+	// should be checked less often than config.poll_interval
+	Poll string `yaml:"poll,omitempty"`
+	// Edge triggers only fire when their condition changes from not met to
+	// met, not on every check while it stays met
+	EdgeTrigger bool `yaml:"edge_trigger,omitempty"`
+	// PropagateFailure also runs the on_failure units when a unit in the
+	// chain this unit triggers fails, not only when the unit itself fails
+	PropagateFailure bool `yaml:"propagate_failure,omitempty"`
+	// FailureBackoff delays running a unit again after it fails, doubling
+	// with each consecutive failure up to FailureBackoffMax
+	FailureBackoff    string `yaml:"failure_backoff,omitempty"`
+	FailureBackoffMax string `yaml:"failure_backoff_max,omitempty"`
 }
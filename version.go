@@ -0,0 +1,11 @@
+package brun
+
+// version is set at build time, e.g.
+// go build -ldflags "-X github.com/cbrake/brun.version=v1.2.3" ./cmd/brun
+var version = "dev"
+
+// Version returns the version of brun, or "dev" if it wasn't set at build
+// time
+func Version() string {
+	return version
+}